@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-echo-app/internal/models"
+	"go-echo-app/internal/repository"
+	"go-echo-app/pkg/utils"
+)
+
+// MethodAPIKey API Key认证
+const MethodAPIKey = "api_key"
+
+// API Key格式：gea_<前缀>_<密钥>
+const (
+	apiKeyTag         = "gea"
+	apiKeyPrefixBytes = 6
+	apiKeySecretBytes = 24
+	// lastUsedResolution 最近使用时间的更新粒度，避免每个请求都写仓库
+	lastUsedResolution = time.Minute
+)
+
+// API Key错误
+var (
+	ErrAPIKeyNotFound    = errors.New("api key not found")
+	ErrScopeNotAllowed   = errors.New("requested scope exceeds caller scopes")
+	ErrInvalidExpiration = errors.New("expiration must be in the future")
+)
+
+// APIKeyService API Key的创建、吊销和认证
+type APIKeyService struct {
+	keys  models.APIKeyRepository
+	users models.UserRepository
+}
+
+// NewAPIKeyService 创建API Key服务
+func NewAPIKeyService(keys models.APIKeyRepository, users models.UserRepository) *APIKeyService {
+	return &APIKeyService{keys: keys, users: users}
+}
+
+// Create 为owner创建API Key，返回只展示一次的明文Key。
+// 调用方本身使用带范围的API Key认证时，新Key的范围必须被调用方的范围覆盖，未指定时继承调用方的范围
+func (s *APIKeyService) Create(ctx context.Context, owner *Principal, req *models.CreateAPIKeyRequest) (string, *models.APIKey, error) {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return "", nil, ErrInvalidExpiration
+	}
+	scopes := req.Scopes
+	if owner.Method == MethodAPIKey && len(owner.Scopes) > 0 {
+		// 不带范围的Key拥有角色的全部权限，不能由带范围的Key创建
+		if len(scopes) == 0 {
+			scopes = owner.Scopes
+		}
+		for _, scope := range scopes {
+			if !permissionGranted(owner.Scopes, scope) {
+				return "", nil, fmt.Errorf("%w: %s", ErrScopeNotAllowed, scope)
+			}
+		}
+	}
+
+	prefix := utils.RandomToken(apiKeyPrefixBytes)
+	raw := apiKeyTag + "_" + prefix + "_" + utils.RandomToken(apiKeySecretBytes)
+
+	key := &models.APIKey{
+		ID:        utils.NewID(),
+		Prefix:    prefix,
+		Hash:      hashAPIKey(raw),
+		Name:      req.Name,
+		OwnerID:   owner.Subject,
+		Scopes:    append([]string{}, scopes...),
+		ExpiresAt: req.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+	if err := s.keys.Create(ctx, key); err != nil {
+		return "", nil, err
+	}
+	return raw, key, nil
+}
+
+// List 列出owner的全部API Key
+func (s *APIKeyService) List(ctx context.Context, ownerID string) ([]*models.APIKey, error) {
+	return s.keys.ListByOwner(ctx, ownerID)
+}
+
+// Revoke 吊销owner的API Key，不属于owner的Key视为不存在
+func (s *APIKeyService) Revoke(ctx context.Context, ownerID, id string) error {
+	key, err := s.keys.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && key.OwnerID != ownerID) {
+		return ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.keys.Revoke(ctx, id, time.Now())
+}
+
+// Verify 实现TokenVerifier，按前缀查找Key并比对摘要
+func (s *APIKeyService) Verify(ctx context.Context, raw string) (*Principal, error) {
+	prefix, ok := parseAPIKey(raw)
+	if !ok {
+		return nil, fmt.Errorf("%w: malformed api key", ErrInvalidToken)
+	}
+
+	key, err := s.keys.GetByPrefix(ctx, prefix)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w: unknown api key", ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	switch {
+	case subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashAPIKey(raw))) != 1:
+		return nil, fmt.Errorf("%w: unknown api key", ErrInvalidToken)
+	case key.RevokedAt != nil:
+		return nil, fmt.Errorf("%w: api key revoked", ErrInvalidToken)
+	case key.ExpiresAt != nil && !now.Before(*key.ExpiresAt):
+		return nil, fmt.Errorf("%w: api key expired", ErrInvalidToken)
+	}
+
+	user, err := s.users.GetByID(ctx, key.OwnerID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w: api key owner no longer exists", ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		if err := s.keys.TouchLastUsed(ctx, key.ID, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Principal{
+		Subject: user.ID,
+		Email:   user.Email,
+		Roles:   user.Roles,
+		Scopes:  key.Scopes,
+		Method:  MethodAPIKey,
+		KeyID:   key.ID,
+	}, nil
+}
+
+// parseAPIKey 校验Key格式并返回前缀
+func parseAPIKey(raw string) (string, bool) {
+	parts := strings.Split(raw, "_")
+	if len(parts) != 3 || parts[0] != apiKeyTag || len(parts[1]) != apiKeyPrefixBytes*2 || len(parts[2]) != apiKeySecretBytes*2 {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// hashAPIKey 计算Key摘要，Key本身是高熵随机数，使用SHA-256即可
+func hashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// containsString 判断切片是否包含指定字符串
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go-echo-app/internal/models"
+	"go-echo-app/internal/repository"
+)
+
+func TestAPIKeyServiceCreateScopes(t *testing.T) {
+	tests := []struct {
+		name       string
+		owner      *Principal
+		scopes     []string
+		wantScopes []string
+		wantErr    error
+	}{
+		{name: "interactive caller may create an unscoped key", owner: &Principal{Method: MethodJWT}, wantScopes: []string{}},
+		{name: "interactive caller picks any scope", owner: &Principal{Method: MethodJWT}, scopes: []string{PermissionUsersWrite}, wantScopes: []string{PermissionUsersWrite}},
+		{name: "unscoped api key picks any scope", owner: &Principal{Method: MethodAPIKey}, scopes: []string{PermissionProxyUse}, wantScopes: []string{PermissionProxyUse}},
+		{
+			name:       "scoped api key without scopes inherits its own",
+			owner:      &Principal{Method: MethodAPIKey, Scopes: []string{PermissionProxyUse}},
+			wantScopes: []string{PermissionProxyUse},
+		},
+		{
+			name:       "subset of caller scopes",
+			owner:      &Principal{Method: MethodAPIKey, Scopes: []string{PermissionProxyUse, PermissionUsersRead}},
+			scopes:     []string{PermissionUsersRead},
+			wantScopes: []string{PermissionUsersRead},
+		},
+		{
+			name:       "covered by a caller wildcard",
+			owner:      &Principal{Method: MethodAPIKey, Scopes: []string{"users:*"}},
+			scopes:     []string{PermissionUsersWrite},
+			wantScopes: []string{PermissionUsersWrite},
+		},
+		{
+			name:    "scope outside caller scopes",
+			owner:   &Principal{Method: MethodAPIKey, Scopes: []string{PermissionProxyUse}},
+			scopes:  []string{PermissionProxyUse, PermissionUsersWrite},
+			wantErr: ErrScopeNotAllowed,
+		},
+		{
+			name:    "wildcard wider than caller scopes",
+			owner:   &Principal{Method: MethodAPIKey, Scopes: []string{PermissionUsersRead}},
+			scopes:  []string{"users:*"},
+			wantErr: ErrScopeNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewAPIKeyService(repository.NewMemoryAPIKeyRepository(), repository.NewMemoryUserRepository())
+			tt.owner.Subject = "u1"
+
+			_, key, err := s.Create(context.Background(), tt.owner, &models.CreateAPIKeyRequest{Name: "ci", Scopes: tt.scopes})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(key.Scopes, tt.wantScopes) {
+				t.Fatalf("Scopes = %v, want %v", key.Scopes, tt.wantScopes)
+			}
+		})
+	}
+}
+
+// 带范围的Key不能通过创建不带范围的Key获得所属用户的全部权限
+func TestAPIKeyScopedKeyCannotEscalate(t *testing.T) {
+	ctx := context.Background()
+	users := repository.NewMemoryUserRepository()
+	now := time.Now().UTC()
+	if err := users.Create(ctx, &models.User{ID: "u1", Name: "Ann", Email: "ann@example.com", Roles: []string{RoleUser}, CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	s := NewAPIKeyService(repository.NewMemoryAPIKeyRepository(), users)
+	policy := NewPolicy(nil)
+
+	raw, _, err := s.Create(ctx, &Principal{Subject: "u1", Method: MethodJWT}, &models.CreateAPIKeyRequest{Name: "proxy", Scopes: []string{PermissionProxyUse}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scoped, err := s.Verify(ctx, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, _, err = s.Create(ctx, scoped, &models.CreateAPIKeyRequest{Name: "child"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := s.Verify(ctx, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := policy.Permissions(child); !reflect.DeepEqual(got, []string{PermissionProxyUse}) {
+		t.Fatalf("child key permissions = %v, want only %s", got, PermissionProxyUse)
+	}
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"crypto"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go-echo-app/internal/config"
+	"go-echo-app/internal/models"
+	"go-echo-app/pkg/utils"
+)
+
+// TokenIssuer 按JWT配置签发访问令牌
+type TokenIssuer struct {
+	method   jwt.SigningMethod
+	key      crypto.PrivateKey
+	keys     *KeySet
+	issuer   string
+	audience string
+	ttl      time.Duration
+}
+
+// NewTokenIssuer 创建访问令牌签发器，keys不为nil时使用密钥集的当前密钥签名并写入kid
+func NewTokenIssuer(cfg *config.JWTConfig, keys *KeySet) (*TokenIssuer, error) {
+	method := jwt.GetSigningMethod(cfg.Algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", cfg.Algorithm)
+	}
+
+	i := &TokenIssuer{
+		method:   method,
+		keys:     keys,
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		ttl:      time.Duration(cfg.ExpireTime) * time.Hour,
+	}
+	if keys == nil {
+		key, err := loadSigningKey(cfg)
+		if err != nil {
+			return nil, err
+		}
+		i.key = key
+	}
+	return i, nil
+}
+
+// Issue 为用户签发访问令牌，返回令牌及其过期时间
+func (i *TokenIssuer) Issue(user *models.User) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(i.ttl)
+
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        utils.NewID(),
+			Subject:   user.ID,
+			Issuer:    i.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		Email: user.Email,
+		Roles: user.Roles,
+	}
+	if i.audience != "" {
+		claims.Audience = jwt.ClaimStrings{i.audience}
+	}
+
+	token := jwt.NewWithClaims(i.method, claims)
+	key := i.key
+	if i.keys != nil {
+		active := i.keys.Active()
+		token.Header["kid"] = active.ID
+		key = active.Private
+	}
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("sign access token: %w", err)
+	}
+	return signed, expiresAt, nil
+}
+
+// loadSigningKey 加载签名密钥：HS算法使用共享密钥，RS/ES算法读取PEM私钥
+func loadSigningKey(cfg *config.JWTConfig) (crypto.PrivateKey, error) {
+	switch cfg.Algorithm {
+	case "HS256", "HS384", "HS512":
+		if cfg.Secret == "" {
+			return nil, fmt.Errorf("JWT_SECRET is required for %s", cfg.Algorithm)
+		}
+		return []byte(cfg.Secret), nil
+	}
+
+	if cfg.PrivateKeyFile == "" {
+		return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE is required to issue %s tokens", cfg.Algorithm)
+	}
+	data, err := os.ReadFile(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read jwt private key: %w", err)
+	}
+
+	switch cfg.Algorithm {
+	case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+		return jwt.ParseRSAPrivateKeyFromPEM(data)
+	case "ES256", "ES384", "ES512":
+		return jwt.ParseECPrivateKeyFromPEM(data)
+	}
+	return nil, fmt.Errorf("unsupported jwt algorithm %q", cfg.Algorithm)
+}
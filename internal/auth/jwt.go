@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go-echo-app/internal/config"
+)
+
+// JWTVerifier 校验本系统签发的JWT
+type JWTVerifier struct {
+	keyFunc jwt.Keyfunc
+	parser  *jwt.Parser
+}
+
+// NewJWTVerifier 根据JWT配置创建校验器，只接受配置的签名算法。
+// keys不为nil时按令牌头中的kid从密钥集查找验签密钥
+func NewJWTVerifier(cfg *config.JWTConfig, keys *KeySet) (*JWTVerifier, error) {
+	method := jwt.GetSigningMethod(cfg.Algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", cfg.Algorithm)
+	}
+
+	v := &JWTVerifier{parser: newParser(cfg, method.Alg())}
+	if keys != nil {
+		v.keyFunc = keySetKeyFunc(keys)
+		return v, nil
+	}
+
+	key, err := loadVerificationKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+	v.keyFunc = func(*jwt.Token) (interface{}, error) { return key, nil }
+	return v, nil
+}
+
+// Verify 实现TokenVerifier
+func (v *JWTVerifier) Verify(_ context.Context, token string) (*Principal, error) {
+	claims := &Claims{}
+	if _, err := v.parser.ParseWithClaims(token, claims, v.keyFunc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return principalFromClaims(claims, MethodJWT), nil
+}
+
+// keySetKeyFunc 按kid从密钥集查找验签密钥
+func keySetKeyFunc(keys *KeySet) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("missing kid")
+		}
+		key := keys.Get(kid)
+		if key == nil {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		return key.Public, nil
+	}
+}
+
+// newParser 创建带签发方、受众、过期和时钟偏差校验的解析器
+func newParser(cfg *config.JWTConfig, algorithms ...string) *jwt.Parser {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(algorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Duration(cfg.ClockSkew) * time.Second),
+	}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+	return jwt.NewParser(options...)
+}
+
+// loadVerificationKey 加载验签密钥：HS算法使用共享密钥，RS/ES算法读取PEM公钥
+func loadVerificationKey(cfg *config.JWTConfig) (crypto.PublicKey, error) {
+	switch cfg.Algorithm {
+	case "HS256", "HS384", "HS512":
+		if cfg.Secret == "" {
+			return nil, fmt.Errorf("JWT_SECRET is required for %s", cfg.Algorithm)
+		}
+		return []byte(cfg.Secret), nil
+	}
+
+	if cfg.PublicKeyFile == "" {
+		return nil, fmt.Errorf("JWT_PUBLIC_KEY_FILE is required for %s", cfg.Algorithm)
+	}
+	data, err := os.ReadFile(cfg.PublicKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read jwt public key: %w", err)
+	}
+
+	switch cfg.Algorithm {
+	case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+		return jwt.ParseRSAPublicKeyFromPEM(data)
+	case "ES256", "ES384", "ES512":
+		return jwt.ParseECPublicKeyFromPEM(data)
+	}
+	return nil, fmt.Errorf("unsupported jwt algorithm %q", cfg.Algorithm)
+}
@@ -0,0 +1,483 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go-echo-app/internal/config"
+)
+
+// rsaKeyBits 轮换时生成的RSA密钥长度
+const rsaKeyBits = 2048
+
+const (
+	// rotationRetryDelay 轮换失败后的重试间隔
+	rotationRetryDelay = time.Minute
+	// reloadInterval 遇到未知kid时重新读取密钥目录的最短间隔，避免伪造的kid频繁触发读盘
+	reloadInterval = 10 * time.Second
+	// pemHeaderCreatedAt 持久化私钥中记录创建时间的PEM头
+	pemHeaderCreatedAt = "Created-At"
+)
+
+// SigningKey 密钥集中的一把密钥，Private为nil的密钥只用于验签
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	Private   crypto.Signer
+	Public    crypto.PublicKey
+	CreatedAt time.Time
+}
+
+// KeySet 非对称签名密钥集
+//
+// 最新创建的可签名密钥为当前密钥，其余密钥在被替换后继续保留retention时长，
+// 以便之前签发的令牌在过期前仍可验签。配置了dir时生成的私钥连同创建时间一起持久化，
+// 重启后继续使用；多个副本共享同一目录时，遇到未知kid或轮换前会重新读取目录，使用其他副本生成的密钥。
+type KeySet struct {
+	algorithm string
+	retention time.Duration
+	dir       string
+
+	mu   sync.RWMutex
+	keys []*SigningKey // 按CreatedAt升序
+	// retiredAt 被替换的时间，用于计算旧密钥的保留期限
+	retiredAt map[string]time.Time
+	// loadedAt 上次读取密钥目录的时间
+	loadedAt time.Time
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewKeySet 根据JWT配置创建密钥集，HS算法不使用密钥集时返回nil。
+// 依次加载JWT_PRIVATE_KEY_FILE、JWT_PUBLIC_KEY_FILE（仅验签）和JWT_KEYS_DIR中的密钥，没有可签名密钥时生成一把。
+// 轮换生成的密钥必须保存到JWT_KEYS_DIR，否则重启后之前签发的令牌全部失效，各副本也会使用不同的密钥签名
+func NewKeySet(cfg *config.JWTConfig) (*KeySet, error) {
+	if strings.HasPrefix(cfg.Algorithm, "HS") {
+		return nil, nil
+	}
+	if !asymmetricAlgorithm(cfg.Algorithm) {
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", cfg.Algorithm)
+	}
+	if cfg.KeyRotation > 0 && cfg.KeysDir == "" {
+		return nil, errors.New("JWT_KEY_ROTATION requires JWT_KEYS_DIR so that rotated keys survive restarts and are shared between replicas")
+	}
+
+	s := &KeySet{
+		algorithm: cfg.Algorithm,
+		retention: time.Duration(cfg.ExpireTime)*time.Hour + time.Duration(cfg.ClockSkew)*time.Second,
+		dir:       cfg.KeysDir,
+		retiredAt: make(map[string]time.Time),
+		stop:      make(chan struct{}),
+	}
+
+	if cfg.PrivateKeyFile != "" {
+		if err := s.loadFile(cfg.PrivateKeyFile); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.PublicKeyFile != "" {
+		if err := s.loadFile(cfg.PublicKeyFile); err != nil {
+			return nil, err
+		}
+	}
+	if s.dir != "" {
+		if err := s.loadDir(); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.Active() == nil {
+		if _, err := s.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Algorithm 返回密钥集的签名算法
+func (s *KeySet) Algorithm() string {
+	return s.algorithm
+}
+
+// Active 返回当前签名密钥
+func (s *KeySet) Active() *SigningKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.active()
+}
+
+// Get 按kid查找验签密钥，找不到时重新读取密钥目录，以便验证其他副本签发的令牌
+func (s *KeySet) Get(kid string) *SigningKey {
+	if key := s.get(kid); key != nil {
+		return key
+	}
+	if err := s.reload(false); err != nil {
+		return nil
+	}
+	return s.get(kid)
+}
+
+// get 在已加载的密钥中按kid查找
+func (s *KeySet) get(kid string) *SigningKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys {
+		if key.ID == kid {
+			return key
+		}
+	}
+	return nil
+}
+
+// Keys 返回全部有效密钥
+func (s *KeySet) Keys() []*SigningKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*SigningKey(nil), s.keys...)
+}
+
+// Rotate 生成新的当前密钥，原密钥转为仅验签并在保留期后删除
+func (s *KeySet) Rotate() (*SigningKey, error) {
+	private, err := generateKey(s.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	key, err := s.newKey(private, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if s.dir != "" {
+		if err := s.persist(key); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if previous := s.active(); previous != nil {
+		s.retiredAt[previous.ID] = key.CreatedAt
+	}
+	s.keys = append(s.keys, key)
+	s.prune(key.CreatedAt)
+	return key, nil
+}
+
+// StartRotation 在后台按interval轮换当前密钥，interval<=0时不轮换。
+// 到期时先重新读取密钥目录，其他副本已经轮换过时直接使用其密钥，不再生成新的
+func (s *KeySet) StartRotation(interval time.Duration, logf func(format string, args ...interface{})) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		wait := time.Until(s.Active().CreatedAt.Add(interval))
+		for {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-s.stop:
+				timer.Stop()
+				return
+			}
+
+			key, rotated, err := s.rotateIfDue(interval)
+			switch {
+			case err != nil:
+				logf("jwt key rotation failed: %v", err)
+				wait = rotationRetryDelay
+				continue
+			case rotated:
+				logf("jwt signing key rotated, kid=%s", key.ID)
+			}
+			wait = time.Until(key.CreatedAt.Add(interval))
+		}
+	}()
+}
+
+// rotateIfDue 当前密钥已使用满interval时轮换，返回此后的当前密钥以及是否由本次调用生成
+func (s *KeySet) rotateIfDue(interval time.Duration) (*SigningKey, bool, error) {
+	if err := s.reload(true); err != nil {
+		return nil, false, err
+	}
+	if active := s.Active(); time.Until(active.CreatedAt.Add(interval)) > 0 {
+		return active, false, nil
+	}
+	key, err := s.Rotate()
+	return key, err == nil, err
+}
+
+// Close 停止后台轮换
+func (s *KeySet) Close() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+// JWKS 返回全部有效密钥的公钥，包括其他副本轮换生成的密钥
+func (s *KeySet) JWKS() JWKSet {
+	_ = s.reload(false)
+
+	s.mu.Lock()
+	s.prune(time.Now())
+	keys := append([]*SigningKey(nil), s.keys...)
+	s.mu.Unlock()
+
+	set := JWKSet{Keys: make([]JWK, 0, len(keys))}
+	for i := len(keys) - 1; i >= 0; i-- {
+		jwk, err := NewJWK(keys[i].Public, keys[i].ID, keys[i].Algorithm)
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// active 返回最新的可签名密钥，调用方需持有锁
+func (s *KeySet) active() *SigningKey {
+	for i := len(s.keys) - 1; i >= 0; i-- {
+		if s.keys[i].Private != nil {
+			return s.keys[i]
+		}
+	}
+	return nil
+}
+
+// prune 删除超过保留期的旧密钥，调用方需持有写锁
+func (s *KeySet) prune(now time.Time) {
+	kept := s.keys[:0]
+	for _, key := range s.keys {
+		retired, ok := s.retiredAt[key.ID]
+		if ok && now.Sub(retired) > s.retention {
+			delete(s.retiredAt, key.ID)
+			if s.dir != "" {
+				_ = os.Remove(s.keyPath(key.ID))
+			}
+			continue
+		}
+		kept = append(kept, key)
+	}
+	s.keys = kept
+}
+
+// newKey 由私钥构造密钥，kid取公钥指纹
+func (s *KeySet) newKey(private crypto.Signer, createdAt time.Time) (*SigningKey, error) {
+	kid, err := Thumbprint(private.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{
+		ID:        kid,
+		Algorithm: s.algorithm,
+		Private:   private,
+		Public:    private.Public(),
+		CreatedAt: createdAt,
+	}, nil
+}
+
+// add 加入密钥并保持按创建时间排序，同一kid只保留一份
+func (s *KeySet) add(key *SigningKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.keys {
+		if existing.ID == key.ID {
+			return
+		}
+	}
+	s.keys = append(s.keys, key)
+	sort.SliceStable(s.keys, func(i, j int) bool { return s.keys[i].CreatedAt.Before(s.keys[j].CreatedAt) })
+
+	// 除最新的可签名密钥外，其余密钥按其后继的创建时间计算保留期
+	active := s.active()
+	s.retiredAt = make(map[string]time.Time)
+	for i, k := range s.keys {
+		if k != active && i+1 < len(s.keys) {
+			s.retiredAt[k.ID] = s.keys[i+1].CreatedAt
+		}
+	}
+}
+
+// reload 重新读取密钥目录，force为false时距上次读取不足reloadInterval则跳过
+func (s *KeySet) reload(force bool) error {
+	if s.dir == "" {
+		return nil
+	}
+	s.mu.RLock()
+	recent := time.Since(s.loadedAt) < reloadInterval
+	s.mu.RUnlock()
+	if recent && !force {
+		return nil
+	}
+	return s.loadDir()
+}
+
+// loadFile 加载PEM格式的私钥或公钥。创建时间取PEM头中记录的时间，没有时取文件修改时间
+func (s *KeySet) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read jwt key %s: %w", path, err)
+	}
+	createdAt, err := pemCreatedAt(data)
+	if err != nil {
+		return fmt.Errorf("parse jwt key %s: %w", path, err)
+	}
+	if createdAt.IsZero() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		createdAt = info.ModTime()
+	}
+	key, err := s.parsePEM(data, createdAt)
+	if err != nil {
+		return fmt.Errorf("parse jwt key %s: %w", path, err)
+	}
+	s.add(key)
+	return nil
+}
+
+// loadDir 加载密钥目录中持久化的私钥，已加载的kid不会重复加载
+func (s *KeySet) loadDir() error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if s.get(strings.TrimSuffix(filepath.Base(path), ".pem")) != nil {
+			continue
+		}
+		err := s.loadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			// 其他副本在读取期间删除了过期密钥
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.loadedAt = time.Now()
+	s.prune(s.loadedAt)
+	s.mu.Unlock()
+	return nil
+}
+
+// persist 以PKCS#8格式保存私钥，创建时间写在PEM头中，不依赖文件修改时间。
+// 先写临时文件再重命名，其他副本不会读到写了一半的密钥
+func (s *KeySet) persist(key *SigningKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key.Private)
+	if err != nil {
+		return err
+	}
+	data := pem.EncodeToMemory(&pem.Block{
+		Type:    "PRIVATE KEY",
+		Headers: map[string]string{pemHeaderCreatedAt: key.CreatedAt.UTC().Format(time.RFC3339Nano)},
+		Bytes:   der,
+	})
+
+	tmp := s.keyPath(key.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("persist jwt key: %w", err)
+	}
+	if err := os.Rename(tmp, s.keyPath(key.ID)); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("persist jwt key: %w", err)
+	}
+	return nil
+}
+
+// pemCreatedAt 读取persist写入的创建时间，没有记录时返回零值
+func pemCreatedAt(data []byte) (time.Time, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return time.Time{}, errors.New("no PEM data found")
+	}
+	value, ok := block.Headers[pemHeaderCreatedAt]
+	if !ok {
+		return time.Time{}, nil
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s header: %w", pemHeaderCreatedAt, err)
+	}
+	return createdAt, nil
+}
+
+// keyPath 返回密钥文件路径
+func (s *KeySet) keyPath(kid string) string {
+	return filepath.Join(s.dir, kid+".pem")
+}
+
+// parsePEM 解析与算法匹配的私钥或公钥
+func (s *KeySet) parsePEM(data []byte, createdAt time.Time) (*SigningKey, error) {
+	var public crypto.PublicKey
+	if strings.HasPrefix(s.algorithm, "ES") {
+		if private, err := jwt.ParseECPrivateKeyFromPEM(data); err == nil {
+			return s.newKey(private, createdAt)
+		}
+		key, err := jwt.ParseECPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		public = key
+	} else {
+		if private, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+			return s.newKey(private, createdAt)
+		}
+		key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		public = key
+	}
+
+	kid, err := Thumbprint(public)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{ID: kid, Algorithm: s.algorithm, Public: public, CreatedAt: createdAt}, nil
+}
+
+// generateKey 生成与算法匹配的私钥
+func generateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+		return rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case "ES256":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ES384":
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "ES512":
+		return ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	}
+	return nil, errors.New("unsupported jwt algorithm " + algorithm)
+}
+
+// asymmetricAlgorithm 判断是否为密钥集支持的非对称算法
+func asymmetricAlgorithm(algorithm string) bool {
+	switch algorithm {
+	case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512":
+		return true
+	}
+	return false
+}
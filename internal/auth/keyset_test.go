@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-echo-app/internal/config"
+)
+
+func testKeySetConfig(dir string) *config.JWTConfig {
+	return &config.JWTConfig{
+		Algorithm:   "ES256",
+		ExpireTime:  1,
+		KeysDir:     dir,
+		KeyRotation: 24,
+	}
+}
+
+func TestNewKeySetRequiresKeysDirForRotation(t *testing.T) {
+	if _, err := NewKeySet(testKeySetConfig("")); err == nil {
+		t.Fatal("expected error when rotation is enabled without JWT_KEYS_DIR")
+	}
+}
+
+func TestKeySetPersistsCreatedAt(t *testing.T) {
+	dir := t.TempDir()
+	first, err := NewKeySet(testKeySetConfig(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	active := first.Active()
+
+	// 文件修改时间不可靠（复制、备份恢复都会改变），创建时间以PEM头为准
+	path := filepath.Join(dir, active.ID+".pem")
+	if err := os.Chtimes(path, time.Unix(0, 0), time.Unix(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := NewKeySet(testKeySetConfig(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded := second.Active()
+	if reloaded.ID != active.ID {
+		t.Fatalf("active kid = %s, want %s", reloaded.ID, active.ID)
+	}
+	if !reloaded.CreatedAt.Equal(active.CreatedAt) {
+		t.Fatalf("CreatedAt = %v, want %v", reloaded.CreatedAt, active.CreatedAt)
+	}
+
+	key, rotated, err := second.rotateIfDue(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated || key.ID != active.ID {
+		t.Fatalf("rotateIfDue rotated a fresh key after restart")
+	}
+}
+
+func TestKeySetSharesKeysThroughDir(t *testing.T) {
+	dir := t.TempDir()
+	a, err := NewKeySet(testKeySetConfig(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewKeySet(testKeySetConfig(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Active().ID != b.Active().ID {
+		t.Fatal("replicas started with different keys")
+	}
+
+	rotated, err := a.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// b遇到未知kid时重新读取目录
+	b.mu.Lock()
+	b.loadedAt = time.Time{}
+	b.mu.Unlock()
+	if b.Get(rotated.ID) == nil {
+		t.Fatal("replica cannot verify a key rotated by another replica")
+	}
+	if b.Active().ID != rotated.ID {
+		t.Fatal("replica does not sign with the newest shared key")
+	}
+
+	// a刚轮换过，b到期时直接使用a的密钥
+	key, didRotate, err := b.rotateIfDue(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if didRotate || key.ID != rotated.ID {
+		t.Fatal("replica rotated again although the shared key is fresh")
+	}
+}
@@ -0,0 +1,472 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go-echo-app/internal/config"
+	"go-echo-app/internal/models"
+	"go-echo-app/internal/repository"
+	"go-echo-app/pkg/utils"
+)
+
+// MethodOIDC 外部OIDC签发方认证
+const MethodOIDC = "oidc"
+
+// OIDC相关默认值
+const (
+	defaultJWKSCache       = time.Hour
+	minJWKSRefreshInterval = 10 * time.Second
+	maxOIDCDocumentSize    = 1 << 20
+)
+
+// defaultOIDCAlgorithms 未配置时接受的签名算法
+var defaultOIDCAlgorithms = []string{"RS256", "ES256"}
+
+// discoveryDocument OIDC发现文档中用到的字段
+type discoveryDocument struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+// OIDCProvider 单个外部签发方，缓存发现文档和JWKS
+type OIDCProvider struct {
+	config config.OIDCProviderConfig
+	client *http.Client
+	parser *jwt.Parser
+
+	mu          sync.Mutex
+	jwksURI     string
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+	refreshing  chan struct{} // 正在进行的刷新，完成时关闭
+}
+
+// NewOIDCProvider 创建外部签发方，发现文档和JWKS在首次校验时获取
+func NewOIDCProvider(cfg config.OIDCProviderConfig, clockSkew time.Duration, client *http.Client) *OIDCProvider {
+	algorithms := cfg.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = defaultOIDCAlgorithms
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(algorithms),
+		jwt.WithIssuer(cfg.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+
+	return &OIDCProvider{
+		config: cfg,
+		client: client,
+		parser: jwt.NewParser(options...),
+	}
+}
+
+// Verify 校验令牌签名和标准声明，返回全部声明
+func (p *OIDCProvider) Verify(ctx context.Context, token string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := p.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+// key 返回kid对应的公钥，缓存过期或遇到未知kid时刷新JWKS。
+// 刷新期间不持有锁，并发的请求等待同一次刷新而不是各自请求签发方
+func (p *OIDCProvider) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	key, ok := p.lookup(kid)
+	if ok && now.Sub(p.fetchedAt) < p.cacheTTL() {
+		return key, nil
+	}
+
+	if p.refreshing != nil {
+		// 等待其他请求发起的刷新
+		done := p.refreshing
+		p.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+		p.mu.Lock()
+		key, ok = p.lookup(kid)
+	} else if now.Sub(p.lastAttempt) >= minJWKSRefreshInterval {
+		// 限制刷新频率，避免伪造的kid导致频繁请求签发方
+		p.lastAttempt = now
+		err := p.refresh(ctx)
+		if err != nil && !ok {
+			return nil, err
+		}
+		// 签发方暂时不可用时继续使用缓存的密钥
+		key, ok = p.lookup(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	return key, nil
+}
+
+// lookup 从缓存查找公钥，令牌未携带kid且只有一把密钥时使用该密钥，调用方需持有锁
+func (p *OIDCProvider) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// refresh 获取发现文档（仅首次）和JWKS并替换缓存。调用方需持有锁，
+// 请求签发方期间释放锁，其他请求可以继续使用缓存的密钥
+func (p *OIDCProvider) refresh(ctx context.Context) error {
+	done := make(chan struct{})
+	p.refreshing = done
+	jwksURI := p.jwksURI
+	p.mu.Unlock()
+
+	keys, jwksURI, err := p.fetchKeys(ctx, jwksURI)
+
+	p.mu.Lock()
+	p.refreshing = nil
+	close(done)
+	if err != nil {
+		return err
+	}
+	p.jwksURI = jwksURI
+	p.keys = keys
+	p.fetchedAt = time.Now()
+	return nil
+}
+
+// fetchKeys 获取JWKS，jwksURI为空时先通过发现文档获取，返回公钥和使用的jwksURI
+func (p *OIDCProvider) fetchKeys(ctx context.Context, jwksURI string) (map[string]crypto.PublicKey, string, error) {
+	if jwksURI == "" {
+		discoveryURL := p.config.DiscoveryURL
+		if discoveryURL == "" {
+			discoveryURL = strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+		}
+
+		var doc discoveryDocument
+		if err := p.fetchJSON(ctx, discoveryURL, &doc); err != nil {
+			return nil, "", fmt.Errorf("oidc discovery for %s: %w", p.config.Issuer, err)
+		}
+		if doc.Issuer != p.config.Issuer {
+			return nil, "", fmt.Errorf("oidc discovery for %s: issuer mismatch %q", p.config.Issuer, doc.Issuer)
+		}
+		if doc.JWKSURI == "" {
+			return nil, "", fmt.Errorf("oidc discovery for %s: missing jwks_uri", p.config.Issuer)
+		}
+		jwksURI = doc.JWKSURI
+	}
+
+	var set JWKSet
+	if err := p.fetchJSON(ctx, jwksURI, &set); err != nil {
+		return nil, "", fmt.Errorf("oidc jwks for %s: %w", p.config.Issuer, err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, jwksURI, nil
+}
+
+// fetchJSON 获取并解析JSON文档
+func (p *OIDCProvider) fetchJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxOIDCDocumentSize)).Decode(v)
+}
+
+// cacheTTL 返回JWKS缓存时间
+func (p *OIDCProvider) cacheTTL() time.Duration {
+	if p.config.JWKSCacheSeconds > 0 {
+		return time.Duration(p.config.JWKSCacheSeconds) * time.Second
+	}
+	return defaultJWKSCache
+}
+
+// OIDCVerifier 校验外部签发方的令牌，并将外部身份映射为本地用户
+type OIDCVerifier struct {
+	providers map[string]*OIDCProvider
+	users     models.UserRepository
+}
+
+// NewOIDCVerifier 创建外部签发方校验器，未配置签发方时返回nil
+func NewOIDCVerifier(cfg *config.OIDCConfig, clockSkew time.Duration, users models.UserRepository) *OIDCVerifier {
+	if len(cfg.Providers) == 0 {
+		return nil
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	v := &OIDCVerifier{
+		providers: make(map[string]*OIDCProvider, len(cfg.Providers)),
+		users:     users,
+	}
+	for _, p := range cfg.Providers {
+		v.providers[p.Issuer] = NewOIDCProvider(p, clockSkew, client)
+	}
+	return v
+}
+
+// Handles 判断是否为已配置的外部签发方
+func (v *OIDCVerifier) Handles(issuer string) bool {
+	_, ok := v.providers[issuer]
+	return ok
+}
+
+// Verify 实现TokenVerifier
+func (v *OIDCVerifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	provider, ok := v.providers[unverifiedIssuer(token)]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown issuer", ErrInvalidToken)
+	}
+
+	claims, err := provider.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return v.principal(ctx, provider.config, claims)
+}
+
+// principal 将外部声明映射为本地调用方
+func (v *OIDCVerifier) principal(ctx context.Context, cfg config.OIDCProviderConfig, claims jwt.MapClaims) (*Principal, error) {
+	mapping := cfg.Claims
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing sub claim", ErrInvalidToken)
+	}
+	email, _ := claimValue(claims, defaultString(mapping.Email, "email")).(string)
+	if email == "" {
+		return nil, fmt.Errorf("%w: missing email claim", ErrInvalidToken)
+	}
+	verified, _ := claims["email_verified"].(bool)
+	if mapping.VerifiedEmailRequired() && !verified {
+		return nil, fmt.Errorf("%w: email not verified", ErrInvalidToken)
+	}
+
+	roles := mapRoles(claimStrings(claimValue(claims, mapping.Roles)), mapping)
+	user, err := v.localUser(ctx, cfg, claims, subject, email, verified, roles)
+	if err != nil {
+		return nil, err
+	}
+
+	// 默认只使用签发方授予的角色，本地角色需要配置显式信任
+	if cfg.TrustLocalRoles {
+		roles = mergeRoles(user.Roles, roles)
+	}
+	scope, _ := claims["scope"].(string)
+	return &Principal{
+		Subject: user.ID,
+		Email:   user.Email,
+		Roles:   roles,
+		Scopes:  strings.Fields(scope),
+		Issuer:  cfg.Issuer,
+		Method:  MethodOIDC,
+	}, nil
+}
+
+// localUser 按外部身份（iss、sub）查找关联的本地用户，关联的用户已被删除时拒绝。尚未关联时，
+// 配置允许且邮箱已验证的情况下关联邮箱相同的已有用户，否则按配置以映射后的角色自动创建
+func (v *OIDCVerifier) localUser(ctx context.Context, cfg config.OIDCProviderConfig, claims jwt.MapClaims, subject, email string, verified bool, roles []string) (*models.User, error) {
+	user, err := v.users.GetByIdentity(ctx, cfg.Issuer, subject)
+	if err == nil {
+		return activeUser(user)
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	// 邮箱由签发方控制，不能单凭邮箱接管已有的本地账号
+	existing, err := v.users.GetByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if !cfg.LinkVerifiedEmail || !verified || existing.IdentitySubject != "" {
+			return nil, fmt.Errorf("%w: %s belongs to a local user not linked to this identity", ErrInvalidToken, email)
+		}
+		existing.IdentityIssuer = cfg.Issuer
+		existing.IdentitySubject = subject
+		existing.UpdatedAt = time.Now().UTC()
+		if err := v.users.Update(ctx, existing); err != nil {
+			return v.linkedAfterConflict(ctx, cfg.Issuer, subject, err)
+		}
+		return existing, nil
+	case !errors.Is(err, repository.ErrNotFound):
+		return nil, err
+	}
+	if !cfg.AutoCreateUsers {
+		return nil, fmt.Errorf("%w: no local user for %s", ErrInvalidToken, email)
+	}
+
+	name, _ := claimValue(claims, defaultString(cfg.Claims.Name, "name")).(string)
+	if name == "" {
+		name = email
+	}
+	now := time.Now().UTC()
+	user = &models.User{
+		ID:              utils.NewID(),
+		Name:            name,
+		Email:           email,
+		Roles:           roles,
+		IdentityIssuer:  cfg.Issuer,
+		IdentitySubject: subject,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := v.users.Create(ctx, user); err != nil {
+		return v.linkedAfterConflict(ctx, cfg.Issuer, subject, err)
+	}
+	return user, nil
+}
+
+// linkedAfterConflict 处理关联或创建失败：并发请求可能已经完成了关联，此时返回关联的用户
+func (v *OIDCVerifier) linkedAfterConflict(ctx context.Context, issuer, subject string, err error) (*models.User, error) {
+	if !errors.Is(err, repository.ErrDuplicateEmail) && !errors.Is(err, repository.ErrDuplicateIdentity) &&
+		!errors.Is(err, repository.ErrVersionConflict) {
+		return nil, err
+	}
+	user, lookupErr := v.users.GetByIdentity(ctx, issuer, subject)
+	if lookupErr != nil {
+		return nil, fmt.Errorf("%w: cannot link identity: %v", ErrInvalidToken, err)
+	}
+	return activeUser(user)
+}
+
+// activeUser 拒绝已软删除的用户，删除后外部身份不能再登录，也不会自动创建新用户
+func activeUser(user *models.User) (*models.User, error) {
+	if user.DeletedAt != nil {
+		return nil, fmt.Errorf("%w: user %s is deleted", ErrInvalidToken, user.ID)
+	}
+	return user, nil
+}
+
+// MultiVerifier 按令牌的iss将外部签发方的令牌交给OIDC校验器，其余交给本地校验器
+type MultiVerifier struct {
+	local TokenVerifier
+	oidc  *OIDCVerifier
+}
+
+// NewMultiVerifier 组合本地和外部签发方校验器，oidc为nil时只使用本地校验器
+func NewMultiVerifier(local TokenVerifier, oidc *OIDCVerifier) *MultiVerifier {
+	return &MultiVerifier{local: local, oidc: oidc}
+}
+
+// Verify 实现TokenVerifier
+func (v *MultiVerifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	if v.oidc != nil && v.oidc.Handles(unverifiedIssuer(token)) {
+		return v.oidc.Verify(ctx, token)
+	}
+	return v.local.Verify(ctx, token)
+}
+
+// unverifiedIssuer 读取未校验的iss，只用于选择校验器
+func unverifiedIssuer(token string) string {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return ""
+	}
+	issuer, _ := claims.GetIssuer()
+	return issuer
+}
+
+// claimValue 按以.分隔的路径读取声明
+func claimValue(claims jwt.MapClaims, path string) interface{} {
+	if path == "" {
+		return nil
+	}
+
+	var current interface{} = map[string]interface{}(claims)
+	for _, part := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[part]
+	}
+	return current
+}
+
+// claimStrings 将数组或空格分隔的字符串声明转换为字符串切片
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// mapRoles 将外部角色映射为本地角色并去重
+func mapRoles(external []string, mapping config.OIDCClaimMapping) []string {
+	seen := make(map[string]bool)
+	var roles []string
+	for _, r := range external {
+		local, ok := mapping.RoleMapping[r]
+		if ok && !seen[local] {
+			seen[local] = true
+			roles = append(roles, local)
+		}
+	}
+	if len(roles) == 0 {
+		roles = append(roles, mapping.DefaultRoles...)
+	}
+	return roles
+}
+
+// defaultString 返回非空的value，否则返回fallback
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
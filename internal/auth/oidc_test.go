@@ -0,0 +1,471 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go-echo-app/internal/config"
+	"go-echo-app/internal/models"
+	"go-echo-app/internal/repository"
+)
+
+// testIdP 本地替身签发方，提供发现文档和可轮换的JWKS
+type testIdP struct {
+	server *httptest.Server
+
+	mu             sync.Mutex
+	keys           map[string]*rsa.PrivateKey
+	issuer         string // 发现文档中的issuer，为空时使用服务地址
+	discoveryCalls int
+	jwksCalls      int
+	down           bool
+	// jwksGate 不为nil时，JWKS请求先通知jwksStarted，再等待jwksGate关闭后才响应
+	jwksGate    chan struct{}
+	jwksStarted chan struct{}
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	idp := &testIdP{keys: make(map[string]*rsa.PrivateKey)}
+	idp.server = httptest.NewServer(http.HandlerFunc(idp.serve))
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *testIdP) serve(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	gate, started := idp.jwksGate, idp.jwksStarted
+	idp.mu.Unlock()
+	if gate != nil && r.URL.Path == "/jwks" {
+		started <- struct{}{}
+		<-gate
+	}
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	if idp.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		idp.discoveryCalls++
+		issuer := idp.issuer
+		if issuer == "" {
+			issuer = idp.server.URL
+		}
+		json.NewEncoder(w).Encode(discoveryDocument{Issuer: issuer, JWKSURI: idp.server.URL + "/jwks"})
+	case "/jwks":
+		idp.jwksCalls++
+		set := JWKSet{Keys: []JWK{}}
+		for kid, key := range idp.keys {
+			jwk, _ := NewJWK(&key.PublicKey, kid, "RS256")
+			set.Keys = append(set.Keys, jwk)
+		}
+		json.NewEncoder(w).Encode(set)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// rotate 用新密钥替换签发方发布的全部密钥
+func (idp *testIdP) rotate(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.keys = map[string]*rsa.PrivateKey{kid: key}
+	return key
+}
+
+func (idp *testIdP) calls() (discovery, jwks int) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	return idp.discoveryCalls, idp.jwksCalls
+}
+
+// sign 签发测试令牌
+func (idp *testIdP) sign(t *testing.T, key *rsa.PrivateKey, kid, audience string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   idp.server.URL,
+		"sub":   "ext-1",
+		"aud":   audience,
+		"email": "ann@example.com",
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func newTestProvider(idp *testIdP) *OIDCProvider {
+	return NewOIDCProvider(config.OIDCProviderConfig{
+		Name:     "test",
+		Issuer:   idp.server.URL,
+		Audience: "app",
+	}, 0, idp.server.Client())
+}
+
+func TestOIDCProviderDiscovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		issuer  string
+		down    bool
+		wantErr bool
+	}{
+		{name: "discovery and jwks", wantErr: false},
+		{name: "issuer mismatch in discovery document", issuer: "https://evil.example.com", wantErr: true},
+		{name: "issuer unavailable", down: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newTestIdP(t)
+			key := idp.rotate(t, "k1")
+			idp.issuer, idp.down = tt.issuer, tt.down
+
+			_, err := newTestProvider(idp).Verify(context.Background(), idp.sign(t, key, "k1", "app"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOIDCProviderRejectsClaims(t *testing.T) {
+	idp := newTestIdP(t)
+	key := idp.rotate(t, "k1")
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newTestProvider(idp)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "wrong audience", token: idp.sign(t, key, "k1", "other-app")},
+		{name: "signed by an unknown key with a known kid", token: idp.sign(t, other, "k1", "app")},
+		{name: "malformed", token: "not-a-jwt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := p.Verify(context.Background(), tt.token); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestOIDCProviderKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	idp := newTestIdP(t)
+	k1 := idp.rotate(t, "k1")
+	p := newTestProvider(idp)
+
+	if _, err := p.Verify(ctx, idp.sign(t, k1, "k1", "app")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Verify(ctx, idp.sign(t, k1, "k1", "app")); err != nil {
+		t.Fatal(err)
+	}
+	if discovery, jwks := idp.calls(); discovery != 1 || jwks != 1 {
+		t.Fatalf("calls = %d discovery, %d jwks; want the cached keys to be reused", discovery, jwks)
+	}
+
+	// 签发方轮换密钥后，刷新间隔内的未知kid不会触发刷新
+	k2 := idp.rotate(t, "k2")
+	if _, err := p.Verify(ctx, idp.sign(t, k2, "k2", "app")); err == nil {
+		t.Fatal("unknown kid accepted before the refresh interval elapsed")
+	}
+	if _, jwks := idp.calls(); jwks != 1 {
+		t.Fatalf("jwks calls = %d, want the refresh to be throttled", jwks)
+	}
+
+	// 超过刷新间隔后，未知kid触发刷新并使用新密钥
+	p.mu.Lock()
+	p.lastAttempt = time.Now().Add(-minJWKSRefreshInterval)
+	p.mu.Unlock()
+	if _, err := p.Verify(ctx, idp.sign(t, k2, "k2", "app")); err != nil {
+		t.Fatal(err)
+	}
+	if discovery, jwks := idp.calls(); discovery != 1 || jwks != 2 {
+		t.Fatalf("calls = %d discovery, %d jwks; want one jwks refresh without rediscovery", discovery, jwks)
+	}
+
+	// 签发方下线的旧密钥不再被接受
+	if _, err := p.Verify(ctx, idp.sign(t, k1, "k1", "app")); err == nil {
+		t.Fatal("retired key still accepted")
+	}
+}
+
+func TestOIDCProviderKeepsCachedKeysWhenIssuerIsDown(t *testing.T) {
+	ctx := context.Background()
+	idp := newTestIdP(t)
+	key := idp.rotate(t, "k1")
+	p := newTestProvider(idp)
+
+	if _, err := p.Verify(ctx, idp.sign(t, key, "k1", "app")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 缓存过期且签发方不可用时继续使用缓存的密钥
+	idp.mu.Lock()
+	idp.down = true
+	idp.mu.Unlock()
+	p.mu.Lock()
+	p.fetchedAt = time.Now().Add(-2 * defaultJWKSCache)
+	p.lastAttempt = time.Time{}
+	p.mu.Unlock()
+
+	if _, err := p.Verify(ctx, idp.sign(t, key, "k1", "app")); err != nil {
+		t.Fatalf("Verify() with cached keys error = %v", err)
+	}
+}
+
+func TestOIDCProviderRefreshDoesNotBlockCachedKeys(t *testing.T) {
+	ctx := context.Background()
+	idp := newTestIdP(t)
+	k1 := idp.rotate(t, "k1")
+	p := newTestProvider(idp)
+	if _, err := p.Verify(ctx, idp.sign(t, k1, "k1", "app")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 签发方轮换到k2，且JWKS响应被挂起
+	k2 := idp.rotate(t, "k2")
+	gate, started := make(chan struct{}), make(chan struct{}, 1)
+	idp.mu.Lock()
+	idp.jwksGate, idp.jwksStarted = gate, started
+	idp.mu.Unlock()
+	p.mu.Lock()
+	p.lastAttempt = time.Time{}
+	p.mu.Unlock()
+
+	const callers = 5
+	token := idp.sign(t, k2, "k2", "app")
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			_, err := p.Verify(ctx, token)
+			errs <- err
+		}()
+	}
+	<-started
+
+	// 刷新进行中时，缓存中的密钥仍可立即使用
+	cached := make(chan error, 1)
+	go func() {
+		_, err := p.Verify(ctx, idp.sign(t, k1, "k1", "app"))
+		cached <- err
+	}()
+	select {
+	case err := <-cached:
+		if err != nil {
+			t.Fatalf("Verify() with cached key during refresh error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Verify() with cached key blocked by the JWKS refresh")
+	}
+
+	close(gate)
+	for i := 0; i < callers; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Verify() with rotated key error = %v", err)
+		}
+	}
+	if _, jwks := idp.calls(); jwks != 2 {
+		t.Fatalf("jwks calls = %d, want concurrent callers to share one refresh", jwks)
+	}
+}
+
+const testIssuer = "https://sso.example.com"
+
+func testOIDCProvider() config.OIDCProviderConfig {
+	return config.OIDCProviderConfig{
+		Issuer: testIssuer,
+		Claims: config.OIDCClaimMapping{
+			Roles:        "roles",
+			RoleMapping:  map[string]string{"staff": "user"},
+			DefaultRoles: []string{"user"},
+		},
+		AutoCreateUsers: true,
+	}
+}
+
+func testOIDCClaims(sub, email string, verified bool) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            testIssuer,
+		"sub":            sub,
+		"email":          email,
+		"email_verified": verified,
+		"roles":          []interface{}{"staff"},
+	}
+}
+
+// seedLocalAdmin 创建一个没有关联外部身份的本地管理员
+func seedLocalAdmin(t *testing.T, users models.UserRepository) *models.User {
+	t.Helper()
+	now := time.Now().UTC()
+	admin := &models.User{ID: "admin", Name: "Admin", Email: "admin@example.com", Roles: []string{"admin"}, CreatedAt: now, UpdatedAt: now}
+	if err := users.Create(context.Background(), admin); err != nil {
+		t.Fatal(err)
+	}
+	return admin
+}
+
+func TestOIDCPrincipal(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(cfg *config.OIDCProviderConfig)
+		claims  jwt.MapClaims
+		wantErr bool
+		wantID  string
+		admin   bool
+	}{
+		{
+			name:    "unverified email is rejected by default",
+			claims:  testOIDCClaims("u1", "new@example.com", false),
+			wantErr: true,
+		},
+		{
+			name:    "missing sub is rejected",
+			claims:  testOIDCClaims("", "new@example.com", true),
+			wantErr: true,
+		},
+		{
+			name:   "unverified email allowed when explicitly disabled",
+			mutate: func(cfg *config.OIDCProviderConfig) { f := false; cfg.Claims.RequireVerifiedEmail = &f },
+			claims: testOIDCClaims("u1", "new@example.com", false),
+		},
+		{
+			name:    "email of an unlinked local user is not taken over",
+			claims:  testOIDCClaims("u1", "admin@example.com", true),
+			wantErr: true,
+		},
+		{
+			name:   "linking by verified email requires opt-in and drops local roles",
+			mutate: func(cfg *config.OIDCProviderConfig) { cfg.LinkVerifiedEmail = true },
+			claims: testOIDCClaims("u1", "admin@example.com", true),
+			wantID: "admin",
+		},
+		{
+			name: "trusted local roles are merged",
+			mutate: func(cfg *config.OIDCProviderConfig) {
+				cfg.LinkVerifiedEmail = true
+				cfg.TrustLocalRoles = true
+			},
+			claims: testOIDCClaims("u1", "admin@example.com", true),
+			wantID: "admin",
+			admin:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := repository.NewMemoryUserRepository()
+			seedLocalAdmin(t, users)
+			cfg := testOIDCProvider()
+			if tt.mutate != nil {
+				tt.mutate(&cfg)
+			}
+			v := &OIDCVerifier{users: users}
+
+			p, err := v.principal(context.Background(), cfg, tt.claims)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Fatalf("principal() error = %v, want ErrInvalidToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantID != "" && p.Subject != tt.wantID {
+				t.Fatalf("Subject = %s, want %s", p.Subject, tt.wantID)
+			}
+			if got := p.HasAnyRole("admin"); got != tt.admin {
+				t.Fatalf("admin role = %v, want %v (roles %v)", got, tt.admin, p.Roles)
+			}
+		})
+	}
+}
+
+func TestOIDCPrincipalFollowsIdentityNotEmail(t *testing.T) {
+	users := repository.NewMemoryUserRepository()
+	v := &OIDCVerifier{users: users}
+	cfg := testOIDCProvider()
+
+	first, err := v.principal(context.Background(), cfg, testOIDCClaims("u1", "old@example.com", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 签发方修改了邮箱，仍映射到同一个本地用户
+	again, err := v.principal(context.Background(), cfg, testOIDCClaims("u1", "renamed@example.com", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Subject != first.Subject {
+		t.Fatalf("Subject = %s, want %s", again.Subject, first.Subject)
+	}
+
+	// 另一个外部身份使用相同邮箱不能接管该用户
+	if _, err := v.principal(context.Background(), cfg, testOIDCClaims("u2", "old@example.com", true)); err == nil {
+		t.Fatal("second identity with the same email was accepted")
+	}
+}
+
+func TestOIDCPrincipalDeletedUser(t *testing.T) {
+	ctx := context.Background()
+	users := repository.NewMemoryUserRepository()
+	v := &OIDCVerifier{users: users}
+	cfg := testOIDCProvider()
+
+	p, err := v.principal(ctx, cfg, testOIDCClaims("u1", "ann@example.com", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := users.GetByID(ctx, p.Subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deletedAt := time.Now().UTC()
+	user.DeletedAt = &deletedAt
+	if err := users.Update(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	// 删除的用户不能再登录，也不会为同一外部身份创建新用户
+	if _, err := v.principal(ctx, cfg, testOIDCClaims("u1", "ann@example.com", true)); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("principal() for deleted user error = %v, want ErrInvalidToken", err)
+	}
+
+	// 已删除用户的邮箱可以由其他外部身份使用
+	other, err := v.principal(ctx, cfg, testOIDCClaims("u2", "ann@example.com", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Subject == p.Subject {
+		t.Fatal("new identity was mapped to the deleted user")
+	}
+}
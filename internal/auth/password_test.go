@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"go-echo-app/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 测试使用较小的参数，避免拖慢测试
+func testArgon2Config() *config.PasswordConfig {
+	return &config.PasswordConfig{Algorithm: PasswordArgon2id, Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 1, BcryptCost: bcrypt.MinCost}
+}
+
+func testBcryptConfig() *config.PasswordConfig {
+	cfg := testArgon2Config()
+	cfg.Algorithm = PasswordBcrypt
+	return cfg
+}
+
+func newTestHasher(t *testing.T, cfg *config.PasswordConfig) *PasswordHasher {
+	t.Helper()
+	h, err := NewPasswordHasher(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestNewPasswordHasherValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(cfg *config.PasswordConfig)
+	}{
+		{name: "unknown algorithm", mutate: func(cfg *config.PasswordConfig) { cfg.Algorithm = "md5" }},
+		{name: "argon2 zero iterations", mutate: func(cfg *config.PasswordConfig) { cfg.Argon2Iterations = 0 }},
+		{name: "argon2 memory below 8*p", mutate: func(cfg *config.PasswordConfig) { cfg.Argon2Memory = 8; cfg.Argon2Parallelism = 2 }},
+		{name: "argon2 parallelism above 255", mutate: func(cfg *config.PasswordConfig) { cfg.Argon2Memory = 1 << 20; cfg.Argon2Parallelism = 256 }},
+		{name: "bcrypt cost too low", mutate: func(cfg *config.PasswordConfig) { cfg.Algorithm = PasswordBcrypt; cfg.BcryptCost = bcrypt.MinCost - 1 }},
+		{name: "bcrypt cost too high", mutate: func(cfg *config.PasswordConfig) { cfg.Algorithm = PasswordBcrypt; cfg.BcryptCost = bcrypt.MaxCost + 1 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testArgon2Config()
+			tt.mutate(cfg)
+			if _, err := NewPasswordHasher(cfg); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestPasswordHasherVerify(t *testing.T) {
+	argon := newTestHasher(t, testArgon2Config())
+	bcryptHasher := newTestHasher(t, testBcryptConfig())
+
+	stronger := testArgon2Config()
+	stronger.Argon2Iterations = 2
+	strongerArgon := newTestHasher(t, stronger)
+
+	argonHash, err := argon.Hash("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := bcryptHasher.Hash("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		hasher     *PasswordHasher
+		hash       string
+		password   string
+		wantOK     bool
+		wantRehash bool
+		wantErr    error
+	}{
+		{name: "argon2 match", hasher: argon, hash: argonHash, password: "s3cret", wantOK: true},
+		{name: "argon2 mismatch", hasher: argon, hash: argonHash, password: "wrong"},
+		{name: "argon2 with changed parameters", hasher: strongerArgon, hash: argonHash, password: "s3cret", wantOK: true, wantRehash: true},
+		{name: "bcrypt match", hasher: bcryptHasher, hash: bcryptHash, password: "s3cret", wantOK: true},
+		{name: "bcrypt mismatch", hasher: bcryptHasher, hash: bcryptHash, password: "wrong"},
+		{name: "bcrypt hash under argon2 config", hasher: argon, hash: bcryptHash, password: "s3cret", wantOK: true, wantRehash: true},
+		{name: "argon2 hash under bcrypt config", hasher: bcryptHasher, hash: argonHash, password: "s3cret", wantOK: true, wantRehash: true},
+		{name: "empty hash never matches", hasher: argon, hash: "", password: ""},
+		{name: "unknown format", hasher: argon, hash: "plaintext", password: "plaintext", wantErr: ErrUnknownPasswordHash},
+		{name: "truncated argon2 hash", hasher: argon, hash: "$argon2id$v=19$m=64,t=1,p=1$c2FsdA", password: "s3cret", wantErr: ErrUnknownPasswordHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, rehash, err := tt.hasher.Verify(tt.hash, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOK || rehash != tt.wantRehash {
+				t.Fatalf("Verify() = (%v, %v), want (%v, %v)", ok, rehash, tt.wantOK, tt.wantRehash)
+			}
+		})
+	}
+}
+
+func TestPasswordHasherVerifyRejectsMalformedArgon2(t *testing.T) {
+	h := newTestHasher(t, testArgon2Config())
+	for _, hash := range []string{
+		"$argon2id$v=18$m=64,t=1,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$!!!$a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$",
+	} {
+		if _, _, err := h.Verify(hash, "s3cret"); err == nil {
+			t.Errorf("Verify(%q): expected error", hash)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 认证方式
+const (
+	MethodJWT = "jwt"
+)
+
+// 认证错误
+var (
+	ErrInvalidToken = errors.New("invalid or expired token")
+)
+
+// Claims 本系统签发和校验的JWT声明
+type Claims struct {
+	jwt.RegisteredClaims
+	Email string   `json:"email,omitempty"`
+	Roles []string `json:"roles,omitempty"`
+	Scope string   `json:"scope,omitempty"`
+}
+
+// Principal 通过认证的调用方
+type Principal struct {
+	Subject string   `json:"sub"`
+	Email   string   `json:"email,omitempty"`
+	Roles   []string `json:"roles,omitempty"`
+	Scopes  []string `json:"scopes,omitempty"`
+	// Permissions 由角色和scopes计算出的有效权限，认证中间件按Policy填充
+	Permissions []string `json:"permissions,omitempty"`
+	Issuer      string   `json:"iss,omitempty"`
+	Method      string   `json:"method"`
+	KeyID       string   `json:"key_id,omitempty"` // API Key认证时的Key ID
+	Claims      *Claims  `json:"-"`
+}
+
+// TokenVerifier 令牌校验器
+type TokenVerifier interface {
+	// Verify 校验令牌并返回调用方，令牌无效时返回ErrInvalidToken
+	Verify(ctx context.Context, token string) (*Principal, error)
+}
+
+// principalFromClaims 由JWT声明构造调用方
+func principalFromClaims(claims *Claims, method string) *Principal {
+	return &Principal{
+		Subject: claims.Subject,
+		Email:   claims.Email,
+		Roles:   claims.Roles,
+		Scopes:  strings.Fields(claims.Scope),
+		Issuer:  claims.Issuer,
+		Method:  method,
+		Claims:  claims,
+	}
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"sort"
+	"strings"
+)
+
+// 内置权限
+const (
+	PermissionUsersRead     = "users:read"
+	PermissionUsersWrite    = "users:write"
+	PermissionUsersAdmin    = "users:admin" // 查看和恢复已删除的用户
+	PermissionProxyUse      = "proxy:use"
+	PermissionProxyAdmin    = "proxy:admin"
+	PermissionAPIKeysManage = "api_keys:manage"
+)
+
+// 内置角色
+const (
+	RoleAdmin   = "admin"
+	RoleUser    = "user"
+	RoleService = "service"
+)
+
+// DefaultRoles 内置角色的权限，可通过配置覆盖或增加角色
+var DefaultRoles = map[string][]string{
+	RoleAdmin:   {"*"},
+	RoleUser:    {PermissionUsersRead, PermissionProxyUse, PermissionAPIKeysManage},
+	RoleService: {PermissionProxyUse},
+}
+
+// Policy 角色到权限的映射
+type Policy struct {
+	roles map[string][]string
+}
+
+// NewPolicy 创建权限策略，roles与内置角色合并，同名角色以roles为准
+func NewPolicy(roles map[string][]string) *Policy {
+	merged := make(map[string][]string, len(DefaultRoles)+len(roles))
+	for role, permissions := range DefaultRoles {
+		merged[role] = permissions
+	}
+	for role, permissions := range roles {
+		merged[role] = permissions
+	}
+	return &Policy{roles: merged}
+}
+
+// Permissions 计算调用方的有效权限。
+// 调用方带有scopes（如API Key）时，有效权限为角色授予的权限中被scopes覆盖的部分
+func (p *Policy) Permissions(principal *Principal) []string {
+	seen := make(map[string]bool)
+	var granted []string
+	for _, role := range principal.Roles {
+		for _, permission := range p.roles[role] {
+			if !seen[permission] {
+				seen[permission] = true
+				granted = append(granted, permission)
+			}
+		}
+	}
+	if principal.Method != MethodAPIKey || len(principal.Scopes) == 0 {
+		sort.Strings(granted)
+		return granted
+	}
+
+	var effective []string
+	for _, scope := range principal.Scopes {
+		if permissionGranted(granted, scope) {
+			effective = append(effective, scope)
+		}
+	}
+	sort.Strings(effective)
+	return effective
+}
+
+// HasPermission 判断调用方是否拥有权限，支持 * 和 resource:* 通配
+func (p *Principal) HasPermission(permission string) bool {
+	return permissionGranted(p.Permissions, permission)
+}
+
+// HasAnyRole 判断调用方是否拥有任一角色
+func (p *Principal) HasAnyRole(roles ...string) bool {
+	for _, role := range roles {
+		if containsString(p.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// permissionGranted 判断权限列表是否覆盖permission
+func permissionGranted(granted []string, permission string) bool {
+	for _, g := range granted {
+		if g == "*" || g == permission {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(g, "*"); ok && strings.HasPrefix(permission, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeRoles 合并角色并去重
+func mergeRoles(lists ...[]string) []string {
+	var roles []string
+	for _, list := range lists {
+		for _, role := range list {
+			if !containsString(roles, role) {
+				roles = append(roles, role)
+			}
+		}
+	}
+	return roles
+}
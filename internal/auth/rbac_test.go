@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPolicyPermissions(t *testing.T) {
+	policy := NewPolicy(map[string][]string{
+		"auditor": {PermissionUsersRead},
+		RoleUser:  {PermissionUsersRead, "proxy:*"},
+	})
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		want      []string
+	}{
+		{name: "no roles", principal: &Principal{}, want: nil},
+		{name: "admin has everything", principal: &Principal{Roles: []string{RoleAdmin}}, want: []string{"*"}},
+		{name: "configured role", principal: &Principal{Roles: []string{"auditor"}}, want: []string{PermissionUsersRead}},
+		{name: "configured role overrides built-in", principal: &Principal{Roles: []string{RoleUser}}, want: []string{"proxy:*", PermissionUsersRead}},
+		{name: "roles are merged", principal: &Principal{Roles: []string{"auditor", RoleService}}, want: []string{PermissionProxyUse, PermissionUsersRead}},
+		{name: "unknown role grants nothing", principal: &Principal{Roles: []string{"ghost"}}, want: nil},
+		{
+			name:      "api key scopes narrow role permissions",
+			principal: &Principal{Roles: []string{RoleUser}, Scopes: []string{PermissionProxyUse}, Method: MethodAPIKey},
+			want:      []string{PermissionProxyUse},
+		},
+		{
+			name:      "api key scopes cannot exceed role permissions",
+			principal: &Principal{Roles: []string{"auditor"}, Scopes: []string{PermissionUsersRead, PermissionUsersWrite}, Method: MethodAPIKey},
+			want:      []string{PermissionUsersRead},
+		},
+		{
+			name:      "scopes only narrow api keys",
+			principal: &Principal{Roles: []string{"auditor"}, Scopes: []string{"openid"}, Method: MethodJWT},
+			want:      []string{PermissionUsersRead},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Permissions(tt.principal); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Permissions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrincipalHasPermission(t *testing.T) {
+	tests := []struct {
+		granted    []string
+		permission string
+		want       bool
+	}{
+		{granted: []string{"*"}, permission: PermissionProxyAdmin, want: true},
+		{granted: []string{"users:*"}, permission: PermissionUsersWrite, want: true},
+		{granted: []string{"users:*"}, permission: PermissionProxyUse, want: false},
+		{granted: []string{PermissionUsersRead}, permission: PermissionUsersRead, want: true},
+		{granted: []string{PermissionUsersRead}, permission: PermissionUsersWrite, want: false},
+		{granted: nil, permission: PermissionUsersRead, want: false},
+	}
+	for _, tt := range tests {
+		p := &Principal{Permissions: tt.granted}
+		if got := p.HasPermission(tt.permission); got != tt.want {
+			t.Errorf("%v HasPermission(%s) = %v, want %v", tt.granted, tt.permission, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// 刷新令牌错误
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+)
+
+// RefreshToken 刷新令牌记录，同一次登录轮换出的令牌属于同一个家族
+type RefreshToken struct {
+	Hash      string
+	FamilyID  string
+	UserID    string
+	ExpiresAt time.Time
+	Used      bool
+}
+
+// RefreshTokenStore 刷新令牌存储，只保存令牌摘要
+type RefreshTokenStore interface {
+	// Save 保存新签发的刷新令牌
+	Save(token *RefreshToken) error
+	// Consume 原子地将令牌标记为已使用并返回记录。
+	// 令牌已被使用过时返回记录和ErrRefreshTokenReused，不存在、过期或家族已吊销时返回ErrInvalidRefreshToken
+	Consume(hash string) (*RefreshToken, error)
+	// Get 返回令牌记录，不改变其状态
+	Get(hash string) (*RefreshToken, error)
+	// RevokeFamily 吊销整个令牌家族
+	RevokeFamily(familyID string) error
+	// RevokeUser 吊销用户的全部令牌家族
+	RevokeUser(userID string) error
+}
+
+// HashRefreshToken 计算刷新令牌摘要
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// refreshFamily 令牌家族状态
+type refreshFamily struct {
+	userID    string
+	revoked   bool
+	expiresAt time.Time
+}
+
+// MemoryRefreshTokenStore 基于内存的刷新令牌存储
+type MemoryRefreshTokenStore struct {
+	mu        sync.Mutex
+	tokens    map[string]*RefreshToken
+	families  map[string]*refreshFamily
+	lastSweep time.Time
+}
+
+// NewMemoryRefreshTokenStore 创建内存刷新令牌存储
+func NewMemoryRefreshTokenStore() *MemoryRefreshTokenStore {
+	return &MemoryRefreshTokenStore{
+		tokens:   make(map[string]*RefreshToken),
+		families: make(map[string]*refreshFamily),
+	}
+}
+
+// Save 实现RefreshTokenStore
+func (s *MemoryRefreshTokenStore) Save(token *RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(time.Now())
+
+	family, ok := s.families[token.FamilyID]
+	if !ok {
+		family = &refreshFamily{userID: token.UserID}
+		s.families[token.FamilyID] = family
+	}
+	if token.ExpiresAt.After(family.expiresAt) {
+		family.expiresAt = token.ExpiresAt
+	}
+
+	stored := *token
+	s.tokens[token.Hash] = &stored
+	return nil
+}
+
+// Consume 实现RefreshTokenStore
+func (s *MemoryRefreshTokenStore) Consume(hash string) (*RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, err := s.lookup(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	record := *token
+	if token.Used {
+		return &record, ErrRefreshTokenReused
+	}
+	token.Used = true
+	return &record, nil
+}
+
+// Get 实现RefreshTokenStore
+func (s *MemoryRefreshTokenStore) Get(hash string) (*RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, err := s.lookup(hash)
+	if err != nil {
+		return nil, err
+	}
+	record := *token
+	return &record, nil
+}
+
+// RevokeFamily 实现RefreshTokenStore
+func (s *MemoryRefreshTokenStore) RevokeFamily(familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if family, ok := s.families[familyID]; ok {
+		family.revoked = true
+	}
+	return nil
+}
+
+// RevokeUser 实现RefreshTokenStore
+func (s *MemoryRefreshTokenStore) RevokeUser(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, family := range s.families {
+		if family.userID == userID {
+			family.revoked = true
+		}
+	}
+	return nil
+}
+
+// lookup 查找有效的令牌记录，调用方需持有锁
+func (s *MemoryRefreshTokenStore) lookup(hash string) (*RefreshToken, error) {
+	token, ok := s.tokens[hash]
+	if !ok || !time.Now().Before(token.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if family := s.families[token.FamilyID]; family == nil || family.revoked {
+		return nil, ErrInvalidRefreshToken
+	}
+	return token, nil
+}
+
+// sweep 定期删除过期的令牌和家族，调用方需持有锁
+func (s *MemoryRefreshTokenStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for hash, token := range s.tokens {
+		if !now.Before(token.ExpiresAt) {
+			delete(s.tokens, hash)
+		}
+	}
+	for id, family := range s.families {
+		if !now.Before(family.expiresAt) {
+			delete(s.families, id)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go-echo-app/internal/models"
+	"go-echo-app/internal/repository"
+	"go-echo-app/pkg/utils"
+)
+
+// ErrInvalidCredentials 邮箱或密码错误
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+// ErrWrongPassword 修改密码时当前密码错误
+var ErrWrongPassword = errors.New("current password is incorrect")
+
+// TokenPair 登录或刷新后返回的令牌
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// Service 登录、刷新和注销
+type Service struct {
+	users      models.UserRepository
+	issuer     *TokenIssuer
+	refresh    RefreshTokenStore
+	refreshTTL time.Duration
+	passwords  *PasswordHasher
+	dummyHash  string // 用户不存在时用于等时校验
+}
+
+// NewService 创建认证服务
+func NewService(users models.UserRepository, issuer *TokenIssuer, refresh RefreshTokenStore, refreshTTL time.Duration, passwords *PasswordHasher) (*Service, error) {
+	dummyHash, err := passwords.Hash(utils.RandomToken(16))
+	if err != nil {
+		return nil, err
+	}
+	return &Service{
+		users:      users,
+		issuer:     issuer,
+		refresh:    refresh,
+		refreshTTL: refreshTTL,
+		passwords:  passwords,
+		dummyHash:  dummyHash,
+	}, nil
+}
+
+// Login 校验邮箱和密码，成功后开启新的刷新令牌家族。
+// 密码哈希的算法或参数已过时时，用本次提交的密码重新计算
+func (s *Service) Login(ctx context.Context, email, password string) (*TokenPair, error) {
+	user, err := s.users.GetByEmail(ctx, email)
+	if errors.Is(err, repository.ErrNotFound) {
+		// 用户不存在时同样计算一次哈希，避免通过响应时间探测邮箱
+		_, _, _ = s.passwords.Verify(s.dummyHash, password)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ok, rehash, err := s.passwords.Verify(user.Password, password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	if rehash {
+		// 升级失败不影响登录，下次登录时再试
+		if err := s.setPassword(ctx, user, password); err != nil {
+			log.Printf("upgrade password hash of user %s: %v", user.ID, err)
+		}
+	}
+
+	return s.issue(user, utils.NewID())
+}
+
+// ChangePassword 校验当前密码后修改用户密码，并吊销该用户的全部刷新令牌，
+// 持有旧刷新令牌的其他会话需要重新登录
+func (s *Service) ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	ok, _, err := s.passwords.Verify(user.Password, currentPassword)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrWrongPassword
+	}
+
+	user.UpdatedAt = time.Now().UTC()
+	if err := s.setPassword(ctx, user, newPassword); err != nil {
+		return err
+	}
+	return s.refresh.RevokeUser(user.ID)
+}
+
+// setPassword 用当前配置的算法计算哈希并保存
+func (s *Service) setPassword(ctx context.Context, user *models.User, password string) error {
+	hash, err := s.passwords.Hash(password)
+	if err != nil {
+		return err
+	}
+	user.Password = hash
+	return s.users.Update(ctx, user)
+}
+
+// Refresh 用刷新令牌换取新的令牌对，旧令牌随即失效。
+// 已使用过的令牌再次出现说明令牌可能泄露，此时吊销整个家族
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	record, err := s.refresh.Consume(HashRefreshToken(refreshToken))
+	if errors.Is(err, ErrRefreshTokenReused) {
+		if revokeErr := s.refresh.RevokeFamily(record.FamilyID); revokeErr != nil {
+			return nil, revokeErr
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.GetByID(ctx, record.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issue(user, record.FamilyID)
+}
+
+// Logout 吊销刷新令牌所在的家族，令牌无效时不做处理
+func (s *Service) Logout(refreshToken string) error {
+	record, err := s.refresh.Get(HashRefreshToken(refreshToken))
+	if errors.Is(err, ErrInvalidRefreshToken) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.refresh.RevokeFamily(record.FamilyID)
+}
+
+// issue 签发访问令牌和属于familyID的新刷新令牌
+func (s *Service) issue(user *models.User, familyID string) (*TokenPair, error) {
+	accessToken, accessExpiresAt, err := s.issuer.Issue(user)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken := utils.RandomToken(32)
+	refreshExpiresAt := time.Now().Add(s.refreshTTL)
+	if err := s.refresh.Save(&RefreshToken{
+		Hash:      HashRefreshToken(refreshToken),
+		FamilyID:  familyID,
+		UserID:    user.ID,
+		ExpiresAt: refreshExpiresAt,
+	}); err != nil {
+		return nil, fmt.Errorf("save refresh token: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
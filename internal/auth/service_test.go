@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-echo-app/internal/config"
+	"go-echo-app/internal/models"
+	"go-echo-app/internal/repository"
+)
+
+func newTestService(t *testing.T) (*Service, models.UserRepository) {
+	t.Helper()
+	passwords, err := NewPasswordHasher(&config.PasswordConfig{Algorithm: PasswordBcrypt, BcryptCost: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, err := NewTokenIssuer(&config.JWTConfig{Algorithm: "HS256", Secret: "test-secret", ExpireTime: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := repository.NewMemoryUserRepository()
+	s, err := NewService(users, issuer, NewMemoryRefreshTokenStore(), time.Hour, passwords)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, users
+}
+
+func TestChangePasswordRevokesRefreshTokens(t *testing.T) {
+	ctx := context.Background()
+	s, users := newTestService(t)
+
+	hash, err := s.passwords.Hash("old-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	for _, user := range []*models.User{
+		{ID: "ann", Name: "Ann", Email: "ann@example.com", Password: hash, CreatedAt: now, UpdatedAt: now},
+		{ID: "bob", Name: "Bob", Email: "bob@example.com", Password: hash, CreatedAt: now, UpdatedAt: now},
+	} {
+		if err := users.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 两个会话各自属于一个令牌家族
+	laptop, err := s.Login(ctx, "ann@example.com", "old-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	phone, err := s.Login(ctx, "ann@example.com", "old-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.Login(ctx, "bob@example.com", "old-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.ChangePassword(ctx, "ann", "wrong", "new-password"); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("ChangePassword() with wrong password error = %v, want ErrWrongPassword", err)
+	}
+	if err := s.ChangePassword(ctx, "ann", "old-password", "new-password"); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, pair := range map[string]*TokenPair{"laptop": laptop, "phone": phone} {
+		if _, err := s.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Fatalf("Refresh(%s) after password change error = %v, want ErrInvalidRefreshToken", name, err)
+		}
+	}
+	if _, err := s.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Fatalf("Refresh() of another user error = %v", err)
+	}
+
+	if _, err := s.Login(ctx, "ann@example.com", "old-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Login() with old password error = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := s.Login(ctx, "ann@example.com", "new-password"); err != nil {
+		t.Fatalf("Login() with new password error = %v", err)
+	}
+}
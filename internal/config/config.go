@@ -1,61 +1,234 @@
 package config
 
 import (
+	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Config 应用程序配置结构体
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	JWT       JWTConfig
+	Password  PasswordConfig
+	Users     UsersConfig
+	OIDC      OIDCConfig
+	RBAC      RBACConfig
+	Proxy     ProxyConfig
+	RateLimit RateLimitConfig
+	Redis     RedisConfig
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Port         string
-	Host         string
-	ReadTimeout  int
-	WriteTimeout int
+	Port           string
+	Host           string
+	ReadTimeout    int
+	WriteTimeout   int
+	TrustedProxies []string // 可信代理网段（CIDR），只信任来自这些地址的X-Forwarded-For
 }
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	Driver          string // memory、postgres或sqlite
+	Host            string
+	Port            string
+	User            string
+	Password        string
+	DBName          string
+	SSLMode         string
+	Path            string // SQLite数据库文件
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime int  // 秒
+	ConnectTimeout  int  // 秒
+	AutoMigrate     bool // 启动时执行未执行的迁移
+}
+
+// PostgresDSN 由配置组装Postgres连接串
+func (d *DatabaseConfig) PostgresDSN() string {
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(d.User, d.Password),
+		Host:   net.JoinHostPort(d.Host, d.Port),
+		Path:   "/" + d.DBName,
+	}
+	q := url.Values{}
+	q.Set("sslmode", d.SSLMode)
+	if d.ConnectTimeout > 0 {
+		q.Set("connect_timeout", strconv.Itoa(d.ConnectTimeout))
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// SQLiteDSN 由配置组装SQLite连接串，等待写锁的时间与连接超时一致；
+// 时间按可排序的文本格式保存，以便按时间比较和排序；事务开始时即获取写锁，避免并发升级锁时死锁
+func (d *DatabaseConfig) SQLiteDSN() string {
+	q := url.Values{}
+	q.Set("_time_format", "sqlite")
+	q.Set("_txlock", "immediate")
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Add("_pragma", "journal_mode(WAL)")
+	q.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", d.ConnectTimeout*1000))
+	return "file:" + d.Path + "?" + q.Encode()
 }
 
 // JWTConfig JWT配置
 type JWTConfig struct {
-	Secret     string
-	ExpireTime int // 小时
+	Secret            string
+	ExpireTime        int    // 小时
+	Algorithm         string // HS256、RS256或ES256
+	PublicKeyFile     string // RS256/ES256验签公钥（PEM）
+	PrivateKeyFile    string // RS256/ES256签名私钥（PEM），签发令牌时使用
+	Issuer            string
+	Audience          string
+	ClockSkew         int // 秒，校验exp/nbf/iat时允许的时钟偏差
+	AuthEnabled       bool
+	PublicRoutes      []string // 免认证路由，格式 "METHOD /path" 或 "/path"，支持以*结尾的前缀匹配
+	RefreshExpireTime int      // 小时，刷新令牌有效期
+	AdminEmail        string   // 启动时创建的初始管理员
+	AdminPassword     string
+	KeysDir           string // RS256/ES256生成的私钥保存目录，多副本部署时应为共享目录；为空时只保存在内存中且不能轮换
+	KeyRotation       int    // 小时，签名密钥轮换间隔，0表示不轮换
+}
+
+// minJWTSecretLength HS*共享密钥的最短长度（字节），与HS256的输出长度相同
+const minJWTSecretLength = 32
+
+// placeholderJWTSecrets 示例配置中的占位密钥，任何人都能查到，不能用于签名
+var placeholderJWTSecrets = []string{"your-secret-key", "your-secret-key-here"}
+
+// Validate 检查启用认证时HS*共享密钥是否足够安全：
+// 密钥为空、是示例中的占位值或短于32字节时，任何人都可能伪造带admin角色的令牌
+func (j *JWTConfig) Validate() error {
+	if !j.AuthEnabled || !strings.HasPrefix(strings.ToUpper(j.Algorithm), "HS") {
+		return nil
+	}
+	if j.Secret == "" {
+		return fmt.Errorf("JWT_SECRET is required when AUTH_ENABLED=true and JWT_ALGORITHM=%s", j.Algorithm)
+	}
+	for _, placeholder := range placeholderJWTSecrets {
+		if j.Secret == placeholder {
+			return fmt.Errorf("JWT_SECRET must not be the example value %q", placeholder)
+		}
+	}
+	if len(j.Secret) < minJWTSecretLength {
+		return fmt.Errorf("JWT_SECRET must be at least %d bytes, got %d", minJWTSecretLength, len(j.Secret))
+	}
+	return nil
+}
+
+// PasswordConfig 密码哈希配置
+type PasswordConfig struct {
+	Algorithm         string // argon2id或bcrypt，用于新计算的哈希
+	Argon2Memory      int    // KiB
+	Argon2Iterations  int
+	Argon2Parallelism int
+	BcryptCost        int
+}
+
+// UsersConfig 用户资源配置
+type UsersConfig struct {
+	RequireIfMatch   bool // 修改和删除用户时必须携带If-Match，缺少时返回428
+	DeletedRetention int  // 小时，软删除的用户保留多久后永久删除，0表示不删除
+	PurgeInterval    int  // 分钟，清理已删除用户的间隔
 }
 
 // LoadConfig 加载配置
 func LoadConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port:         getEnv("PORT", "8080"),
-			Host:         getEnv("HOST", "localhost"),
-			ReadTimeout:  getEnvAsInt("READ_TIMEOUT", 30),
-			WriteTimeout: getEnvAsInt("WRITE_TIMEOUT", 30),
+			Port:           getEnv("PORT", "8080"),
+			Host:           getEnv("HOST", "localhost"),
+			ReadTimeout:    getEnvAsInt("READ_TIMEOUT", 30),
+			WriteTimeout:   getEnvAsInt("WRITE_TIMEOUT", 30),
+			TrustedProxies: getEnvAsSlice("TRUSTED_PROXIES", nil),
 		},
 		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", ""),
-			DBName:   getEnv("DB_NAME", "go_echo_app"),
-			SSLMode:  getEnv("DB_SSL_MODE", "disable"),
+			Driver:          getEnv("DB_DRIVER", "memory"),
+			Host:            getEnv("DB_HOST", "localhost"),
+			Port:            getEnv("DB_PORT", "5432"),
+			User:            getEnv("DB_USER", "postgres"),
+			Password:        getEnv("DB_PASSWORD", ""),
+			DBName:          getEnv("DB_NAME", "go_echo_app"),
+			SSLMode:         getEnv("DB_SSL_MODE", "disable"),
+			Path:            getEnv("DB_PATH", "go_echo_app.db"),
+			MaxOpenConns:    getEnvAsInt("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    getEnvAsInt("DB_MAX_IDLE_CONNS", 5),
+			ConnMaxLifetime: getEnvAsInt("DB_CONN_MAX_LIFETIME", 300),
+			ConnectTimeout:  getEnvAsInt("DB_CONNECT_TIMEOUT", 5),
+			AutoMigrate:     getEnvAsBool("DB_AUTO_MIGRATE", true),
 		},
 		JWT: JWTConfig{
-			Secret:     getEnv("JWT_SECRET", "your-secret-key"),
-			ExpireTime: getEnvAsInt("JWT_EXPIRE_TIME", 24),
+			Secret:            getEnv("JWT_SECRET", ""),
+			ExpireTime:        getEnvAsInt("JWT_EXPIRE_TIME", 24),
+			Algorithm:         getEnv("JWT_ALGORITHM", "RS256"),
+			PublicKeyFile:     getEnv("JWT_PUBLIC_KEY_FILE", ""),
+			PrivateKeyFile:    getEnv("JWT_PRIVATE_KEY_FILE", ""),
+			Issuer:            getEnv("JWT_ISSUER", ""),
+			Audience:          getEnv("JWT_AUDIENCE", ""),
+			ClockSkew:         getEnvAsInt("JWT_CLOCK_SKEW", 30),
+			AuthEnabled:       getEnvAsBool("AUTH_ENABLED", true),
+			PublicRoutes:      getEnvAsSlice("AUTH_PUBLIC_ROUTES", nil),
+			RefreshExpireTime: getEnvAsInt("JWT_REFRESH_EXPIRE_TIME", 720),
+			AdminEmail:        getEnv("AUTH_ADMIN_EMAIL", ""),
+			AdminPassword:     getEnv("AUTH_ADMIN_PASSWORD", ""),
+			KeysDir:           getEnv("JWT_KEYS_DIR", "jwt_keys"),
+			KeyRotation:       getEnvAsInt("JWT_KEY_ROTATION", 0),
+		},
+		Password: PasswordConfig{
+			Algorithm:         getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+			Argon2Memory:      getEnvAsInt("PASSWORD_ARGON2_MEMORY", 64*1024),
+			Argon2Iterations:  getEnvAsInt("PASSWORD_ARGON2_ITERATIONS", 3),
+			Argon2Parallelism: getEnvAsInt("PASSWORD_ARGON2_PARALLELISM", 2),
+			BcryptCost:        getEnvAsInt("PASSWORD_BCRYPT_COST", 10),
+		},
+		Users: UsersConfig{
+			RequireIfMatch:   getEnvAsBool("USERS_REQUIRE_IF_MATCH", false),
+			DeletedRetention: getEnvAsInt("USERS_DELETED_RETENTION", 30*24),
+			PurgeInterval:    getEnvAsInt("USERS_PURGE_INTERVAL", 60),
+		},
+		OIDC: OIDCConfig{
+			ConfigFile: getEnv("OIDC_CONFIG_FILE", ""),
+		},
+		RBAC: RBACConfig{
+			ConfigFile: getEnv("RBAC_CONFIG_FILE", ""),
+		},
+		Proxy: ProxyConfig{
+			Timeout:           getEnvAsInt("PROXY_TIMEOUT", 30),
+			ConfigFile:        getEnv("PROXY_CONFIG_FILE", ""),
+			AdminToken:        getEnv("PROXY_ADMIN_TOKEN", ""),
+			MaxBodySize:       int64(getEnvAsInt("PROXY_MAX_BODY_SIZE", 10<<20)),
+			CompressResponses: getEnvAsBool("PROXY_COMPRESS_RESPONSES", true),
+			CompressMinSize:   getEnvAsInt("PROXY_COMPRESS_MIN_SIZE", 1024),
+			FaultsEnabled:     getEnvAsBool("PROXY_FAULTS_ENABLED", false),
+			MirrorConcurrency: getEnvAsInt("PROXY_MIRROR_CONCURRENCY", 32),
+			MirrorDiffLimit:   getEnvAsInt("PROXY_MIRROR_DIFF_LIMIT", 100),
+			IdempotencyTTL:    getEnvAsInt("PROXY_IDEMPOTENCY_TTL", 86400),
+			UnmatchedRoles:    getEnvAsSlice("PROXY_UNMATCHED_ROLES", nil),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:           getEnvAsBool("RATE_LIMIT_ENABLED", false),
+			RequestsPerSecond: getEnvAsFloat("RATE_LIMIT_RPS", 10),
+			Burst:             getEnvAsInt("RATE_LIMIT_BURST", 20),
+			PreAuthRPS:        getEnvAsFloat("RATE_LIMIT_PRE_AUTH_RPS", 0),
+			PreAuthBurst:      getEnvAsInt("RATE_LIMIT_PRE_AUTH_BURST", 0),
+			KeyBy:             getEnvAsSlice("RATE_LIMIT_KEY_BY", []string{"ip"}),
+			PerRoute:          getEnvAsBool("RATE_LIMIT_PER_ROUTE", false),
+			Routes:            getEnvAsSlice("RATE_LIMIT_ROUTES", nil),
+			IdleTimeout:       getEnvAsInt("RATE_LIMIT_IDLE_TIMEOUT", 600),
+			Store:             getEnv("RATE_LIMIT_STORE", "memory"),
+			FailOpen:          getEnvAsBool("RATE_LIMIT_FAIL_OPEN", true),
+			KeyPrefix:         getEnv("RATE_LIMIT_KEY_PREFIX", "ratelimit:"),
+		},
+		Redis: RedisConfig{
+			URL:       getEnv("REDIS_URL", "redis://localhost:6379/0"),
+			TimeoutMs: getEnvAsInt("REDIS_TIMEOUT_MS", 200),
 		},
 	}
 }
@@ -76,4 +249,40 @@ func getEnvAsInt(key string, defaultValue int) int {
 		}
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// getEnvAsFloat 获取环境变量并转换为浮点数
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsSlice 获取逗号分隔的环境变量并转换为字符串切片
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+// getEnvAsBool 获取环境变量并转换为布尔值
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ProxyConfig HTTP中转配置
+type ProxyConfig struct {
+	Timeout           int // 秒
+	ConfigFile        string
+	AdminToken        string // 管理接口令牌，为空时不开放管理接口
+	MaxBodySize       int64  // 请求体上限，字节，<=0时不限制
+	CompressResponses bool
+	CompressMinSize   int // 字节
+	FaultsEnabled     bool
+	MirrorConcurrency int
+	MirrorDiffLimit   int
+	IdempotencyTTL    int      // 秒
+	UnmatchedRoles    []string // 允许中转到未配置上游的目标的角色，为空时不限制
+	Upstreams         []UpstreamConfig
+	Faults            []FaultRule
+}
+
+// UpstreamConfig 上游服务配置
+type UpstreamConfig struct {
+	Name        string            `json:"name"`
+	URL         string            `json:"url"`
+	Compression CompressionConfig `json:"compression"`
+	Mirror      *MirrorConfig     `json:"mirror,omitempty"`
+	Canary      *CanaryConfig     `json:"canary,omitempty"`
+	Coalesce    CoalesceConfig    `json:"coalesce"`
+	Limits      LimitConfig       `json:"limits"`
+	// AllowedRoles 允许中转到该上游的角色，为空时不限制
+	AllowedRoles []string `json:"allowed_roles,omitempty"`
+	// Headers 转发到该上游时设置的请求头，如上游凭证，覆盖客户端的同名请求头
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// LimitConfig 上游出站限流配置，字段为0表示不限制
+type LimitConfig struct {
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	Burst             int     `json:"burst"` // 默认等于每秒请求数向上取整
+	MaxInFlight       int     `json:"max_in_flight"`
+	QueueSize         int     `json:"queue_size"`       // 达到并发上限时最多排队的请求数
+	QueueTimeoutMs    int     `json:"queue_timeout_ms"` // 排队等待上限，默认1秒
+}
+
+// CompressionConfig 上游请求体压缩配置
+type CompressionConfig struct {
+	// RequestEncoding 发往上游的请求体编码（gzip、deflate、br），为空表示不压缩
+	RequestEncoding string `json:"request_encoding"`
+	// MinSize 请求体达到该字节数才压缩
+	MinSize int `json:"min_size"`
+}
+
+// CoalesceConfig 相同并发GET请求合并配置
+type CoalesceConfig struct {
+	Enabled bool `json:"enabled"`
+	// Headers 参与合并键计算的请求头，如Accept、Accept-Language
+	Headers []string `json:"headers,omitempty"`
+	// AllowCredentials 允许合并携带凭据的请求，此时凭据也参与合并键计算
+	AllowCredentials bool `json:"allow_credentials"`
+}
+
+// MirrorConfig 流量镜像配置，影子上游的响应只用于比对，不返回给调用方
+type MirrorConfig struct {
+	URL         string   `json:"url"`               // 影子上游基础URL，替换主上游URL前缀
+	Percent     *float64 `json:"percent,omitempty"` // 采样比例0-100，未设置时视为100，0表示不镜像
+	Methods     []string `json:"methods,omitempty"` // 仅镜像这些方法，为空时镜像全部
+	TimeoutMs   int      `json:"timeout_ms"`
+	RecordDiffs bool     `json:"record_diffs"`
+	// Headers 发往影子上游时设置的请求头，主上游的Headers不会发给影子上游
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// CanaryConfig 灰度分流配置，按权重把上游流量分配到不同版本
+type CanaryConfig struct {
+	Variants       []VariantConfig `json:"variants"`
+	StickyCookie   string          `json:"sticky_cookie,omitempty"`   // 通过该Cookie保持分组
+	StickyHeader   string          `json:"sticky_header,omitempty"`   // 按该请求头的值哈希分组，优先于Cookie
+	OverrideHeader string          `json:"override_header,omitempty"` // 强制指定版本的请求头，默认X-Canary-Variant
+	CookieMaxAge   int             `json:"cookie_max_age,omitempty"`  // 秒，默认1天
+}
+
+// VariantConfig 上游版本
+type VariantConfig struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// FaultRule 故障注入规则
+type FaultRule struct {
+	ID       string         `json:"id"`
+	Enabled  bool           `json:"enabled"`
+	Match    FaultMatch     `json:"match"`
+	Delay    *FaultDelay    `json:"delay,omitempty"`
+	Abort    *FaultAbort    `json:"abort,omitempty"`
+	Truncate *FaultTruncate `json:"truncate,omitempty"`
+	Reset    *FaultReset    `json:"reset,omitempty"`
+}
+
+// FaultMatch 故障注入作用范围，字段均为空时匹配所有中转请求
+type FaultMatch struct {
+	Upstream    string `json:"upstream,omitempty"`
+	PathPattern string `json:"path_pattern,omitempty"` // path.Match通配符，匹配目标URL路径
+	Header      string `json:"header,omitempty"`
+	HeaderValue string `json:"header_value,omitempty"` // 为空时只要求请求头存在
+}
+
+// FaultDelay 延迟注入，实际延迟为FixedMs加上[0, RandomMs)内的随机值
+type FaultDelay struct {
+	FixedMs  int      `json:"fixed_ms"`
+	RandomMs int      `json:"random_ms"`
+	Percent  *float64 `json:"percent,omitempty"` // 触发概率0-100，未设置时视为100，0表示从不触发
+}
+
+// FaultAbort 直接以指定状态码中止请求
+type FaultAbort struct {
+	Status  int      `json:"status"`
+	Percent *float64 `json:"percent,omitempty"`
+}
+
+// FaultTruncate 截断上游响应体，仅保留前Bytes字节
+type FaultTruncate struct {
+	Bytes   int      `json:"bytes"`
+	Percent *float64 `json:"percent,omitempty"`
+}
+
+// FaultReset 不转发请求并直接重置客户端连接
+type FaultReset struct {
+	Percent *float64 `json:"percent,omitempty"`
+}
+
+// proxyFile 中转配置文件结构
+type proxyFile struct {
+	Upstreams []UpstreamConfig `json:"upstreams"`
+	Faults    []FaultRule      `json:"faults"`
+}
+
+// LoadFile 从ConfigFile加载上游等中转配置，未设置文件时直接返回
+func (p *ProxyConfig) LoadFile() error {
+	if p.ConfigFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(p.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("read proxy config: %w", err)
+	}
+
+	var file proxyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("parse proxy config %s: %w", p.ConfigFile, err)
+	}
+
+	p.Upstreams = file.Upstreams
+	p.Faults = file.Faults
+	return nil
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RateLimitConfig 入站限流配置
+type RateLimitConfig struct {
+	Enabled           bool
+	RequestsPerSecond float64
+	Burst             int
+	PreAuthRPS        float64 // 认证之前按IP的宽松规则，<=0时为默认规则的5倍
+	PreAuthBurst      int
+	KeyBy             []string // 依次尝试的限流键：user、api_key、ip
+	PerRoute          bool     // 每个路由单独计数
+	Routes            []string // 路由级规则，格式 "METHOD /path=rate:burst"
+	IdleTimeout       int      // 秒，空闲超过该时长的计数会被清理
+	Store             string   // memory或redis
+	FailOpen          bool     // 存储不可用时是否放行
+	KeyPrefix         string   // Redis键前缀
+}
+
+// RedisConfig Redis连接配置
+type RedisConfig struct {
+	URL       string // 如 redis://:password@localhost:6379/0
+	TimeoutMs int
+}
+
+// RouteRateLimit 路由级限流规则
+type RouteRateLimit struct {
+	Method            string
+	Path              string
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// ParseRoutes 解析路由级限流规则
+func (r *RateLimitConfig) ParseRoutes() ([]RouteRateLimit, error) {
+	rules := make([]RouteRateLimit, 0, len(r.Routes))
+	for _, raw := range r.Routes {
+		route, limit, ok := strings.Cut(raw, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit route %q", raw)
+		}
+
+		method, path, ok := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok {
+			return nil, fmt.Errorf("rate limit route %q must be \"METHOD /path\"", route)
+		}
+
+		rateStr, burstStr, _ := strings.Cut(limit, ":")
+		rps, err := strconv.ParseFloat(strings.TrimSpace(rateStr), 64)
+		if err != nil || rps <= 0 {
+			return nil, fmt.Errorf("invalid rate in rate limit route %q", raw)
+		}
+		burst := int(rps)
+		if burstStr != "" {
+			if burst, err = strconv.Atoi(strings.TrimSpace(burstStr)); err != nil || burst <= 0 {
+				return nil, fmt.Errorf("invalid burst in rate limit route %q", raw)
+			}
+		}
+		if burst < 1 {
+			burst = 1
+		}
+
+		rules = append(rules, RouteRateLimit{
+			Method:            strings.ToUpper(method),
+			Path:              strings.TrimSpace(path),
+			RequestsPerSecond: rps,
+			Burst:             burst,
+		})
+	}
+	return rules, nil
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// RBACConfig 角色权限配置
+type RBACConfig struct {
+	ConfigFile string
+	// Roles 角色到权限的映射，与内置角色合并，同名角色以配置为准
+	Roles map[string][]string
+}
+
+// rbacFile 角色权限配置文件结构
+type rbacFile struct {
+	Roles map[string][]string `json:"roles"`
+}
+
+// LoadFile 从ConfigFile加载角色权限，未设置文件时直接返回
+func (r *RBACConfig) LoadFile() error {
+	if r.ConfigFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(r.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("read rbac config: %w", err)
+	}
+
+	var file rbacFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("parse rbac config %s: %w", r.ConfigFile, err)
+	}
+
+	r.Roles = file.Roles
+	return nil
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // 注册pgx驱动
+	"go-echo-app/internal/config"
+	_ "modernc.org/sqlite" // 注册纯Go实现的sqlite驱动
+)
+
+// Open 按配置创建连接池并确认数据库可用
+func Open(cfg *config.DatabaseConfig) (*sql.DB, error) {
+	var driver, dsn string
+	switch cfg.Driver {
+	case "postgres":
+		driver, dsn = "pgx", cfg.PostgresDSN()
+	case "sqlite":
+		driver, dsn = "sqlite", cfg.SQLiteDSN()
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", cfg.Driver, err)
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ConnectTimeout)*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connect %s: %w", cfg.Driver, err)
+	}
+	return db, nil
+}
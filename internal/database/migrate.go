@@ -0,0 +1,445 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles 内置的迁移脚本，文件名格式为 <版本>_<名称>[.<驱动>].up.sql / .down.sql，
+// 带驱动名的脚本只用于对应的数据库
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockKey Postgres咨询锁的键，同一数据库上的迁移互斥
+const migrationLockKey = 7_391_028_455
+
+// migrationTable 记录已执行迁移的表
+const migrationTable = "schema_migrations"
+
+var (
+	migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)(?:\.(postgres|sqlite))?\.(up|down)\.sql$`)
+	migrationNameCleaner = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// ErrChecksumMismatch 已执行的迁移脚本被修改
+var ErrChecksumMismatch = errors.New("migration checksum mismatch")
+
+// Migration 一个版本的迁移脚本
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // Up脚本的SHA-256
+}
+
+// MigrationStatus 迁移的执行状态
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	Modified  bool // 执行后脚本被修改
+	Missing   bool // 数据库中已执行，但当前程序中没有该脚本
+}
+
+// Migrator 执行数据库迁移
+type Migrator struct {
+	db         *sql.DB
+	driver     string
+	migrations []Migration
+}
+
+// NewMigrator 使用内置迁移脚本创建Migrator，driver为postgres或sqlite
+func NewMigrator(db *sql.DB, driver string) (*Migrator, error) {
+	if driver != "postgres" && driver != "sqlite" {
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+	fsys, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := LoadMigrations(fsys, driver)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, driver: driver, migrations: migrations}, nil
+}
+
+// LoadMigrations 读取fsys根目录下适用于driver的迁移脚本，按版本排序
+func LoadMigrations(fsys fs.FS, driver string) ([]Migration, error) {
+	paths, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, path := range paths {
+		m := migrationFilePattern.FindStringSubmatch(path)
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", path)
+		}
+		if m[3] != "" && m[3] != driver {
+			continue
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return nil, err
+		}
+
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		} else if migration.Name != m[2] {
+			return nil, fmt.Errorf("migration %04d has conflicting names %q and %q", version, migration.Name, m[2])
+		}
+		if m[4] == "up" {
+			if migration.Up != "" {
+				return nil, fmt.Errorf("migration %04d_%s has more than one up script", version, m[2])
+			}
+			migration.Up = string(data)
+			sum := sha256.Sum256(data)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			if migration.Down != "" {
+				return nil, fmt.Errorf("migration %04d_%s has more than one down script", version, m[2])
+			}
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// appliedMigration 迁移表中的一行
+type appliedMigration struct {
+	version   int64
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Up 执行所有未执行的迁移，返回本次执行的迁移
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			ran, err := m.apply(ctx, conn, migration)
+			if err != nil {
+				return err
+			}
+			if ran {
+				done = append(done, migration)
+			}
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down 按版本从新到旧回滚steps个已执行的迁移，返回本次回滚的迁移
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int64, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+		if steps < len(versions) {
+			versions = versions[:steps]
+		}
+
+		for _, version := range versions {
+			migration, ok := m.find(version)
+			if !ok {
+				return fmt.Errorf("migration %04d_%s is not known to this binary", version, applied[version].name)
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %04d_%s has no down script", migration.Version, migration.Name)
+			}
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status 返回内置迁移和数据库中已执行迁移的状态，按版本排序
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.appliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = row.checksum != migration.Checksum
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range applied {
+		appliedAt := row.appliedAt
+		statuses = append(statuses, MigrationStatus{
+			Version:   row.version,
+			Name:      row.name,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Pending 返回未执行的迁移数量
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if !status.Applied {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// withLock 在独占的连接上执行fn。Postgres使用会话级咨询锁，
+// SQLite依赖写事务（_txlock=immediate）串行化，并在事务内重新检查迁移状态
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.driver == "postgres" {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+			return fmt.Errorf("acquire migration lock: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey)
+	}
+	return fn(conn)
+}
+
+// ensureTable 创建迁移表，Postgres的执行时间使用TIMESTAMPTZ。
+// 迁移表由迁移器自身维护，不属于任何迁移脚本，旧版本创建的TIMESTAMP列在这里升级
+func (m *Migrator) ensureTable(ctx context.Context, conn *sql.Conn) error {
+	timestamp := "TIMESTAMP"
+	if m.driver == "postgres" {
+		timestamp = "TIMESTAMPTZ"
+	}
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+migrationTable+` (
+    version    BIGINT       PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    checksum   VARCHAR(64)  NOT NULL,
+    applied_at `+timestamp+` NOT NULL
+)`)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", migrationTable, err)
+	}
+	if m.driver != "postgres" {
+		return nil
+	}
+
+	var dataType string
+	err = conn.QueryRowContext(ctx, `SELECT data_type FROM information_schema.columns
+WHERE table_schema = current_schema() AND table_name = $1 AND column_name = 'applied_at'`, migrationTable).Scan(&dataType)
+	if err != nil {
+		return fmt.Errorf("inspect %s: %w", migrationTable, err)
+	}
+	if dataType == "timestamp without time zone" {
+		_, err = conn.ExecContext(ctx, "ALTER TABLE "+migrationTable+" ALTER COLUMN applied_at TYPE TIMESTAMPTZ USING applied_at AT TIME ZONE 'UTC'")
+		if err != nil {
+			return fmt.Errorf("upgrade %s: %w", migrationTable, err)
+		}
+	}
+	return nil
+}
+
+// applied 读取已执行的迁移
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM "+migrationTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var row appliedMigration
+		if err := rows.Scan(&row.version, &row.name, &row.checksum, &row.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[row.version] = row
+	}
+	return applied, rows.Err()
+}
+
+// verify 确认已执行的迁移脚本未被修改
+func (m *Migrator) verify(applied map[int64]appliedMigration) error {
+	for _, migration := range m.migrations {
+		if row, ok := applied[migration.Version]; ok && row.checksum != migration.Checksum {
+			return fmt.Errorf("%w: %04d_%s was changed after it was applied", ErrChecksumMismatch, migration.Version, migration.Name)
+		}
+	}
+	return nil
+}
+
+// apply 在事务中执行一个迁移并记录，其他副本已执行时返回false
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) (bool, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRowContext(ctx, "SELECT 1 FROM "+migrationTable+" WHERE version = "+m.placeholder(1), migration.Version).Scan(&exists)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+
+	if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+		return false, fmt.Errorf("apply migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (version, name, checksum, applied_at) VALUES (%s, %s, %s, %s)",
+		migrationTable, m.placeholder(1), m.placeholder(2), m.placeholder(3), m.placeholder(4))
+	if _, err := tx.ExecContext(ctx, query, migration.Version, migration.Name, migration.Checksum, time.Now().UTC()); err != nil {
+		return false, fmt.Errorf("record migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return true, tx.Commit()
+}
+
+// revert 在事务中回滚一个迁移并删除记录
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+		return fmt.Errorf("revert migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM "+migrationTable+" WHERE version = "+m.placeholder(1), migration.Version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// find 按版本查找内置迁移
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// placeholder 返回第n个参数占位符
+func (m *Migrator) placeholder(n int) string {
+	if m.driver == "postgres" {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?" + strconv.Itoa(n)
+}
+
+// CreateMigration 在dir中创建下一个版本的空迁移脚本，返回up和down文件路径
+func CreateMigration(dir, name string) (string, string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = migrationNameCleaner.ReplaceAllString(name, "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return "", "", errors.New("migration name is required")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", "", err
+	}
+	var version int64 = 1
+	for _, entry := range entries {
+		if m := migrationFilePattern.FindStringSubmatch(entry.Name()); m != nil {
+			if v, _ := strconv.ParseInt(m[1], 10, 64); v >= version {
+				version = v + 1
+			}
+		}
+	}
+
+	prefix := filepath.Join(dir, fmt.Sprintf("%04d_%s", version, name))
+	up, down := prefix+".up.sql", prefix+".down.sql"
+	for _, path := range []string{up, down} {
+		content := fmt.Sprintf("-- %04d_%s\n", version, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return "", "", err
+		}
+	}
+	return up, down, nil
+}
@@ -0,0 +1,291 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"go-echo-app/internal/config"
+)
+
+// openTestSQLite 在临时目录中打开一个SQLite数据库
+func openTestSQLite(t *testing.T) *Migrator {
+	t.Helper()
+	db, err := Open(&config.DatabaseConfig{
+		Driver:         "sqlite",
+		Path:           filepath.Join(t.TempDir(), "test.db"),
+		MaxOpenConns:   1,
+		ConnectTimeout: 5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	m, err := NewMigrator(db, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMigratorUpDown(t *testing.T) {
+	ctx := context.Background()
+	m := openTestSQLite(t)
+
+	done, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != len(m.migrations) {
+		t.Fatalf("Up applied %d migrations, want %d", len(done), len(m.migrations))
+	}
+	if done, err = m.Up(ctx); err != nil || len(done) != 0 {
+		t.Fatalf("second Up = %d, %v; want nothing to do", len(done), err)
+	}
+	if pending, err := m.Pending(ctx); err != nil || pending != 0 {
+		t.Fatalf("Pending() = %d, %v; want 0", pending, err)
+	}
+
+	// 逐个回滚全部迁移，确认每个down脚本都能执行
+	latest := m.migrations[len(m.migrations)-1]
+	reverted, err := m.Down(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reverted) != 1 || reverted[0].Version != latest.Version {
+		t.Fatalf("Down(1) reverted %v, want %04d", reverted, latest.Version)
+	}
+	if pending, err := m.Pending(ctx); err != nil || pending != 1 {
+		t.Fatalf("Pending() = %d, %v; want 1", pending, err)
+	}
+	if reverted, err = m.Down(ctx, len(m.migrations)+1); err != nil {
+		t.Fatal(err)
+	}
+	if len(reverted) != len(m.migrations)-1 {
+		t.Fatalf("Down reverted %d migrations, want %d", len(reverted), len(m.migrations)-1)
+	}
+
+	if done, err = m.Up(ctx); err != nil || len(done) != len(m.migrations) {
+		t.Fatalf("Up after full rollback = %d, %v", len(done), err)
+	}
+}
+
+func TestMigratorDetectsModifiedScript(t *testing.T) {
+	ctx := context.Background()
+	m := openTestSQLite(t)
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	m.migrations[0].Checksum = "changed"
+	if _, err := m.Up(ctx); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Up() error = %v, want ErrChecksumMismatch", err)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !statuses[0].Modified || statuses[1].Modified {
+		t.Fatalf("Status() modified flags = %v, %v; want true, false", statuses[0].Modified, statuses[1].Modified)
+	}
+}
+
+func TestMigratorReportsUnknownApplied(t *testing.T) {
+	ctx := context.Background()
+	m := openTestSQLite(t)
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟由更新版本的程序执行过、当前程序中没有的迁移
+	latest := m.migrations[len(m.migrations)-1]
+	m.migrations = m.migrations[:len(m.migrations)-1]
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := statuses[len(statuses)-1]
+	if last.Version != latest.Version || !last.Missing {
+		t.Fatalf("Status() last = %+v, want missing %04d", last, latest.Version)
+	}
+	if _, err := m.Down(ctx, 1); err == nil {
+		t.Fatal("Down() reverted a migration unknown to this binary")
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	file := func(content string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(content)} }
+
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		driver   string
+		versions []int64
+		ups      []string
+		wantErr  string
+	}{
+		{
+			name: "sorted by version",
+			files: fstest.MapFS{
+				"0002_b.up.sql":   file("b"),
+				"0001_a.up.sql":   file("a"),
+				"0001_a.down.sql": file("-a"),
+			},
+			driver:   "sqlite",
+			versions: []int64{1, 2},
+			ups:      []string{"a", "b"},
+		},
+		{
+			name: "driver specific scripts",
+			files: fstest.MapFS{
+				"0001_a.postgres.up.sql": file("pg"),
+				"0001_a.sqlite.up.sql":   file("lite"),
+			},
+			driver:   "postgres",
+			versions: []int64{1},
+			ups:      []string{"pg"},
+		},
+		{
+			name:    "invalid file name",
+			files:   fstest.MapFS{"1-a.sql": file("")},
+			driver:  "sqlite",
+			wantErr: "invalid migration file name",
+		},
+		{
+			name: "conflicting names",
+			files: fstest.MapFS{
+				"0001_a.up.sql":   file("a"),
+				"0001_b.down.sql": file("b"),
+			},
+			driver:  "sqlite",
+			wantErr: "conflicting names",
+		},
+		{
+			name: "generic and driver up scripts",
+			files: fstest.MapFS{
+				"0001_a.up.sql":        file("a"),
+				"0001_a.sqlite.up.sql": file("lite"),
+			},
+			driver:  "sqlite",
+			wantErr: "more than one up script",
+		},
+		{
+			name:    "down without up",
+			files:   fstest.MapFS{"0001_a.down.sql": file("a")},
+			driver:  "sqlite",
+			wantErr: "has no up script",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := LoadMigrations(tt.files, tt.driver)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadMigrations() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(migrations) != len(tt.versions) {
+				t.Fatalf("LoadMigrations() returned %d migrations, want %d", len(migrations), len(tt.versions))
+			}
+			for i, m := range migrations {
+				if m.Version != tt.versions[i] || m.Up != tt.ups[i] || m.Checksum == "" {
+					t.Errorf("migration %d = %+v, want version %d up %q", i, m, tt.versions[i], tt.ups[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "0007_existing.postgres.up.sql"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	up, down, err := CreateMigration(dir, " Add User Avatar! ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(up) != "0008_add_user_avatar.up.sql" || filepath.Base(down) != "0008_add_user_avatar.down.sql" {
+		t.Fatalf("CreateMigration() = %s, %s", up, down)
+	}
+	if _, _, err := CreateMigration(dir, "!!!"); err == nil {
+		t.Fatal("expected error for an empty name")
+	}
+}
+
+// TestMigratorUpgradesLegacyTablePostgres 旧版本创建的迁移表applied_at为TIMESTAMP，迁移器启动时升级为TIMESTAMPTZ。
+// 只在设置了TEST_POSTGRES_DSN时运行，使用临时schema，不影响库中已有的表
+func TestMigratorUpgradesLegacyTablePostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	ctx := context.Background()
+
+	admin, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	schema := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	if _, err := admin.ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+	defer admin.ExecContext(ctx, "DROP SCHEMA "+schema+" CASCADE")
+
+	// 连接参数search_path使临时schema成为当前schema
+	if strings.Contains(dsn, "://") {
+		if strings.Contains(dsn, "?") {
+			dsn += "&search_path=" + schema
+		} else {
+			dsn += "?search_path=" + schema
+		}
+	} else {
+		dsn += " search_path=" + schema
+	}
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, `CREATE TABLE schema_migrations (
+    version    BIGINT       PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    checksum   VARCHAR(64)  NOT NULL,
+    applied_at TIMESTAMP    NOT NULL
+)`); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := NewMigrator(db, "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Pending(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var dataType string
+	err = db.QueryRowContext(ctx, `SELECT data_type FROM information_schema.columns
+WHERE table_schema = $1 AND table_name = 'schema_migrations' AND column_name = 'applied_at'`, schema).Scan(&dataType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dataType != "timestamp with time zone" {
+		t.Fatalf("applied_at type = %q, want timestamp with time zone", dataType)
+	}
+}
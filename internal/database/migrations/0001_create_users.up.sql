@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS users (
+    id         VARCHAR(64)  PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    email      VARCHAR(255) NOT NULL,
+    password   TEXT         NOT NULL DEFAULT '',
+    roles      TEXT         NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP    NOT NULL,
+    updated_at TIMESTAMP    NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users (lower(email));
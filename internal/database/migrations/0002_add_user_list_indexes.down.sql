@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS users_email_idx;
+DROP INDEX IF EXISTS users_name_idx;
+DROP INDEX IF EXISTS users_updated_at_idx;
+DROP INDEX IF EXISTS users_created_at_idx;
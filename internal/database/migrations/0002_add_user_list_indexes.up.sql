@@ -0,0 +1,4 @@
+CREATE INDEX IF NOT EXISTS users_created_at_idx ON users (created_at, id);
+CREATE INDEX IF NOT EXISTS users_updated_at_idx ON users (updated_at, id);
+CREATE INDEX IF NOT EXISTS users_name_idx ON users (name, id);
+CREATE INDEX IF NOT EXISTS users_email_idx ON users (email, id);
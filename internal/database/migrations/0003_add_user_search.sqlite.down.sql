@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS users_search_delete;
+DROP TRIGGER IF EXISTS users_search_update;
+DROP TRIGGER IF EXISTS users_search_insert;
+DROP TABLE IF EXISTS users_search;
+DROP INDEX IF EXISTS users_search_rowid_idx;
+ALTER TABLE users DROP COLUMN search_rowid;
+ALTER TABLE users DROP COLUMN search_text;
+ALTER TABLE users DROP COLUMN phone;
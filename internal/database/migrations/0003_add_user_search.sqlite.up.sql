@@ -0,0 +1,24 @@
+ALTER TABLE users ADD COLUMN phone VARCHAR(32) NOT NULL DEFAULT '';
+-- 规范化（去重音、小写）后的姓名、邮箱和电话，由应用写入
+ALTER TABLE users ADD COLUMN search_text TEXT NOT NULL DEFAULT '';
+-- users_search中对应行的rowid。users没有整数主键，VACUUM可能改变其rowid，因此单独记录
+ALTER TABLE users ADD COLUMN search_rowid INTEGER;
+CREATE UNIQUE INDEX users_search_rowid_idx ON users (search_rowid);
+
+CREATE VIRTUAL TABLE users_search USING fts5(search_text, tokenize = 'trigram');
+
+INSERT INTO users_search (rowid, search_text) SELECT rowid, search_text FROM users;
+UPDATE users SET search_rowid = rowid;
+
+CREATE TRIGGER users_search_insert AFTER INSERT ON users BEGIN
+    INSERT INTO users_search (search_text) VALUES (new.search_text);
+    UPDATE users SET search_rowid = last_insert_rowid() WHERE id = new.id;
+END;
+
+CREATE TRIGGER users_search_update AFTER UPDATE OF search_text ON users BEGIN
+    UPDATE users_search SET search_text = new.search_text WHERE rowid = new.search_rowid;
+END;
+
+CREATE TRIGGER users_search_delete AFTER DELETE ON users BEGIN
+    DELETE FROM users_search WHERE rowid = old.search_rowid;
+END;
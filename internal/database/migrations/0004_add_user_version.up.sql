@@ -0,0 +1,2 @@
+-- 乐观锁版本号，每次更新加一
+ALTER TABLE users ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+-- 软删除时间，为NULL表示未删除；已删除的用户仍占用邮箱，恢复时不会冲突
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP;
+-- 定期清理按删除时间查找超过保留期的用户
+CREATE INDEX IF NOT EXISTS users_deleted_at_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS users_identity_key;
+ALTER TABLE users DROP COLUMN identity_subject;
+ALTER TABLE users DROP COLUMN identity_issuer;
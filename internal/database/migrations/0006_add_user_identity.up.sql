@@ -0,0 +1,4 @@
+-- 关联的外部OIDC身份，外部令牌按(iss, sub)而不是邮箱找到本地用户
+ALTER TABLE users ADD COLUMN identity_issuer VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN identity_subject VARCHAR(255) NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS users_identity_key ON users (identity_issuer, identity_subject) WHERE identity_subject <> '';
@@ -0,0 +1,5 @@
+-- Postgres的时间列使用TIMESTAMPTZ，按绝对时间保存，不受会话时区影响；应用写入的时间均为UTC
+ALTER TABLE users
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE 'UTC',
+    ALTER COLUMN deleted_at TYPE TIMESTAMPTZ USING deleted_at AT TIME ZONE 'UTC';
@@ -0,0 +1,2 @@
+-- SQLite没有带时区的时间类型，时间以UTC文本保存（见DB_PATH连接参数_time_format），无需修改
+SELECT 1;
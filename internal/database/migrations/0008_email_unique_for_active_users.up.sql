@@ -0,0 +1,4 @@
+-- 邮箱只在未删除的用户中唯一，软删除的用户不再占用邮箱；恢复时若邮箱已被占用则冲突。
+-- 取代0005注释中“已删除的用户仍占用邮箱”的约定（已执行的迁移脚本受校验和保护，不再修改）
+DROP INDEX IF EXISTS users_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users (lower(email)) WHERE deleted_at IS NULL;
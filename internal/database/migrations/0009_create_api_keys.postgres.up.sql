@@ -0,0 +1,16 @@
+-- API Key只保存摘要，认证时按明文前缀查找后比对；用户被永久删除时一并删除其Key
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           VARCHAR(64)  PRIMARY KEY,
+    prefix       VARCHAR(32)  NOT NULL,
+    hash         VARCHAR(128) NOT NULL,
+    name         VARCHAR(100) NOT NULL,
+    owner_id     VARCHAR(64)  NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    scopes       TEXT         NOT NULL DEFAULT '[]',
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS api_keys_prefix_key ON api_keys (prefix);
+CREATE INDEX IF NOT EXISTS api_keys_owner_created_idx ON api_keys (owner_id, created_at);
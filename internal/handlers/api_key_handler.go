@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go-echo-app/internal/auth"
+	appmiddleware "go-echo-app/internal/middleware"
+	"go-echo-app/internal/models"
+	"go-echo-app/pkg/utils"
+)
+
+// APIKeyHandler API Key管理处理器，只能管理当前调用方自己的Key
+type APIKeyHandler struct {
+	service *auth.APIKeyService
+}
+
+// NewAPIKeyHandler 创建API Key处理器
+func NewAPIKeyHandler(service *auth.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+// CreateAPIKey 创建API Key，明文Key只在响应中出现一次
+func (h *APIKeyHandler) CreateAPIKey(c echo.Context) error {
+	principal := appmiddleware.GetPrincipal(c)
+	if principal == nil {
+		return utils.Unauthorized(c, "Authentication required")
+	}
+
+	req := new(models.CreateAPIKeyRequest)
+	if err := c.Bind(req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+	if err := c.Validate(req); err != nil {
+		return utils.ValidationError(c, validationMessage(err))
+	}
+
+	raw, key, err := h.service.Create(c.Request().Context(), principal, req)
+	switch {
+	case errors.Is(err, auth.ErrInvalidExpiration):
+		return utils.ValidationError(c, "expires_at must be in the future")
+	case errors.Is(err, auth.ErrScopeNotAllowed):
+		return utils.Forbidden(c, err.Error())
+	case err != nil:
+		c.Logger().Errorf("create api key failed: %v", err)
+		return utils.InternalServerError(c, "Failed to create API key")
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return utils.SuccessResponse(c, http.StatusCreated, "API key created, store it now as it will not be shown again", models.CreateAPIKeyResponse{
+		APIKey: key,
+		Key:    raw,
+	})
+}
+
+// ListAPIKeys 列出当前调用方的API Key
+func (h *APIKeyHandler) ListAPIKeys(c echo.Context) error {
+	principal := appmiddleware.GetPrincipal(c)
+	if principal == nil {
+		return utils.Unauthorized(c, "Authentication required")
+	}
+
+	keys, err := h.service.List(c.Request().Context(), principal.Subject)
+	if err != nil {
+		c.Logger().Errorf("list api keys failed: %v", err)
+		return utils.InternalServerError(c, "Failed to list API keys")
+	}
+	return utils.SuccessResponse(c, http.StatusOK, "API keys retrieved", keys)
+}
+
+// RevokeAPIKey 吊销当前调用方的API Key
+func (h *APIKeyHandler) RevokeAPIKey(c echo.Context) error {
+	principal := appmiddleware.GetPrincipal(c)
+	if principal == nil {
+		return utils.Unauthorized(c, "Authentication required")
+	}
+
+	err := h.service.Revoke(c.Request().Context(), principal.Subject, c.Param("id"))
+	if errors.Is(err, auth.ErrAPIKeyNotFound) {
+		return utils.NotFound(c, "API key not found")
+	}
+	if err != nil {
+		c.Logger().Errorf("revoke api key failed: %v", err)
+		return utils.InternalServerError(c, "Failed to revoke API key")
+	}
+	return utils.SuccessResponse(c, http.StatusOK, "API key revoked", nil)
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go-echo-app/internal/auth"
+	appmiddleware "go-echo-app/internal/middleware"
+	"go-echo-app/internal/models"
+	"go-echo-app/internal/repository"
+	"go-echo-app/pkg/utils"
+)
+
+// jwksMaxAge JWKS响应的缓存时间（秒）
+const jwksMaxAge = 300
+
+// AuthHandler 登录、刷新、注销、修改密码和公钥发布处理器
+type AuthHandler struct {
+	service *auth.Service
+	keys    *auth.KeySet
+}
+
+// NewAuthHandler 创建认证处理器，keys为nil时JWKS为空
+func NewAuthHandler(service *auth.Service, keys *auth.KeySet) *AuthHandler {
+	return &AuthHandler{service: service, keys: keys}
+}
+
+// Login 使用邮箱和密码登录
+func (h *AuthHandler) Login(c echo.Context) error {
+	req := new(models.LoginRequest)
+	if err := c.Bind(req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+	if err := c.Validate(req); err != nil {
+		return utils.ValidationError(c, validationMessage(err))
+	}
+
+	pair, err := h.service.Login(c.Request().Context(), req.Email, req.Password)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		return utils.Unauthorized(c, "Invalid email or password")
+	}
+	if err != nil {
+		c.Logger().Errorf("login failed: %v", err)
+		return utils.InternalServerError(c, "Login failed")
+	}
+
+	return utils.SuccessResponse(c, http.StatusOK, "Login successful", tokenResponse(pair))
+}
+
+// Refresh 轮换刷新令牌并签发新的访问令牌
+func (h *AuthHandler) Refresh(c echo.Context) error {
+	req := new(models.RefreshTokenRequest)
+	if err := c.Bind(req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+	if err := c.Validate(req); err != nil {
+		return utils.ValidationError(c, validationMessage(err))
+	}
+
+	pair, err := h.service.Refresh(c.Request().Context(), req.RefreshToken)
+	switch {
+	case errors.Is(err, auth.ErrRefreshTokenReused):
+		return utils.Unauthorized(c, "Refresh token reuse detected, session revoked")
+	case errors.Is(err, auth.ErrInvalidRefreshToken):
+		return utils.Unauthorized(c, "Invalid or expired refresh token")
+	case err != nil:
+		c.Logger().Errorf("token refresh failed: %v", err)
+		return utils.InternalServerError(c, "Token refresh failed")
+	}
+
+	return utils.SuccessResponse(c, http.StatusOK, "Token refreshed", tokenResponse(pair))
+}
+
+// Logout 吊销刷新令牌所在的会话
+func (h *AuthHandler) Logout(c echo.Context) error {
+	req := new(models.RefreshTokenRequest)
+	if err := c.Bind(req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+	if err := c.Validate(req); err != nil {
+		return utils.ValidationError(c, validationMessage(err))
+	}
+
+	if err := h.service.Logout(req.RefreshToken); err != nil {
+		c.Logger().Errorf("logout failed: %v", err)
+		return utils.InternalServerError(c, "Logout failed")
+	}
+	return utils.SuccessResponse(c, http.StatusOK, "Logged out", nil)
+}
+
+// ChangePassword 修改当前用户的密码，需要提供当前密码
+func (h *AuthHandler) ChangePassword(c echo.Context) error {
+	principal := appmiddleware.GetPrincipal(c)
+	if principal == nil {
+		return utils.Unauthorized(c, "Authentication required")
+	}
+
+	req := new(models.ChangePasswordRequest)
+	if err := c.Bind(req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+	if err := c.Validate(req); err != nil {
+		return utils.ValidationError(c, validationMessage(err))
+	}
+
+	err := h.service.ChangePassword(c.Request().Context(), principal.Subject, req.CurrentPassword, req.NewPassword)
+	switch {
+	case errors.Is(err, auth.ErrWrongPassword):
+		return utils.BadRequest(c, "Current password is incorrect")
+	case errors.Is(err, repository.ErrNotFound):
+		return utils.NotFound(c, "User not found")
+	case errors.Is(err, repository.ErrVersionConflict):
+		return utils.Conflict(c, "User was modified concurrently, please retry")
+	case err != nil:
+		c.Logger().Errorf("change password failed: %v", err)
+		return utils.InternalServerError(c, "Failed to change password")
+	}
+	return utils.SuccessResponse(c, http.StatusOK, "Password changed", nil)
+}
+
+// JWKS 发布当前及保留期内的签名公钥
+func (h *AuthHandler) JWKS(c echo.Context) error {
+	set := auth.JWKSet{Keys: []auth.JWK{}}
+	if h.keys != nil {
+		set = h.keys.JWKS()
+	}
+
+	c.Response().Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(jwksMaxAge))
+	return c.JSON(http.StatusOK, set)
+}
+
+// tokenResponse 转换为令牌响应
+func tokenResponse(pair *auth.TokenPair) models.TokenResponse {
+	now := time.Now()
+	return models.TokenResponse{
+		AccessToken:      pair.AccessToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(pair.AccessExpiresAt.Sub(now).Seconds()),
+		RefreshToken:     pair.RefreshToken,
+		RefreshExpiresIn: int64(pair.RefreshExpiresAt.Sub(now).Seconds()),
+	}
+}
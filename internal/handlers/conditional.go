@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"go-echo-app/internal/services"
+)
+
+// 条件请求相关的请求头和响应头
+const (
+	headerETag        = "ETag"
+	headerIfMatch     = "If-Match"
+	headerIfNoneMatch = "If-None-Match"
+)
+
+// errIfMatchRequired 配置要求修改用户时携带If-Match，但请求中没有
+var errIfMatchRequired = errors.New("If-Match header required")
+
+// userETag 由版本号生成用户的强ETag
+func userETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseUserETag 解析userETag生成的ETag，弱ETag不能用于If-Match的强比较，返回false
+func parseUserETag(tag string) (int64, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	return version, err == nil && version > 0
+}
+
+// splitETags 拆分If-Match/If-None-Match中以逗号分隔的实体标签
+func splitETags(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// notModified 判断If-None-Match是否与当前ETag匹配，按弱比较忽略W/前缀
+func notModified(c echo.Context, etag string) bool {
+	for _, tag := range splitETags(c.Request().Header.Get(headerIfNoneMatch)) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion 解析If-Match，返回服务层要求的用户版本，0表示不检查（未携带或为"*"）。
+// 没有可匹配的ETag时返回services.ErrVersionMismatch；列出多个ETag时以当前版本是否在其中为准
+func (h *UserHandler) ifMatchVersion(c echo.Context) (int64, error) {
+	header := c.Request().Header.Get(headerIfMatch)
+	if header == "" {
+		if h.requireIfMatch {
+			return 0, errIfMatchRequired
+		}
+		return 0, nil
+	}
+
+	var versions []int64
+	for _, tag := range splitETags(header) {
+		if tag == "*" {
+			return 0, nil
+		}
+		if version, ok := parseUserETag(tag); ok {
+			versions = append(versions, version)
+		}
+	}
+	switch len(versions) {
+	case 0:
+		return 0, services.ErrVersionMismatch
+	case 1:
+		return versions[0], nil
+	}
+
+	user, err := h.service.GetUser(c.Request().Context(), c.Param("id"), true)
+	if err != nil {
+		return 0, err
+	}
+	for _, version := range versions {
+		if version == user.Version {
+			return version, nil
+		}
+	}
+	return 0, services.ErrVersionMismatch
+}
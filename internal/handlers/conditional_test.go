@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go-echo-app/internal/auth"
+	"go-echo-app/internal/config"
+	"go-echo-app/internal/models"
+	"go-echo-app/internal/repository"
+	"go-echo-app/internal/services"
+	"go-echo-app/pkg/validator"
+)
+
+// newConditionalTestServer 创建注册了单个用户路由的服务，返回服务和已创建用户的ID
+func newConditionalTestServer(t *testing.T, requireIfMatch bool) (*echo.Echo, string) {
+	t.Helper()
+	hasher, err := auth.NewPasswordHasher(&config.PasswordConfig{Algorithm: auth.PasswordBcrypt, BcryptCost: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := services.NewUserService(repository.NewMemoryUserRepository(), hasher)
+	user, err := service.CreateUser(context.Background(), &models.CreateUserRequest{Name: "Ann", Email: "ann@example.com", Password: "Passw0rd!"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewUserHandler(service, &config.UsersConfig{RequireIfMatch: requireIfMatch})
+	e := echo.New()
+	e.Validator = validator.NewCustomValidator()
+	e.GET("/users/:id", h.GetUser)
+	e.PUT("/users/:id", h.UpdateUser)
+	e.DELETE("/users/:id", h.DeleteUser)
+	return e, user.ID
+}
+
+func TestParseUserETag(t *testing.T) {
+	tests := []struct {
+		tag     string
+		version int64
+		ok      bool
+	}{
+		{tag: `"3"`, version: 3, ok: true},
+		{tag: `W/"3"`, ok: false},
+		{tag: `"0"`, ok: false},
+		{tag: `"abc"`, ok: false},
+		{tag: `3`, ok: false},
+		{tag: `"`, ok: false},
+	}
+	for _, tt := range tests {
+		version, ok := parseUserETag(tt.tag)
+		if ok != tt.ok || (ok && version != tt.version) {
+			t.Errorf("parseUserETag(%s) = %d, %v; want %d, %v", tt.tag, version, ok, tt.version, tt.ok)
+		}
+	}
+}
+
+func TestUserConditionalRequests(t *testing.T) {
+	e, id := newConditionalTestServer(t, false)
+
+	// 按顺序执行，后面的步骤依赖前面修改后的版本
+	steps := []struct {
+		name     string
+		method   string
+		header   string
+		value    string
+		status   int
+		wantETag string
+	}{
+		{name: "get returns etag", method: http.MethodGet, status: http.StatusOK, wantETag: `"1"`},
+		{name: "if-none-match current", method: http.MethodGet, header: headerIfNoneMatch, value: `"1"`, status: http.StatusNotModified},
+		{name: "if-none-match weak", method: http.MethodGet, header: headerIfNoneMatch, value: `W/"1"`, status: http.StatusNotModified},
+		{name: "if-none-match stale", method: http.MethodGet, header: headerIfNoneMatch, value: `"0", "7"`, status: http.StatusOK},
+		{name: "update with stale if-match", method: http.MethodPut, header: headerIfMatch, value: `"2"`, status: http.StatusPreconditionFailed},
+		{name: "update with weak if-match", method: http.MethodPut, header: headerIfMatch, value: `W/"1"`, status: http.StatusPreconditionFailed},
+		{name: "update with current if-match", method: http.MethodPut, header: headerIfMatch, value: `"1"`, status: http.StatusOK, wantETag: `"2"`},
+		{name: "update with one of several etags", method: http.MethodPut, header: headerIfMatch, value: `"1", "2"`, status: http.StatusOK, wantETag: `"3"`},
+		{name: "update without if-match", method: http.MethodPut, status: http.StatusOK, wantETag: `"4"`},
+		{name: "delete with stale if-match", method: http.MethodDelete, header: headerIfMatch, value: `"3"`, status: http.StatusPreconditionFailed},
+		{name: "delete with current if-match", method: http.MethodDelete, header: headerIfMatch, value: `"4"`, status: http.StatusOK},
+	}
+
+	for _, step := range steps {
+		var body string
+		if step.method == http.MethodPut {
+			body = `{"name":"Anne","email":"ann@example.com"}`
+		}
+		req := httptest.NewRequest(step.method, "/users/"+id, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if step.header != "" {
+			req.Header.Set(step.header, step.value)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != step.status {
+			t.Fatalf("%s: status = %d, want %d: %s", step.name, rec.Code, step.status, rec.Body.String())
+		}
+		if step.wantETag != "" && rec.Header().Get(headerETag) != step.wantETag {
+			t.Fatalf("%s: ETag = %s, want %s", step.name, rec.Header().Get(headerETag), step.wantETag)
+		}
+	}
+}
+
+func TestUserRequireIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		ifMatch string
+		status  int
+	}{
+		{name: "update without if-match", method: http.MethodPut, status: http.StatusPreconditionRequired},
+		{name: "delete without if-match", method: http.MethodDelete, status: http.StatusPreconditionRequired},
+		{name: "wildcard skips the version check", method: http.MethodPut, ifMatch: "*", status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, id := newConditionalTestServer(t, true)
+			req := httptest.NewRequest(tt.method, "/users/"+id, strings.NewReader(`{"name":"Anne","email":"ann@example.com"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tt.ifMatch != "" {
+				req.Header.Set(headerIfMatch, tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+}
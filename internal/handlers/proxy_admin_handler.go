@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go-echo-app/internal/config"
+	"go-echo-app/pkg/utils"
+)
+
+// faultState 故障注入状态
+type faultState struct {
+	Enabled bool               `json:"enabled"`
+	Rules   []config.FaultRule `json:"rules"`
+}
+
+// RequireAdminToken 未启用认证时保护管理接口，校验X-Admin-Token请求头，未配置PROXY_ADMIN_TOKEN时管理接口不可用
+func (h *ProxyHandler) RequireAdminToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if h.config.AdminToken == "" {
+			return utils.NotFound(c, "Admin API is disabled")
+		}
+		token := c.Request().Header.Get("X-Admin-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(h.config.AdminToken)) != 1 {
+			return utils.Unauthorized(c, "Invalid admin token")
+		}
+		return next(c)
+	}
+}
+
+// GetFaults 获取故障注入配置
+func (h *ProxyHandler) GetFaults(c echo.Context) error {
+	return utils.SuccessResponse(c, http.StatusOK, "Fault injection state", h.faultState())
+}
+
+// ReplaceFaults 整体替换故障注入配置
+func (h *ProxyHandler) ReplaceFaults(c echo.Context) error {
+	var req faultState
+	if err := c.Bind(&req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+
+	if err := h.faults.SetRules(req.Rules); err != nil {
+		return utils.BadRequest(c, err.Error())
+	}
+	h.faults.SetEnabled(req.Enabled)
+
+	return utils.SuccessResponse(c, http.StatusOK, "Fault injection updated", h.faultState())
+}
+
+// ToggleFaults 切换故障注入总开关
+func (h *ProxyHandler) ToggleFaults(c echo.Context) error {
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := c.Bind(&req); err != nil || req.Enabled == nil {
+		return utils.BadRequest(c, "Request body must contain 'enabled'")
+	}
+
+	h.faults.SetEnabled(*req.Enabled)
+	return utils.SuccessResponse(c, http.StatusOK, "Fault injection updated", h.faultState())
+}
+
+// ToggleFaultRule 启用或停用单条故障规则
+func (h *ProxyHandler) ToggleFaultRule(c echo.Context) error {
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := c.Bind(&req); err != nil || req.Enabled == nil {
+		return utils.BadRequest(c, "Request body must contain 'enabled'")
+	}
+
+	if !h.faults.SetRuleEnabled(c.Param("id"), *req.Enabled) {
+		return utils.NotFound(c, "Fault rule not found")
+	}
+	return utils.SuccessResponse(c, http.StatusOK, "Fault rule updated", h.faultState())
+}
+
+// GetMirrorStats 获取流量镜像统计
+func (h *ProxyHandler) GetMirrorStats(c echo.Context) error {
+	return utils.SuccessResponse(c, http.StatusOK, "Mirror statistics", h.mirror.Stats())
+}
+
+// GetMirrorDiffs 获取主/影子响应差异记录，可通过upstream参数过滤
+func (h *ProxyHandler) GetMirrorDiffs(c echo.Context) error {
+	diffs := h.mirror.Diffs(c.QueryParam("upstream"))
+	return utils.SuccessResponse(c, http.StatusOK, "Mirror diffs", map[string]interface{}{
+		"diffs": diffs,
+		"count": len(diffs),
+	})
+}
+
+// ClearMirrorDiffs 清空差异记录
+func (h *ProxyHandler) ClearMirrorDiffs(c echo.Context) error {
+	h.mirror.ClearDiffs()
+	return utils.SuccessResponse(c, http.StatusOK, "Mirror diffs cleared", nil)
+}
+
+// GetCanaryStatus 获取各上游的灰度权重和版本指标
+func (h *ProxyHandler) GetCanaryStatus(c echo.Context) error {
+	status := make(map[string]interface{})
+	for _, upstream := range h.upstreams.All() {
+		if len(upstream.Variants) > 0 {
+			status[upstream.Name] = h.canary.Status(upstream)
+		}
+	}
+	return utils.SuccessResponse(c, http.StatusOK, "Canary status", status)
+}
+
+// UpdateCanaryWeights 调整上游各版本的灰度权重
+func (h *ProxyHandler) UpdateCanaryWeights(c echo.Context) error {
+	upstream := h.upstreams.Get(c.Param("upstream"))
+	if upstream == nil {
+		return utils.NotFound(c, "Upstream not found")
+	}
+
+	var req struct {
+		Weights map[string]int `json:"weights"`
+	}
+	if err := c.Bind(&req); err != nil || len(req.Weights) == 0 {
+		return utils.BadRequest(c, "Request body must contain 'weights'")
+	}
+
+	if err := h.canary.SetWeights(upstream, req.Weights); err != nil {
+		return utils.BadRequest(c, err.Error())
+	}
+	return utils.SuccessResponse(c, http.StatusOK, "Canary weights updated", h.canary.Status(upstream))
+}
+
+// faultState 返回当前故障注入状态
+func (h *ProxyHandler) faultState() faultState {
+	return faultState{
+		Enabled: h.faults.Enabled(),
+		Rules:   h.faults.Rules(),
+	}
+}
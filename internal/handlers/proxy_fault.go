@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go-echo-app/internal/proxy"
+)
+
+// sleepContext 等待指定时长，请求被取消时提前返回
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// resetConnection 劫持并以RST方式关闭客户端连接
+func resetConnection(c echo.Context) error {
+	conn, _, err := c.Response().Hijack()
+	if err != nil {
+		// 不支持劫持（如HTTP/2）时中止当前流
+		panic(http.ErrAbortHandler)
+	}
+
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+	return conn.Close()
+}
+
+// truncateResponse 将响应体解码后截断为前n个字节
+func truncateResponse(resp *proxy.Response, n int) error {
+	if err := resp.Decode(); err != nil {
+		return err
+	}
+	if n < len(resp.Body) {
+		resp.Body = resp.Body[:n]
+		resp.Header.Del("Content-Length")
+	}
+	return nil
+}
@@ -2,23 +2,85 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"go-echo-app/internal/auth"
+	"go-echo-app/internal/config"
+	appmiddleware "go-echo-app/internal/middleware"
+	"go-echo-app/internal/proxy"
+	"go-echo-app/pkg/utils"
 )
 
+// maxIdempotencyKeyLength Idempotency-Key的最大长度
+const maxIdempotencyKeyLength = 255
+
+// idempotentMethods 需要幂等键保护的非幂等方法
+var idempotentMethods = map[string]bool{
+	http.MethodPost:  true,
+	http.MethodPatch: true,
+}
+
+// ProxyHandler HTTP中转处理器
+type ProxyHandler struct {
+	config    *config.ProxyConfig
+	upstreams *proxy.Registry
+	faults    *proxy.FaultInjector
+	mirror    *proxy.Mirror
+	canary    *proxy.Canary
+	coalescer *proxy.Coalescer
+	idem      proxy.IdempotencyStore
+	limiter   *proxy.Limiter
+	client    *http.Client
+}
+
+// NewProxyHandler 创建HTTP中转处理器
+func NewProxyHandler(cfg *config.ProxyConfig) (*ProxyHandler, error) {
+	upstreams, err := proxy.NewRegistry(cfg.Upstreams)
+	if err != nil {
+		return nil, err
+	}
+
+	faults, err := proxy.NewFaultInjector(cfg.FaultsEnabled, cfg.Faults)
+	if err != nil {
+		return nil, err
+	}
+
+	// 关闭Transport的自动解压，由中转自行协商上下游编码
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableCompression = true
+	client := &http.Client{Transport: transport}
+
+	return &ProxyHandler{
+		config:    cfg,
+		upstreams: upstreams,
+		faults:    faults,
+		mirror:    proxy.NewMirror(client, cfg.MirrorConcurrency, cfg.MirrorDiffLimit),
+		canary:    proxy.NewCanary(upstreams),
+		coalescer: proxy.NewCoalescer(),
+		idem:      proxy.NewMemoryIdempotencyStore(),
+		limiter:   proxy.NewLimiter(upstreams),
+		client:    client,
+	}, nil
+}
+
 // ProxyRequest 处理HTTP中转请求
-func ProxyRequest(c echo.Context) error {
+func (h *ProxyHandler) ProxyRequest(c echo.Context) error {
 	// 从请求头或查询参数中获取目标URL
 	targetURL := c.QueryParam("target")
 	if targetURL == "" {
 		targetURL = c.Request().Header.Get("X-Target-URL")
 	}
-	
+
 	if targetURL == "" {
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "Missing target URL. Please provide 'target' query parameter or 'X-Target-URL' header",
@@ -34,15 +96,18 @@ func ProxyRequest(c echo.Context) error {
 	}
 
 	// 读取请求体
-	body, err := io.ReadAll(c.Request().Body)
+	body, err := io.ReadAll(h.limitBody(c))
 	if err != nil {
+		if isBodyTooLarge(err) {
+			return h.bodyTooLarge(c)
+		}
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to read request body",
 		})
 	}
 
 	// 创建转发请求
-	req, err := http.NewRequest(c.Request().Method, targetURL, bytes.NewBuffer(body))
+	req, err := http.NewRequest(c.Request().Method, targetURL, nil)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to create request",
@@ -51,48 +116,22 @@ func ProxyRequest(c echo.Context) error {
 
 	// 复制请求头（排除一些不应该转发的头）
 	for key, values := range c.Request().Header {
-		if key != "Host" && key != "X-Target-URL" {
+		if key != "Host" && key != http.CanonicalHeaderKey("X-Target-URL") {
 			for _, value := range values {
 				req.Header.Add(key, value)
 			}
 		}
 	}
-
-	// 设置超时
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-
-	// 发送请求
-	resp, err := client.Do(req)
-	if err != nil {
-		return c.JSON(http.StatusBadGateway, map[string]string{
-			"error": "Failed to forward request: " + err.Error(),
-		})
+	// 调用方已通过网关认证时，其凭证只用于网关，不转发给上游
+	if appmiddleware.GetPrincipal(c) != nil {
+		proxy.StripGatewayCredentials(req.Header)
 	}
-	defer resp.Body.Close()
 
-	// 读取响应体
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to read response body",
-		})
-	}
-
-	// 复制响应头
-	for key, values := range resp.Header {
-		for _, value := range values {
-			c.Response().Header().Add(key, value)
-		}
-	}
-
-	// 返回响应
-	return c.Blob(resp.StatusCode, resp.Header.Get("Content-Type"), respBody)
+	return h.forward(c, req, body, time.Duration(h.config.Timeout)*time.Second)
 }
 
 // ProxyRequestWithConfig 带配置的HTTP中转请求
-func ProxyRequestWithConfig(c echo.Context) error {
+func (h *ProxyHandler) ProxyRequestWithConfig(c echo.Context) error {
 	// 从请求体获取配置
 	var config struct {
 		TargetURL string            `json:"target_url"`
@@ -102,7 +141,11 @@ func ProxyRequestWithConfig(c echo.Context) error {
 		Timeout   int               `json:"timeout,omitempty"`
 	}
 
+	c.Request().Body = h.limitBody(c)
 	if err := c.Bind(&config); err != nil {
+		if isBodyTooLarge(err) {
+			return h.bodyTooLarge(c)
+		}
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "Invalid request body",
 		})
@@ -129,15 +172,14 @@ func ProxyRequestWithConfig(c echo.Context) error {
 	}
 
 	// 准备请求体
-	var body io.Reader
+	var body []byte
 	if config.Body != nil {
 		// 这里可以根据需要将config.Body转换为JSON或其他格式
-		bodyBytes, _ := json.Marshal(config.Body)
-		body = bytes.NewBuffer(bodyBytes)
+		body, _ = json.Marshal(config.Body)
 	}
 
 	// 创建转发请求
-	req, err := http.NewRequest(method, config.TargetURL, body)
+	req, err := http.NewRequest(method, config.TargetURL, nil)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to create request",
@@ -155,29 +197,228 @@ func ProxyRequestWithConfig(c echo.Context) error {
 	}
 
 	// 设置超时
-	timeout := 30 * time.Second
+	timeout := time.Duration(h.config.Timeout) * time.Second
 	if config.Timeout > 0 {
 		timeout = time.Duration(config.Timeout) * time.Second
 	}
 
-	client := &http.Client{
-		Timeout: timeout,
+	return h.forward(c, req, body, timeout)
+}
+
+// limitBody 返回限制了大小的请求体，超过MaxBodySize时读取出错
+func (h *ProxyHandler) limitBody(c echo.Context) io.ReadCloser {
+	if h.config.MaxBodySize <= 0 {
+		return c.Request().Body
+	}
+	return http.MaxBytesReader(c.Response(), c.Request().Body, h.config.MaxBodySize)
+}
+
+// isBodyTooLarge 判断错误是否由请求体超过上限引起
+func isBodyTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
+
+// bodyTooLarge 返回请求体过大的错误响应
+func (h *ProxyHandler) bodyTooLarge(c echo.Context) error {
+	return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{
+		"error": fmt.Sprintf("Request body exceeds %d bytes", h.config.MaxBodySize),
+	})
+}
+
+// forward 将请求发送到上游并把响应写回客户端
+func (h *ProxyHandler) forward(c echo.Context, req *http.Request, body []byte, timeout time.Duration) error {
+	upstream := h.upstreams.Match(req.URL)
+	if !h.allowed(c, upstream) {
+		return utils.Forbidden(c, "Not allowed to proxy to this target")
+	}
+
+	// 幂等键：重复请求直接重放首次响应
+	var idemKey string
+	idempotencyKey := req.Header.Get("Idempotency-Key")
+	if idempotencyKey == "" {
+		idempotencyKey = c.Request().Header.Get("Idempotency-Key")
+	}
+	if idempotencyKey != "" && idempotentMethods[req.Method] {
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Idempotency-Key is too long",
+			})
+		}
+
+		target := req.URL.String()
+		idemKey = proxy.IdempotencyKey(idempotencyKey, clientIdentity(c), req.Method, target)
+		fingerprint := proxy.RequestFingerprint(req.Method, target, body)
+		state, stored := h.idem.Acquire(idemKey, fingerprint, time.Duration(h.config.IdempotencyTTL)*time.Second)
+
+		switch state {
+		case proxy.IdempotencyInProgress:
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "A request with this Idempotency-Key is still in progress",
+			})
+		case proxy.IdempotencyMismatch:
+			return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+				"error": "Idempotency-Key was already used with a different request",
+			})
+		case proxy.IdempotencyReplay:
+			c.Response().Header().Set("Idempotent-Replayed", "true")
+			return h.writeResponse(c, stored)
+		}
+
+		// 未保存响应就结束（转发失败、故障注入等）时释放键，允许客户端重试
+		defer func() {
+			if idemKey != "" {
+				h.idem.Release(idemKey)
+			}
+		}()
+	}
+
+	// 故障注入
+	fault := h.faults.Evaluate(upstreamName(upstream), req.URL.Path, c.Request().Header)
+	if fault.Active() {
+		c.Response().Header().Set("X-Fault-Injected", fault.RuleID)
+		if err := sleepContext(c.Request().Context(), fault.Delay); err != nil {
+			return err
+		}
+		if fault.Reset {
+			return resetConnection(c)
+		}
+		if fault.AbortStatus != 0 {
+			return c.JSON(fault.AbortStatus, map[string]string{
+				"error": "Fault injected by rule " + fault.RuleID,
+			})
+		}
+	}
+
+	// 按上游配置压缩请求体
+	if upstream != nil {
+		encoded, err := upstream.CompressRequestBody(req.Header, body)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to compress request body",
+			})
+		}
+		body = encoded
+	}
+	setRequestBody(req, body)
+
+	// 中转只向上游声明自己能解码的编码
+	req.Header.Set("Accept-Encoding", proxy.AcceptEncoding)
+
+	// 异步镜像到影子上游，不影响主请求；影子请求只带镜像配置的请求头
+	shadow := h.mirror.Start(upstream, req.Method, req.URL, req.Header, body)
+
+	// 上游凭证只来自上游配置
+	if upstream != nil {
+		upstream.SetHeaders(req.Header)
+	}
+
+	// 灰度分流
+	assignment := h.canary.Assign(upstream, c.Request())
+	if assignment != nil {
+		req.URL = upstream.Rewrite(req.URL, assignment.Variant.URL)
+		req.Host = ""
+		c.Response().Header().Set(proxy.DefaultOverrideHeader, assignment.Variant.Name)
+		if assignment.Cookie != nil {
+			c.SetCookie(assignment.Cookie)
+		}
+	}
+
+	start := time.Now()
+	resp, perr := h.send(c, upstream, req, timeout)
+	shadow.Complete(resp)
+
+	if assignment != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		h.canary.Record(upstream, assignment.Variant, status, time.Since(start))
+	}
+
+	if perr != nil {
+		return perr.write(c)
+	}
+
+	if idemKey != "" {
+		h.idem.Complete(idemKey, resp)
+		idemKey = ""
+	}
+
+	if fault.Active() && fault.TruncateBytes >= 0 {
+		if err := truncateResponse(resp, fault.TruncateBytes); err != nil {
+			return c.JSON(http.StatusBadGateway, map[string]string{
+				"error": "Failed to decode upstream response: " + err.Error(),
+			})
+		}
+	}
+
+	return h.writeResponse(c, resp)
+}
+
+// send 发送请求并缓冲上游响应，上游开启合并时相同的并发请求共享一次上游调用
+func (h *ProxyHandler) send(c echo.Context, upstream *proxy.Upstream, req *http.Request, timeout time.Duration) (*proxy.Response, *proxyError) {
+	key, ok := proxy.CoalesceKey(upstream, req)
+	if !ok {
+		ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+		defer cancel()
+		return h.do(ctx, upstream, req)
+	}
+
+	resp, shared, err := h.coalescer.Do(c.Request().Context(), key, func() (*proxy.Response, error) {
+		// 共享的上游调用不随发起方断开而取消
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request().Context()), timeout)
+		defer cancel()
+
+		resp, perr := h.do(ctx, upstream, req)
+		if perr != nil {
+			return nil, perr
+		}
+		return resp, nil
+	})
+	if err != nil {
+		var perr *proxyError
+		if errors.As(err, &perr) {
+			return nil, perr
+		}
+		return nil, &proxyError{status: http.StatusGatewayTimeout, message: "Request canceled: " + err.Error()}
 	}
 
-	// 发送请求
-	resp, err := client.Do(req)
+	if shared {
+		c.Response().Header().Set("X-Proxy-Coalesced", "true")
+	}
+	return resp, nil
+}
+
+// do 在上游限流许可内发送单个请求并缓冲上游响应
+func (h *ProxyHandler) do(ctx context.Context, upstream *proxy.Upstream, req *http.Request) (*proxy.Response, *proxyError) {
+	release, err := h.limiter.Acquire(ctx, upstream)
 	if err != nil {
-		return c.JSON(http.StatusBadGateway, map[string]string{
-			"error": "Failed to forward request: " + err.Error(),
-		})
+		return nil, limitError(err)
 	}
-	defer resp.Body.Close()
+	defer release()
 
-	// 读取响应体
-	respBody, err := io.ReadAll(resp.Body)
+	httpResp, err := h.client.Do(req.WithContext(ctx))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to read response body",
+		return nil, &proxyError{status: http.StatusBadGateway, message: "Failed to forward request: " + err.Error()}
+	}
+
+	resp, err := proxy.ReadResponse(httpResp)
+	if err != nil {
+		return nil, &proxyError{status: http.StatusInternalServerError, message: "Failed to read response body"}
+	}
+	return resp, nil
+}
+
+// writeResponse 按客户端Accept-Encoding协商编码后写回上游响应
+func (h *ProxyHandler) writeResponse(c echo.Context, resp *proxy.Response) error {
+	minSize := 0
+	if h.config.CompressResponses {
+		minSize = h.config.CompressMinSize
+	}
+	if err := resp.Negotiate(c.Request().Header.Get("Accept-Encoding"), minSize); err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]string{
+			"error": "Failed to decode upstream response: " + err.Error(),
 		})
 	}
 
@@ -189,5 +430,87 @@ func ProxyRequestWithConfig(c echo.Context) error {
 	}
 
 	// 返回响应
-	return c.Blob(resp.StatusCode, resp.Header.Get("Content-Type"), respBody)
-}
\ No newline at end of file
+	return c.Blob(resp.StatusCode, resp.Header.Get("Content-Type"), resp.Body)
+}
+
+// proxyError 转发失败时返回给客户端的错误
+type proxyError struct {
+	status     int
+	message    string
+	retryAfter time.Duration
+}
+
+// limitError 将上游限流错误转换为客户端错误
+func limitError(err error) *proxyError {
+	var le *proxy.LimitError
+	if !errors.As(err, &le) {
+		return &proxyError{status: http.StatusGatewayTimeout, message: "Request canceled: " + err.Error()}
+	}
+
+	status := http.StatusServiceUnavailable
+	if errors.Is(err, proxy.ErrRateLimited) {
+		status = http.StatusTooManyRequests
+	}
+	return &proxyError{status: status, message: le.Error(), retryAfter: le.RetryAfter}
+}
+
+// Error 实现error接口
+func (e *proxyError) Error() string {
+	return e.message
+}
+
+// write 将错误写回客户端
+func (e *proxyError) write(c echo.Context) error {
+	if e.retryAfter > 0 {
+		seconds := int(math.Ceil(e.retryAfter.Seconds()))
+		c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	return c.JSON(e.status, map[string]string{
+		"error": e.message,
+	})
+}
+
+// setRequestBody 设置转发请求的请求体
+func setRequestBody(req *http.Request, body []byte) {
+	if len(body) == 0 {
+		req.Body = nil
+		req.ContentLength = 0
+		return
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+}
+
+// allowed 按上游的角色策略判断调用方能否中转，未启用认证或拥有proxy:admin权限时不限制
+func (h *ProxyHandler) allowed(c echo.Context, upstream *proxy.Upstream) bool {
+	principal := appmiddleware.GetPrincipal(c)
+	if principal == nil || principal.HasPermission(auth.PermissionProxyAdmin) {
+		return true
+	}
+
+	roles := h.config.UnmatchedRoles
+	if upstream != nil {
+		roles = upstream.Config.AllowedRoles
+	}
+	return len(roles) == 0 || principal.HasAnyRole(roles...)
+}
+
+// clientIdentity 返回用于区分客户端的标识，已认证时使用调用方身份
+func clientIdentity(c echo.Context) string {
+	if principal := appmiddleware.GetPrincipal(c); principal != nil {
+		return "sub:" + principal.Subject
+	}
+	return "ip:" + c.RealIP()
+}
+
+// upstreamName 返回上游名称，未匹配上游时为空
+func upstreamName(upstream *proxy.Upstream) string {
+	if upstream == nil {
+		return ""
+	}
+	return upstream.Name
+}
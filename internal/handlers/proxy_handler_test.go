@@ -0,0 +1,503 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go-echo-app/internal/auth"
+	"go-echo-app/internal/config"
+	appmiddleware "go-echo-app/internal/middleware"
+	"go-echo-app/internal/proxy"
+)
+
+// upstreamRequest 测试上游收到的请求
+type upstreamRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newTestUpstream 启动返回gzip编码JSON的测试上游，收到的请求写入received
+func newTestUpstream(t *testing.T, received chan<- upstreamRequest) *httptest.Server {
+	t.Helper()
+	body, err := proxy.Encode(proxy.EncodingGzip, []byte(`{"ok":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received <- upstreamRequest{header: r.Header.Clone(), body: data}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", proxy.EncodingGzip)
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestProxyServer 创建注册了中转路由的服务
+func newTestProxyServer(t *testing.T, cfg *config.ProxyConfig) *echo.Echo {
+	t.Helper()
+	h, err := NewProxyHandler(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	e.Any("/proxy", h.ProxyRequest)
+	e.POST("/proxy/config", h.ProxyRequestWithConfig)
+	return e
+}
+
+func TestProxyRequestShaping(t *testing.T) {
+	received := make(chan upstreamRequest, 1)
+	upstream := newTestUpstream(t, received)
+	e := newTestProxyServer(t, &config.ProxyConfig{
+		Timeout: 5,
+		Upstreams: []config.UpstreamConfig{{
+			Name:        "api",
+			URL:         upstream.URL,
+			Compression: config.CompressionConfig{RequestEncoding: "gzip", MinSize: 4},
+		}},
+	})
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		header       map[string]string
+		body         string
+		wantBody     string
+		wantEncoding string
+		wantHeader   map[string]string
+	}{
+		{
+			name:       "request body compressed and headers forwarded",
+			method:     http.MethodPost,
+			path:       "/proxy?target=" + upstream.URL + "/items",
+			header:     map[string]string{"X-Request-Tag": "abc", "Accept-Encoding": "identity"},
+			body:       "hello upstream",
+			wantBody:   `{"ok":true}`,
+			wantHeader: map[string]string{"X-Request-Tag": "abc", "Content-Encoding": "gzip", "Accept-Encoding": proxy.AcceptEncoding},
+		},
+		{
+			name:       "target header is not forwarded",
+			method:     http.MethodGet,
+			path:       "/proxy",
+			header:     map[string]string{"X-Target-URL": upstream.URL + "/items"},
+			wantBody:   `{"ok":true}`,
+			wantHeader: map[string]string{"X-Target-Url": "", "Content-Encoding": ""},
+		},
+		{
+			name:         "encoding accepted by the client is passed through",
+			method:       http.MethodGet,
+			path:         "/proxy?target=" + upstream.URL + "/items",
+			header:       map[string]string{"Accept-Encoding": "gzip"},
+			wantBody:     `{"ok":true}`,
+			wantEncoding: proxy.EncodingGzip,
+		},
+		{
+			name:       "config request sets headers and JSON body",
+			method:     http.MethodPost,
+			path:       "/proxy/config",
+			header:     map[string]string{"Content-Type": "application/json"},
+			body:       `{"target_url":"` + upstream.URL + `/items","method":"PUT","headers":{"X-Custom":"1"},"body":{"a":1}}`,
+			wantBody:   `{"ok":true}`,
+			wantHeader: map[string]string{"X-Custom": "1", "Content-Type": "application/json", "Content-Encoding": "gzip"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+			}
+			got := <-received
+			for k, want := range tt.wantHeader {
+				if v := got.header.Get(k); v != want {
+					t.Errorf("upstream header %s = %q, want %q", k, v, want)
+				}
+			}
+			if got.header.Get("Content-Encoding") == proxy.EncodingGzip {
+				if _, err := proxy.Decode(proxy.EncodingGzip, got.body); err != nil {
+					t.Errorf("upstream body is not gzip: %v", err)
+				}
+			}
+
+			if enc := rec.Header().Get("Content-Encoding"); enc != tt.wantEncoding {
+				t.Fatalf("response Content-Encoding = %q, want %q", enc, tt.wantEncoding)
+			}
+			body, err := proxy.Decode(rec.Header().Get("Content-Encoding"), rec.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.wantBody {
+				t.Fatalf("response body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestProxyRequestValidation(t *testing.T) {
+	e := newTestProxyServer(t, &config.ProxyConfig{Timeout: 5})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "missing target", method: http.MethodGet, path: "/proxy", status: http.StatusBadRequest},
+		{name: "invalid target", method: http.MethodGet, path: "/proxy?target=%3A%2F%2Fbad", status: http.StatusBadRequest},
+		{name: "config without target", method: http.MethodPost, path: "/proxy/config", body: `{}`, status: http.StatusBadRequest},
+		{name: "unreachable target", method: http.MethodGet, path: "/proxy?target=http://127.0.0.1:1/x", status: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestFaultAdminRequiresToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		status int
+	}{
+		{name: "disabled without a configured token", token: "", header: "", status: http.StatusNotFound},
+		{name: "disabled even when a token is sent", token: "", header: "secret", status: http.StatusNotFound},
+		{name: "missing token", token: "secret", header: "", status: http.StatusUnauthorized},
+		{name: "wrong token", token: "secret", header: "guess", status: http.StatusUnauthorized},
+		{name: "valid token", token: "secret", header: "secret", status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := NewProxyHandler(&config.ProxyConfig{AdminToken: tt.token})
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := echo.New()
+			e.Group("/admin", h.RequireAdminToken).GET("/faults", h.GetFaults)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/faults", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Admin-Token", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestProxyIdempotencyKey(t *testing.T) {
+	var hits int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "order %d", n)
+	}))
+	defer upstream.Close()
+	e := newTestProxyServer(t, &config.ProxyConfig{Timeout: 5, IdempotencyTTL: 60})
+
+	tests := []struct {
+		name       string
+		method     string
+		key        string
+		body       string
+		status     int
+		wantBody   string
+		wantReplay bool
+	}{
+		{name: "first request is forwarded", method: http.MethodPost, key: "k1", body: "a", status: http.StatusCreated, wantBody: "order 1"},
+		{name: "retry is replayed", method: http.MethodPost, key: "k1", body: "a", status: http.StatusCreated, wantBody: "order 1", wantReplay: true},
+		{name: "key reused with another body", method: http.MethodPost, key: "k1", body: "b", status: http.StatusUnprocessableEntity},
+		{name: "new key is forwarded", method: http.MethodPost, key: "k2", body: "a", status: http.StatusCreated, wantBody: "order 2"},
+		{name: "without a key every call is forwarded", method: http.MethodPost, body: "a", status: http.StatusCreated, wantBody: "order 3"},
+		{name: "idempotent methods ignore the key", method: http.MethodPut, key: "k1", body: "a", status: http.StatusCreated, wantBody: "order 4"},
+		{name: "key too long", method: http.MethodPost, key: strings.Repeat("k", maxIdempotencyKeyLength+1), status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/proxy?target="+upstream.URL+"/orders", strings.NewReader(tt.body))
+			if tt.key != "" {
+				req.Header.Set("Idempotency-Key", tt.key)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Fatalf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+			if replayed := rec.Header().Get("Idempotent-Replayed") == "true"; replayed != tt.wantReplay {
+				t.Fatalf("replayed = %v, want %v", replayed, tt.wantReplay)
+			}
+		})
+	}
+}
+
+func TestProxyUpstreamRoles(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	h, err := NewProxyHandler(&config.ProxyConfig{
+		Timeout:        5,
+		UnmatchedRoles: []string{"ops"},
+		Upstreams: []config.UpstreamConfig{
+			{Name: "billing", URL: upstream.URL + "/billing", AllowedRoles: []string{"finance"}},
+			{Name: "open", URL: upstream.URL + "/open"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		path      string
+		status    int
+	}{
+		{name: "auth disabled", path: "/billing/x", status: http.StatusOK},
+		{name: "allowed role", principal: &auth.Principal{Subject: "u1", Roles: []string{"finance"}}, path: "/billing/x", status: http.StatusOK},
+		{name: "role not allowed", principal: &auth.Principal{Subject: "u1", Roles: []string{"ops"}}, path: "/billing/x", status: http.StatusForbidden},
+		{name: "proxy admin bypasses roles", principal: &auth.Principal{Subject: "u1", Permissions: []string{auth.PermissionProxyAdmin}}, path: "/billing/x", status: http.StatusOK},
+		{name: "upstream without roles", principal: &auth.Principal{Subject: "u1"}, path: "/open/x", status: http.StatusOK},
+		{name: "unmatched target allowed", principal: &auth.Principal{Subject: "u1", Roles: []string{"ops"}}, path: "/other", status: http.StatusOK},
+		{name: "unmatched target denied", principal: &auth.Principal{Subject: "u1", Roles: []string{"finance"}}, path: "/other", status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.GET("/proxy", h.ProxyRequest, func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					if tt.principal != nil {
+						appmiddleware.SetPrincipal(c, tt.principal)
+					}
+					return next(c)
+				}
+			})
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/proxy?target="+upstream.URL+tt.path, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestProxyRequestBodyLimit(t *testing.T) {
+	received := make(chan upstreamRequest, 10)
+	upstream := newTestUpstream(t, received)
+	e := newTestProxyServer(t, &config.ProxyConfig{Timeout: 5, MaxBodySize: 16})
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "body within limit", path: "/proxy?target=" + upstream.URL, body: strings.Repeat("a", 16), status: http.StatusOK},
+		{name: "body over limit", path: "/proxy?target=" + upstream.URL, body: strings.Repeat("a", 17), status: http.StatusRequestEntityTooLarge},
+		{name: "config body over limit", path: "/proxy/config", body: `{"target_url":"` + upstream.URL + `"}`, status: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+	if len(received) != 1 {
+		t.Fatalf("upstream received %d requests, want only the one within the limit", len(received))
+	}
+}
+
+func TestProxyStripsGatewayCredentials(t *testing.T) {
+	received := make(chan upstreamRequest, 1)
+	shadowReceived := make(chan upstreamRequest, 1)
+	target := newTestUpstream(t, received)
+	shadow := newTestUpstream(t, shadowReceived)
+
+	h, err := NewProxyHandler(&config.ProxyConfig{
+		Timeout:           5,
+		MirrorConcurrency: 1,
+		Upstreams: []config.UpstreamConfig{
+			{
+				Name:    "billing",
+				URL:     target.URL + "/billing",
+				Headers: map[string]string{"Authorization": "Bearer upstream-secret"},
+				Mirror:  &config.MirrorConfig{URL: shadow.URL + "/billing", Headers: map[string]string{"X-Shadow-Key": "shadow-secret"}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		authenticated bool
+		path          string
+		wantAuth      string
+		wantShadow    bool
+	}{
+		{name: "configured upstream credentials replace the gateway token", authenticated: true, path: "/billing/x", wantAuth: "Bearer upstream-secret", wantShadow: true},
+		{name: "unmatched target gets no credentials", authenticated: true, path: "/other", wantAuth: ""},
+		{name: "without gateway auth the header belongs to the target", authenticated: false, path: "/other", wantAuth: "Bearer gateway-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.GET("/proxy", h.ProxyRequest, func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					if tt.authenticated {
+						appmiddleware.SetPrincipal(c, &auth.Principal{Subject: "u1"})
+					}
+					return next(c)
+				}
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/proxy?target="+target.URL+tt.path, nil)
+			req.Header.Set("Authorization", "Bearer gateway-token")
+			req.Header.Set("X-API-Key", "gateway-key")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+			}
+
+			got := <-received
+			if auth := got.header.Get("Authorization"); auth != tt.wantAuth {
+				t.Fatalf("target Authorization = %q, want %q", auth, tt.wantAuth)
+			}
+			if key := got.header.Get("X-API-Key"); tt.authenticated && key != "" {
+				t.Fatalf("target received the gateway API key %q", key)
+			}
+
+			if !tt.wantShadow {
+				return
+			}
+			select {
+			case s := <-shadowReceived:
+				if s.header.Get("Authorization") != "" || s.header.Get("X-API-Key") != "" {
+					t.Fatalf("shadow received credentials: %v", s.header)
+				}
+				if s.header.Get("X-Shadow-Key") != "shadow-secret" {
+					t.Fatalf("shadow X-Shadow-Key = %q", s.header.Get("X-Shadow-Key"))
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("shadow request not sent")
+			}
+		})
+	}
+}
+
+func TestProxyCoalescesAuthenticatedRequests(t *testing.T) {
+	const callers = 10
+	var hits int32
+	arrived := make(chan struct{}, callers)
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		arrived <- struct{}{}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"items":[]}`))
+	}))
+	defer upstream.Close()
+
+	h, err := NewProxyHandler(&config.ProxyConfig{
+		Timeout: 5,
+		Upstreams: []config.UpstreamConfig{{
+			Name:     "catalog",
+			URL:      upstream.URL,
+			Headers:  map[string]string{"Authorization": "Bearer upstream-secret"},
+			Coalesce: config.CoalesceConfig{Enabled: true},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	e.GET("/proxy", h.ProxyRequest, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			appmiddleware.SetPrincipal(c, &auth.Principal{Subject: c.Request().Header.Get("X-Test-User")})
+			return next(c)
+		}
+	})
+
+	var wg sync.WaitGroup
+	codes := make([]int, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/proxy?target="+upstream.URL+"/items", nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer gateway-token-%d", i))
+			req.Header.Set("X-Test-User", fmt.Sprintf("u%d", i))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			codes[i] = rec.Code
+		}(i)
+	}
+
+	// 第一个请求到达上游后留出时间让其余请求加入同一次调用
+	<-arrived
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Fatalf("caller %d: status = %d", i, code)
+		}
+	}
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Fatalf("upstream hits = %d, want 1", n)
+	}
+}
@@ -1,92 +1,323 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
+	"go-echo-app/internal/auth"
+	"go-echo-app/internal/config"
+	appmiddleware "go-echo-app/internal/middleware"
+	"go-echo-app/internal/models"
+	"go-echo-app/internal/services"
+	"go-echo-app/pkg/jsonpatch"
+	"go-echo-app/pkg/utils"
 )
 
-// User 用户结构体
-type User struct {
-	ID    string `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
+// UserHandler 用户处理器
+type UserHandler struct {
+	service        models.UserService
+	requireIfMatch bool // 修改和删除用户时必须携带If-Match
 }
 
-// GetUsers 获取所有用户
-func GetUsers(c echo.Context) error {
-	users := []User{
-		{ID: "1", Name: "John Doe", Email: "john@example.com"},
-		{ID: "2", Name: "Jane Smith", Email: "jane@example.com"},
-		{ID: "3", Name: "Bob Johnson", Email: "bob@example.com"},
-	}
-	
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"users": users,
-		"count": len(users),
-	})
+// NewUserHandler 创建用户处理器
+func NewUserHandler(service models.UserService, cfg *config.UsersConfig) *UserHandler {
+	return &UserHandler{service: service, requireIfMatch: cfg.RequireIfMatch}
+}
+
+// GetUsers 分页获取用户，支持过滤和排序，相邻页的链接同时写入Link响应头
+func (h *UserHandler) GetUsers(c echo.Context) error {
+	req := new(models.ListUsersRequest)
+	if err := c.Bind(req); err != nil {
+		return utils.BadRequest(c, "Invalid query parameters")
+	}
+	if err := c.Validate(req); err != nil {
+		return utils.ValidationError(c, validationMessage(err))
+	}
+	if req.IncludeDeleted && !hasPermission(c, auth.PermissionUsersAdmin) {
+		return utils.Forbidden(c, "Missing permission "+auth.PermissionUsersAdmin)
+	}
+
+	resp, err := h.service.ListUsers(c.Request().Context(), req)
+	if err != nil {
+		return h.serviceError(c, err)
+	}
+
+	if resp.Page > 0 {
+		// 等价于Page*Limit < Total，用除法避免溢出
+		if resp.Page <= (resp.Total-1)/resp.Limit {
+			resp.Links.Next = pageLink(c, "page", strconv.Itoa(resp.Page+1))
+		}
+		if resp.Page > 1 {
+			resp.Links.Prev = pageLink(c, "page", strconv.Itoa(resp.Page-1))
+		}
+	} else {
+		if resp.NextCursor != "" {
+			resp.Links.Next = pageLink(c, "cursor", resp.NextCursor)
+		}
+		if resp.PrevCursor != "" {
+			resp.Links.Prev = pageLink(c, "cursor", resp.PrevCursor)
+		}
+	}
+
+	var links []string
+	if resp.Links.Next != "" {
+		links = append(links, "<"+resp.Links.Next+`>; rel="next"`)
+	}
+	if resp.Links.Prev != "" {
+		links = append(links, "<"+resp.Links.Prev+`>; rel="prev"`)
+	}
+	if len(links) > 0 {
+		c.Response().Header().Set("Link", strings.Join(links, ", "))
+	}
+
+	return utils.SuccessResponse(c, http.StatusOK, "Users retrieved successfully", resp)
+}
+
+// SearchUsers 按姓名、邮箱或电话搜索用户
+func (h *UserHandler) SearchUsers(c echo.Context) error {
+	req := new(models.SearchUsersRequest)
+	if err := c.Bind(req); err != nil {
+		return utils.BadRequest(c, "Invalid query parameters")
+	}
+	if err := c.Validate(req); err != nil {
+		return utils.ValidationError(c, validationMessage(err))
+	}
+
+	resp, err := h.service.SearchUsers(c.Request().Context(), req)
+	if err != nil {
+		return h.serviceError(c, err)
+	}
+	return utils.SuccessResponse(c, http.StatusOK, "Users retrieved successfully", resp)
 }
 
-// GetUser 根据ID获取用户
-func GetUser(c echo.Context) error {
-	id := c.Param("id")
-	
-	// 模拟从数据库获取用户
-	user := User{
-		ID:    id,
-		Name:  "John Doe",
-		Email: "john@example.com",
-	}
-	
-	return c.JSON(http.StatusOK, user)
+// GetUser 根据ID获取用户，响应头ETag为用户版本，If-None-Match匹配时返回304。
+// include_deleted=true时也返回已删除的用户，需要users:admin权限
+func (h *UserHandler) GetUser(c echo.Context) error {
+	includeDeleted := false
+	if value := c.QueryParam("include_deleted"); value != "" {
+		var err error
+		if includeDeleted, err = strconv.ParseBool(value); err != nil {
+			return utils.BadRequest(c, "Invalid query parameters")
+		}
+	}
+	if includeDeleted && !hasPermission(c, auth.PermissionUsersAdmin) {
+		return utils.Forbidden(c, "Missing permission "+auth.PermissionUsersAdmin)
+	}
+
+	user, err := h.service.GetUser(c.Request().Context(), c.Param("id"), includeDeleted)
+	if err != nil {
+		return h.serviceError(c, err)
+	}
+
+	etag := userETag(user.Version)
+	c.Response().Header().Set(headerETag, etag)
+	if notModified(c, etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return utils.SuccessResponse(c, http.StatusOK, "User retrieved successfully", user)
 }
 
 // CreateUser 创建新用户
-func CreateUser(c echo.Context) error {
-	user := new(User)
-	if err := c.Bind(user); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid request body",
-		})
-	}
-	
-	// 这里应该添加用户到数据库
-	// 模拟设置ID
-	user.ID = "new-id"
-	
-	return c.JSON(http.StatusCreated, map[string]interface{}{
-		"message": "User created successfully",
-		"user":    user,
-	})
+func (h *UserHandler) CreateUser(c echo.Context) error {
+	req := new(models.CreateUserRequest)
+	if err := c.Bind(req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+	if err := c.Validate(req); err != nil {
+		return utils.ValidationError(c, validationMessage(err))
+	}
+
+	user, err := h.service.CreateUser(c.Request().Context(), req)
+	if err != nil {
+		return h.serviceError(c, err)
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, c.Request().URL.Path+"/"+user.ID)
+	c.Response().Header().Set(headerETag, userETag(user.Version))
+	return utils.SuccessResponse(c, http.StatusCreated, "User created successfully", user)
 }
 
-// UpdateUser 更新用户信息
-func UpdateUser(c echo.Context) error {
-	id := c.Param("id")
-	user := new(User)
-	
-	if err := c.Bind(user); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid request body",
-		})
-	}
-	
-	user.ID = id
-	
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "User updated successfully",
-		"user":    user,
-	})
+// UpdateUser 更新用户信息，携带If-Match时只在版本一致时更新
+func (h *UserHandler) UpdateUser(c echo.Context) error {
+	version, err := h.ifMatchVersion(c)
+	if err != nil {
+		return h.serviceError(c, err)
+	}
+
+	req := new(models.UpdateUserRequest)
+	if err := c.Bind(req); err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+	if err := c.Validate(req); err != nil {
+		return utils.ValidationError(c, validationMessage(err))
+	}
+
+	user, err := h.service.UpdateUser(c.Request().Context(), c.Param("id"), version, req)
+	if err != nil {
+		return h.serviceError(c, err)
+	}
+
+	c.Response().Header().Set(headerETag, userETag(user.Version))
+	return utils.SuccessResponse(c, http.StatusOK, "User updated successfully", user)
 }
 
-// DeleteUser 删除用户
-func DeleteUser(c echo.Context) error {
-	id := c.Param("id")
-	
-	// 这里应该从数据库删除用户
-	
-	return c.JSON(http.StatusOK, map[string]string{
-		"message": "User deleted successfully",
-		"id":      id,
+// PatchUser 部分更新用户。请求体为JSON Merge Patch或JSON Patch，应用在由name、email、phone组成的文档上，
+// 结果按与PUT相同的规则校验；携带If-Match时只在版本一致时更新
+func (h *UserHandler) PatchUser(c echo.Context) error {
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	if mediaType != jsonpatch.MediaTypeMergePatch && mediaType != jsonpatch.MediaTypeJSONPatch {
+		c.Response().Header().Set("Accept-Patch", jsonpatch.MediaTypeMergePatch+", "+jsonpatch.MediaTypeJSONPatch)
+		return utils.UnsupportedMediaType(c, "Content-Type must be "+jsonpatch.MediaTypeMergePatch+" or "+jsonpatch.MediaTypeJSONPatch)
+	}
+	version, err := h.ifMatchVersion(c)
+	if err != nil {
+		return h.serviceError(c, err)
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return utils.BadRequest(c, "Invalid request body")
+	}
+
+	// 先检查补丁文档本身，格式错误时返回400，不读取用户
+	var apply func(doc []byte) ([]byte, error)
+	if mediaType == jsonpatch.MediaTypeJSONPatch {
+		patch, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			return utils.BadRequest(c, err.Error())
+		}
+		apply = patch.Apply
+	} else {
+		if !json.Valid(body) {
+			return utils.BadRequest(c, "Invalid request body")
+		}
+		apply = func(doc []byte) ([]byte, error) {
+			return jsonpatch.MergePatch(doc, body)
+		}
+	}
+
+	user, err := h.service.PatchUser(c.Request().Context(), c.Param("id"), version, func(req *models.UpdateUserRequest) error {
+		doc, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		patched, err := apply(doc)
+		if err != nil {
+			return &patchError{message: "Patch cannot be applied: " + err.Error()}
+		}
+
+		*req = models.UpdateUserRequest{}
+		dec := json.NewDecoder(bytes.NewReader(patched))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(req); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				if typeErr.Field == "" {
+					return &patchError{message: "Patched user must be a JSON object"}
+				}
+				return &patchError{message: typeErr.Field + " must be a " + typeErr.Type.Kind().String(), validation: true}
+			}
+			return &patchError{message: "Patched user is invalid: " + strings.TrimPrefix(err.Error(), "json: ")}
+		}
+		if err := c.Validate(req); err != nil {
+			return &patchError{message: validationMessage(err), validation: true}
+		}
+		return nil
 	})
-}
\ No newline at end of file
+	if err != nil {
+		var patchErr *patchError
+		if errors.As(err, &patchErr) {
+			if patchErr.validation {
+				return utils.ValidationError(c, patchErr.message)
+			}
+			return utils.UnprocessableEntity(c, patchErr.message)
+		}
+		return h.serviceError(c, err)
+	}
+
+	c.Response().Header().Set(headerETag, userETag(user.Version))
+	return utils.SuccessResponse(c, http.StatusOK, "User updated successfully", user)
+}
+
+// patchError 补丁无法应用或应用后的用户未通过校验
+type patchError struct {
+	message    string
+	validation bool
+}
+
+func (e *patchError) Error() string {
+	return e.message
+}
+
+// DeleteUser 软删除用户，携带If-Match时只在版本一致时删除
+func (h *UserHandler) DeleteUser(c echo.Context) error {
+	version, err := h.ifMatchVersion(c)
+	if err != nil {
+		return h.serviceError(c, err)
+	}
+
+	if err := h.service.DeleteUser(c.Request().Context(), c.Param("id"), version); err != nil {
+		return h.serviceError(c, err)
+	}
+
+	return utils.SuccessResponse(c, http.StatusOK, "User deleted successfully", nil)
+}
+
+// RestoreUser 恢复已删除的用户，携带If-Match时只在版本一致时恢复
+func (h *UserHandler) RestoreUser(c echo.Context) error {
+	version, err := h.ifMatchVersion(c)
+	if err != nil {
+		return h.serviceError(c, err)
+	}
+
+	user, err := h.service.RestoreUser(c.Request().Context(), c.Param("id"), version)
+	if err != nil {
+		return h.serviceError(c, err)
+	}
+
+	c.Response().Header().Set(headerETag, userETag(user.Version))
+	return utils.SuccessResponse(c, http.StatusOK, "User restored successfully", user)
+}
+
+// hasPermission 判断调用方是否拥有权限。与RequirePermission一致，未经认证的调用方（关闭认证或免认证路由）不做检查
+func hasPermission(c echo.Context, permission string) bool {
+	principal := appmiddleware.GetPrincipal(c)
+	return principal == nil || principal.HasPermission(permission)
+}
+
+// pageLink 返回保留当前查询条件、只替换分页参数的相对链接
+func pageLink(c echo.Context, key, value string) string {
+	query := c.Request().URL.Query()
+	query.Del("page")
+	query.Del("cursor")
+	query.Set(key, value)
+	return c.Request().URL.Path + "?" + query.Encode()
+}
+
+// serviceError 将服务错误转换为HTTP响应
+func (h *UserHandler) serviceError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		return utils.NotFound(c, "User not found")
+	case errors.Is(err, services.ErrEmailTaken):
+		return utils.Conflict(c, "Email already in use")
+	case errors.Is(err, services.ErrInvalidCursor):
+		return utils.BadRequest(c, "Invalid cursor")
+	case errors.Is(err, services.ErrUserNotDeleted):
+		return utils.Conflict(c, "User is not deleted")
+	case errors.Is(err, services.ErrVersionMismatch):
+		return utils.PreconditionFailed(c, "User has been modified, fetch the latest version and retry")
+	case errors.Is(err, errIfMatchRequired):
+		return utils.PreconditionRequired(c, "If-Match header is required")
+	}
+
+	c.Logger().Errorf("user service error: %v", err)
+	return utils.InternalServerError(c, "Internal server error")
+}
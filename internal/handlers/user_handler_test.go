@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go-echo-app/internal/config"
+	"go-echo-app/internal/models"
+	"go-echo-app/internal/repository"
+	"go-echo-app/internal/services"
+	"go-echo-app/pkg/validator"
+)
+
+// newListTestServer 创建只注册GET /users的服务，仓库中有n个用户
+func newListTestServer(t *testing.T, n int) *echo.Echo {
+	t.Helper()
+	repo := repository.NewMemoryUserRepository()
+	now := time.Now().UTC()
+	for i := 0; i < n; i++ {
+		user := &models.User{ID: fmt.Sprintf("u%02d", i), Name: "User", Email: fmt.Sprintf("u%02d@example.com", i), CreatedAt: now, UpdatedAt: now}
+		if err := repo.Create(context.Background(), user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e := echo.New()
+	e.Validator = validator.NewCustomValidator()
+	h := NewUserHandler(services.NewUserService(repo, nil), &config.UsersConfig{})
+	e.GET("/users", h.GetUsers)
+	return e
+}
+
+func TestGetUsersPageBounds(t *testing.T) {
+	e := newListTestServer(t, 5)
+
+	tests := []struct {
+		query    string
+		status   int
+		count    int
+		wantNext bool
+	}{
+		{query: "page=1&limit=2", status: http.StatusOK, count: 2, wantNext: true},
+		{query: "page=2&limit=2", status: http.StatusOK, count: 2, wantNext: true},
+		{query: "page=3&limit=2", status: http.StatusOK, count: 1, wantNext: false},
+		{query: "page=3&limit=100", status: http.StatusOK, count: 0, wantNext: false},
+		{query: "page=10000&limit=100", status: http.StatusOK, count: 0, wantNext: false},
+		// 过大的页码会使偏移量溢出为负数，必须在校验阶段拒绝
+		{query: "page=10001", status: http.StatusUnprocessableEntity},
+		{query: "page=92233720368547758&limit=100", status: http.StatusUnprocessableEntity},
+		{query: "page=9223372036854775807", status: http.StatusUnprocessableEntity},
+		{query: "page=99999999999999999999", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users?"+tt.query, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Data models.UserListResponse `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Data.Count != tt.count || (body.Data.Links.Next != "") != tt.wantNext {
+				t.Fatalf("count = %d, next = %q; want %d, next %v", body.Data.Count, body.Data.Links.Next, tt.count, tt.wantNext)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"strings"
+
+	"go-echo-app/pkg/validator"
+)
+
+// validationMessage 将校验错误转换为可读的消息
+func validationMessage(err error) string {
+	errs := validator.GetValidationErrors(err)
+	if len(errs) == 0 {
+		return err.Error()
+	}
+
+	messages := make([]string, 0, len(errs))
+	for _, e := range errs {
+		messages = append(messages, e.Message)
+	}
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"go-echo-app/internal/auth"
+	"go-echo-app/internal/models"
+	"go-echo-app/internal/repository"
+	"go-echo-app/pkg/utils"
+)
+
+// 认证相关的上下文键
+const (
+	ContextKeyUserID    = "user_id"
+	ContextKeyClaims    = "claims"
+	ContextKeyPrincipal = "principal"
+	// ContextKeyPublicRoute 请求命中免认证路由时为true，此时不做权限检查
+	ContextKeyPublicRoute = "public_route"
+)
+
+// AuthConfig 认证中间件配置
+type AuthConfig struct {
+	Skipper  middleware.Skipper
+	Verifier auth.TokenVerifier
+	// APIKeyVerifier 校验X-API-Key或Authorization: ApiKey携带的Key，为nil时不接受API Key
+	APIKeyVerifier auth.TokenVerifier
+	// Users 设置后拒绝对应用户已被删除或不存在的调用方，使删除前签发、尚未过期的访问令牌立即失效
+	Users models.UserRepository
+	// Policy 计算调用方有效权限的角色策略，为nil时使用内置角色
+	Policy *auth.Policy
+	// PublicRoutes 免认证路由，格式 "METHOD /path" 或 "/path"，以*结尾时按前缀匹配
+	PublicRoutes []string
+	// Realm WWW-Authenticate响应头中的realm
+	Realm string
+}
+
+// publicRoute 解析后的免认证路由
+type publicRoute struct {
+	method string
+	path   string
+	prefix bool
+}
+
+// AuthMiddleware Bearer令牌认证中间件，校验通过后将调用方和声明写入上下文
+func AuthMiddleware(config AuthConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+	if config.Realm == "" {
+		config.Realm = "api"
+	}
+	if config.Policy == nil {
+		config.Policy = auth.NewPolicy(nil)
+	}
+	public := parsePublicRoutes(config.PublicRoutes)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+			if isPublicRoute(c, public) {
+				c.Set(ContextKeyPublicRoute, true)
+				return next(c)
+			}
+
+			verifier, scheme := config.Verifier, "Bearer"
+			token, ok := bearerToken(c.Request().Header.Get(echo.HeaderAuthorization))
+			if key := apiKeyFromRequest(c.Request()); key != "" && config.APIKeyVerifier != nil {
+				verifier, scheme, token, ok = config.APIKeyVerifier, "ApiKey", key, true
+			}
+			if !ok {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="`+config.Realm+`"`)
+				if config.APIKeyVerifier != nil {
+					c.Response().Header().Add(echo.HeaderWWWAuthenticate, `ApiKey realm="`+config.Realm+`"`)
+					return utils.Unauthorized(c, "Missing bearer token or API key")
+				}
+				return utils.Unauthorized(c, "Missing bearer token")
+			}
+
+			principal, err := verifier.Verify(c.Request().Context(), token)
+			if err != nil {
+				if !errors.Is(err, auth.ErrInvalidToken) {
+					c.Logger().Errorf("credential verification failed: %v", err)
+				}
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, scheme+` realm="`+config.Realm+`", error="invalid_token"`)
+				if scheme == "ApiKey" {
+					return utils.Unauthorized(c, "Invalid, expired or revoked API key")
+				}
+				return utils.Unauthorized(c, "Invalid or expired token")
+			}
+			if config.Users != nil {
+				_, err := config.Users.GetByID(c.Request().Context(), principal.Subject)
+				if errors.Is(err, repository.ErrNotFound) {
+					c.Response().Header().Set(echo.HeaderWWWAuthenticate, scheme+` realm="`+config.Realm+`", error="invalid_token"`)
+					return utils.Unauthorized(c, "User has been deleted")
+				}
+				if err != nil {
+					c.Logger().Errorf("load user %s: %v", principal.Subject, err)
+					return utils.InternalServerError(c, "Internal server error")
+				}
+			}
+
+			principal.Permissions = config.Policy.Permissions(principal)
+			SetPrincipal(c, principal)
+			return next(c)
+		}
+	}
+}
+
+// SetPrincipal 将通过认证的调用方写入上下文
+func SetPrincipal(c echo.Context, principal *auth.Principal) {
+	c.Set(ContextKeyPrincipal, principal)
+	c.Set(ContextKeyUserID, principal.Subject)
+	if principal.Claims != nil {
+		c.Set(ContextKeyClaims, principal.Claims)
+	}
+}
+
+// GetPrincipal 返回当前请求的调用方，未认证时返回nil
+func GetPrincipal(c echo.Context) *auth.Principal {
+	principal, _ := c.Get(ContextKeyPrincipal).(*auth.Principal)
+	return principal
+}
+
+// GetClaims 返回当前请求的JWT声明，未使用JWT认证时返回nil
+func GetClaims(c echo.Context) *auth.Claims {
+	claims, _ := c.Get(ContextKeyClaims).(*auth.Claims)
+	return claims
+}
+
+// bearerToken 从Authorization头中提取Bearer令牌
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// apiKeyFromRequest 从X-API-Key或Authorization: ApiKey中提取API Key
+func apiKeyFromRequest(req *http.Request) string {
+	if key := strings.TrimSpace(req.Header.Get("X-API-Key")); key != "" {
+		return key
+	}
+	scheme, value, ok := strings.Cut(req.Header.Get(echo.HeaderAuthorization), " ")
+	if ok && strings.EqualFold(scheme, "ApiKey") {
+		return strings.TrimSpace(value)
+	}
+	return ""
+}
+
+// parsePublicRoutes 解析免认证路由列表
+func parsePublicRoutes(entries []string) []publicRoute {
+	routes := make([]publicRoute, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		var r publicRoute
+		if method, path, ok := strings.Cut(entry, " "); ok {
+			r.method = strings.ToUpper(method)
+			entry = strings.TrimSpace(path)
+		}
+		r.path, r.prefix = strings.CutSuffix(entry, "*")
+		routes = append(routes, r)
+	}
+	return routes
+}
+
+// isPublicRoute 判断请求是否命中免认证路由，精确匹配同时接受路由模式和实际路径
+func isPublicRoute(c echo.Context, routes []publicRoute) bool {
+	req := c.Request()
+	for _, r := range routes {
+		if r.method != "" && r.method != req.Method {
+			continue
+		}
+		if r.prefix {
+			if strings.HasPrefix(req.URL.Path, r.path) {
+				return true
+			}
+			continue
+		}
+		if c.Path() == r.path || req.URL.Path == r.path {
+			return true
+		}
+	}
+	return false
+}
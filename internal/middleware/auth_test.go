@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go-echo-app/internal/auth"
+	"go-echo-app/internal/models"
+	"go-echo-app/internal/repository"
+)
+
+// subjectVerifier 把令牌本身当作用户ID，用于测试
+type subjectVerifier struct{}
+
+func (subjectVerifier) Verify(_ context.Context, token string) (*auth.Principal, error) {
+	return &auth.Principal{Subject: token, Roles: []string{"user"}}, nil
+}
+
+func TestAuthMiddlewareRejectsDeletedUser(t *testing.T) {
+	ctx := context.Background()
+	users := repository.NewMemoryUserRepository()
+	now := time.Now().UTC()
+	for _, user := range []*models.User{
+		{ID: "active", Name: "Ann", Email: "ann@example.com", CreatedAt: now, UpdatedAt: now},
+		{ID: "deleted", Name: "Bob", Email: "bob@example.com", CreatedAt: now, UpdatedAt: now, DeletedAt: &now},
+	} {
+		if err := users.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e := echo.New()
+	e.Use(AuthMiddleware(AuthConfig{Verifier: subjectVerifier{}, Users: users}))
+	e.GET("/me", func(c echo.Context) error { return c.String(http.StatusOK, GetPrincipal(c).Subject) })
+
+	tests := []struct {
+		token  string
+		status int
+	}{
+		{token: "active", status: http.StatusOK},
+		// 删除前签发的令牌在过期前仍能通过签名校验，必须按用户状态拒绝
+		{token: "deleted", status: http.StatusUnauthorized},
+		{token: "missing", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+}
@@ -33,30 +33,3 @@ func CustomMiddleware() []echo.MiddlewareFunc {
 		}),
 	}
 }
-
-// AuthMiddleware 认证中间件
-func AuthMiddleware() echo.MiddlewareFunc {
-	return func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c echo.Context) error {
-			// 这里可以添加JWT验证逻辑
-			// token := c.Request().Header.Get("Authorization")
-			// if token == "" {
-			//     return c.JSON(401, map[string]string{"error": "Unauthorized"})
-			// }
-			
-			return next(c)
-		}
-	}
-}
-
-// RateLimitMiddleware 限流中间件
-func RateLimitMiddleware() echo.MiddlewareFunc {
-	return func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c echo.Context) error {
-			// 这里可以添加限流逻辑
-			// 例如使用令牌桶算法
-			
-			return next(c)
-		}
-	}
-}
\ No newline at end of file
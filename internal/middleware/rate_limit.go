@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"go-echo-app/internal/auth"
+	"go-echo-app/pkg/utils"
+)
+
+// KeyFunc 从请求中提取限流键，返回空字符串表示无法识别
+type KeyFunc func(c echo.Context) string
+
+// RouteLimit 路由级限流规则
+type RouteLimit struct {
+	Method string
+	Path   string // echo路由模式，如 /api/v1/users/:id
+	Rule   RateLimitRule
+}
+
+// RateLimitConfig 限流中间件配置
+type RateLimitConfig struct {
+	Skipper middleware.Skipper
+	Store   RateLimitStore
+	// KeyFunc 提取限流键，默认按客户端IP
+	KeyFunc KeyFunc
+	// DefaultRule 未匹配路由规则时使用的规则
+	DefaultRule RateLimitRule
+	// PerRoute 为true时默认规则按路由分别计数
+	PerRoute bool
+	// Routes 路由级规则，始终按路由分别计数
+	Routes []RouteLimit
+	// FailOpen 存储不可用时放行请求，否则返回503
+	FailOpen bool
+}
+
+// RateLimitMiddleware 令牌桶限流中间件
+func RateLimitMiddleware(config RateLimitConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = KeyByIP
+	}
+
+	routes := make(map[string]RateLimitRule, len(config.Routes))
+	for _, r := range config.Routes {
+		routes[r.Method+" "+r.Path] = r.Rule
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			route := c.Request().Method + " " + c.Path()
+			rule, ok := routes[route]
+			scope := "default"
+			if ok || config.PerRoute {
+				scope = route
+			}
+			if !ok {
+				rule = config.DefaultRule
+			}
+			if rule.RequestsPerSecond <= 0 || rule.Burst <= 0 {
+				return next(c)
+			}
+
+			key := config.KeyFunc(c)
+			if key == "" {
+				key = "ip:" + c.RealIP()
+			}
+
+			result, err := config.Store.Allow(c.Request().Context(), scope+"|"+key, rule)
+			if err != nil {
+				c.Logger().Errorf("rate limit store unavailable: %v", err)
+				if config.FailOpen {
+					return next(c)
+				}
+				return utils.ErrorResponse(c, http.StatusServiceUnavailable, "Rate limiter unavailable", "Service Unavailable")
+			}
+
+			setRateLimitHeaders(c, result)
+			if !result.Allowed {
+				c.Response().Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				return utils.TooManyRequests(c, "Rate limit exceeded")
+			}
+			return next(c)
+		}
+	}
+}
+
+// preAuthFactor 未配置认证前规则时，认证前规则相对默认规则放宽的倍数
+const preAuthFactor = 5
+
+// StagedRateLimitMiddleware 返回认证前后两步的限流中间件。
+// preAuth在认证之前按IP执行宽松的preAuthRule，只用于限制携带无效令牌或API Key的暴力尝试，
+// 同一出口IP后的多个用户不会因此共享正常额度；preAuthRule为零时使用默认规则的5倍。
+// postAuth在认证之后按config.KeyFunc执行默认规则和路由规则，没有调用方的请求回退为按IP计数
+func StagedRateLimitMiddleware(config RateLimitConfig, preAuthRule RateLimitRule) (preAuth, postAuth echo.MiddlewareFunc) {
+	if preAuthRule.RequestsPerSecond <= 0 || preAuthRule.Burst <= 0 {
+		preAuthRule = RateLimitRule{
+			RequestsPerSecond: config.DefaultRule.RequestsPerSecond * preAuthFactor,
+			Burst:             config.DefaultRule.Burst * preAuthFactor,
+		}
+	}
+
+	preAuth = RateLimitMiddleware(RateLimitConfig{
+		Skipper: config.Skipper,
+		Store:   config.Store,
+		// 与认证后按IP的计数使用不同的键
+		KeyFunc:     func(c echo.Context) string { return "pre_auth:" + KeyByIP(c) },
+		DefaultRule: preAuthRule,
+		FailOpen:    config.FailOpen,
+	})
+	return preAuth, RateLimitMiddleware(config)
+}
+
+// KeyByIP 按客户端IP限流，是否信任X-Forwarded-For由Echo的IPExtractor决定
+func KeyByIP(c echo.Context) string {
+	return "ip:" + c.RealIP()
+}
+
+// KeyByAPIKey 按通过认证的API Key限流。请求中未经校验的Key可以随意更换，不能作为限流键
+func KeyByAPIKey(c echo.Context) string {
+	if principal := GetPrincipal(c); principal != nil && principal.Method == auth.MethodAPIKey && principal.KeyID != "" {
+		return "api_key:" + principal.KeyID
+	}
+	return ""
+}
+
+// KeyByUser 按认证用户ID限流
+func KeyByUser(c echo.Context) string {
+	if id, ok := c.Get(ContextKeyUserID).(string); ok && id != "" {
+		return "user:" + id
+	}
+	return ""
+}
+
+// KeyByRoute 按路由限流，所有客户端共享同一个计数
+func KeyByRoute(c echo.Context) string {
+	return "route:" + c.Request().Method + " " + c.Path()
+}
+
+// KeyChain 依次尝试多个KeyFunc，使用第一个非空结果
+func KeyChain(funcs ...KeyFunc) KeyFunc {
+	return func(c echo.Context) string {
+		for _, f := range funcs {
+			if key := f(c); key != "" {
+				return key
+			}
+		}
+		return ""
+	}
+}
+
+// KeyNeedsPrincipal 判断限流键是否要在认证之后才能确定（user、api_key）
+func KeyNeedsPrincipal(name string) bool {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "user", "api_key":
+		return true
+	}
+	return false
+}
+
+// KeyFuncByNames 按名称（user、api_key、ip、route）组合KeyFunc
+func KeyFuncByNames(names []string) (KeyFunc, error) {
+	funcs := make([]KeyFunc, 0, len(names))
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "ip":
+			funcs = append(funcs, KeyByIP)
+		case "api_key":
+			funcs = append(funcs, KeyByAPIKey)
+		case "user":
+			funcs = append(funcs, KeyByUser)
+		case "route":
+			funcs = append(funcs, KeyByRoute)
+		default:
+			return nil, fmt.Errorf("unknown rate limit key %q", name)
+		}
+	}
+	return KeyChain(funcs...), nil
+}
+
+// setRateLimitHeaders 写入RateLimit-*响应头
+func setRateLimitHeaders(c echo.Context, result RateLimitResult) {
+	header := c.Response().Header()
+	header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+}
+
+// ceilSeconds 将时间间隔向上取整为秒
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
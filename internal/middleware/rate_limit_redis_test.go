@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedisStore 在进程内的miniredis上创建限流存储，时间固定以便断言等待时间
+func newTestRedisStore(t *testing.T, prefix string) (*RedisRateLimitStore, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	server.SetTime(time.Unix(1700000000, 0))
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisRateLimitStore(client, prefix), server
+}
+
+func TestRedisRateLimitStoreAllow(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestRedisStore(t, "test:")
+	rule := RateLimitRule{RequestsPerSecond: 2, Burst: 3}
+
+	tests := []struct {
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+		resetAfter time.Duration
+	}{
+		{allowed: true, remaining: 2, resetAfter: 500 * time.Millisecond},
+		{allowed: true, remaining: 1, resetAfter: time.Second},
+		{allowed: true, remaining: 0, resetAfter: 1500 * time.Millisecond},
+		// 时间不前进时桶已空，下一个令牌在一个发放间隔后可用
+		{allowed: false, remaining: 0, retryAfter: 500 * time.Millisecond, resetAfter: 1500 * time.Millisecond},
+	}
+	for i, tt := range tests {
+		got, err := store.Allow(ctx, "ip:1.2.3.4", rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := RateLimitResult{Allowed: tt.allowed, Limit: 3, Remaining: tt.remaining, RetryAfter: tt.retryAfter, ResetAfter: tt.resetAfter}
+		if got != want {
+			t.Fatalf("request %d: Allow() = %+v, want %+v", i+1, got, want)
+		}
+	}
+
+	// 其他键不受影响
+	if got, err := store.Allow(ctx, "ip:5.6.7.8", rule); err != nil || !got.Allowed || got.Remaining != 2 {
+		t.Fatalf("Allow() for another key = %+v, %v", got, err)
+	}
+}
+
+func TestRedisRateLimitStoreRefill(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestRedisStore(t, "test:")
+	rule := RateLimitRule{RequestsPerSecond: 2, Burst: 1}
+
+	if got, err := store.Allow(ctx, "k", rule); err != nil || !got.Allowed {
+		t.Fatalf("first Allow() = %+v, %v", got, err)
+	}
+	if got, err := store.Allow(ctx, "k", rule); err != nil || got.Allowed {
+		t.Fatalf("second Allow() = %+v, %v; want denied", got, err)
+	}
+
+	server.SetTime(time.Unix(1700000000, 0).Add(500 * time.Millisecond))
+	if got, err := store.Allow(ctx, "k", rule); err != nil || !got.Allowed {
+		t.Fatalf("Allow() after emission interval = %+v, %v; want allowed", got, err)
+	}
+}
+
+func TestRedisRateLimitStoreKeyPrefix(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestRedisStore(t, "app:ratelimit:")
+	rule := RateLimitRule{RequestsPerSecond: 1, Burst: 1}
+
+	if _, err := store.Allow(ctx, "default|ip:1.2.3.4", rule); err != nil {
+		t.Fatal(err)
+	}
+	keys := server.Keys()
+	if len(keys) != 1 || keys[0] != "app:ratelimit:default|ip:1.2.3.4" {
+		t.Fatalf("redis keys = %v, want [app:ratelimit:default|ip:1.2.3.4]", keys)
+	}
+	// 键在桶回满后过期，空闲的客户端不会一直占用内存
+	if ttl := server.TTL(keys[0]); ttl <= 0 || ttl > 2*time.Second {
+		t.Fatalf("TTL = %v, want about the reset time", ttl)
+	}
+
+	// 不同前缀的存储互不影响
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	other := NewRedisRateLimitStore(client, "other:")
+	if got, err := other.Allow(ctx, "default|ip:1.2.3.4", rule); err != nil || !got.Allowed {
+		t.Fatalf("Allow() with another prefix = %+v, %v; want allowed", got, err)
+	}
+}
+
+func TestRateLimitMiddlewareRedisUnavailable(t *testing.T) {
+	// 取一个已关闭的端口作为不可达的Redis地址
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: addr, MaxRetries: -1, DialTimeout: time.Second})
+	t.Cleanup(func() { client.Close() })
+	store := NewRedisRateLimitStore(client, "test:")
+
+	tests := []struct {
+		name     string
+		failOpen bool
+		status   int
+	}{
+		{name: "fail open", failOpen: true, status: http.StatusOK},
+		{name: "fail closed", failOpen: false, status: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(RateLimitMiddleware(RateLimitConfig{
+				Store:       store,
+				DefaultRule: RateLimitRule{RequestsPerSecond: 1, Burst: 1},
+				FailOpen:    tt.failOpen,
+			}))
+			e.GET("/", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if rec.Header().Get("RateLimit-Limit") != "" {
+				t.Fatal("RateLimit headers set without a store result")
+			}
+		})
+	}
+}
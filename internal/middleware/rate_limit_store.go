@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimitRule 令牌桶规则
+type RateLimitRule struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// RateLimitResult 一次限流判断的结果
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // 令牌桶回满所需时间
+	RetryAfter time.Duration // 被拒绝时距离下一个可用令牌的时间
+}
+
+// RateLimitStore 限流计数存储
+type RateLimitStore interface {
+	// Allow 为key消耗一个令牌并返回判断结果
+	Allow(ctx context.Context, key string, rule RateLimitRule) (RateLimitResult, error)
+}
+
+// bucket 单个键的令牌桶
+type bucket struct {
+	tokens float64
+	last   time.Time
+	fullAt time.Time // 令牌桶回满的时间，早于该时间清理会放宽限制
+}
+
+// MemoryRateLimitStore 基于内存的令牌桶存储，定期清理空闲的键
+type MemoryRateLimitStore struct {
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	idleTimeout time.Duration
+	stop        chan struct{}
+	once        sync.Once
+}
+
+// NewMemoryRateLimitStore 创建内存令牌桶存储，空闲超过idleTimeout的键会被清理
+func NewMemoryRateLimitStore(idleTimeout time.Duration) *MemoryRateLimitStore {
+	if idleTimeout <= 0 {
+		idleTimeout = 10 * time.Minute
+	}
+
+	s := &MemoryRateLimitStore{
+		buckets:     make(map[string]*bucket),
+		idleTimeout: idleTimeout,
+		stop:        make(chan struct{}),
+	}
+	go s.evictLoop()
+	return s
+}
+
+// Allow 实现RateLimitStore
+func (s *MemoryRateLimitStore) Allow(_ context.Context, key string, rule RateLimitRule) (RateLimitResult, error) {
+	now := time.Now()
+	burst := float64(rule.Burst)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		s.buckets[key] = b
+	}
+
+	// 按经过的时间补充令牌
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = math.Min(burst, b.tokens+elapsed*rule.RequestsPerSecond)
+	b.last = now
+
+	result := RateLimitResult{Limit: rule.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.tokens) / rule.RequestsPerSecond)
+	}
+	result.Remaining = int(math.Floor(b.tokens))
+	result.ResetAfter = secondsToDuration((burst - b.tokens) / rule.RequestsPerSecond)
+	b.fullAt = now.Add(result.ResetAfter)
+	return result, nil
+}
+
+// Close 停止后台清理
+func (s *MemoryRateLimitStore) Close() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+// evictLoop 定期清理空闲的键
+func (s *MemoryRateLimitStore) evictLoop() {
+	ticker := time.NewTicker(s.idleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.evict(time.Now())
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// evict 删除已回满且空闲超过阈值的键
+func (s *MemoryRateLimitStore) evict(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, b := range s.buckets {
+		if now.Sub(b.last) > s.idleTimeout && now.After(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// secondsToDuration 将秒数转换为时间间隔
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
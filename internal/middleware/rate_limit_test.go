@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go-echo-app/internal/auth"
+)
+
+func TestKeyFuncByNamesPrecedence(t *testing.T) {
+	keyFunc, err := KeyFuncByNames([]string{"api_key", "user", "ip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		want      string
+	}{
+		{name: "api key first", principal: &auth.Principal{Subject: "u1", Method: auth.MethodAPIKey, KeyID: "k1"}, want: "api_key:k1"},
+		{name: "user when not an api key", principal: &auth.Principal{Subject: "u1", Method: auth.MethodJWT}, want: "user:u1"},
+		{name: "ip without principal", want: "ip:192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+			if tt.principal != nil {
+				c.Set(ContextKeyPrincipal, tt.principal)
+				c.Set(ContextKeyUserID, tt.principal.Subject)
+			}
+			if got := keyFunc(c); got != tt.want {
+				t.Fatalf("key = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := KeyFuncByNames([]string{"ip", "cookie"}); err == nil {
+		t.Fatal("KeyFuncByNames() accepted an unknown key")
+	}
+}
+
+func TestRateLimitMiddlewareRoutes(t *testing.T) {
+	store := NewMemoryRateLimitStore(0)
+	defer store.Close()
+
+	e := echo.New()
+	e.Use(RateLimitMiddleware(RateLimitConfig{
+		Store:       store,
+		DefaultRule: RateLimitRule{RequestsPerSecond: 0.001, Burst: 2},
+		Routes: []RouteLimit{
+			{Method: http.MethodPost, Path: "/proxy", Rule: RateLimitRule{RequestsPerSecond: 0.001, Burst: 1}},
+		},
+	}))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.POST("/proxy", ok)
+	e.GET("/users/:id", ok)
+	e.GET("/health", ok)
+
+	tests := []struct {
+		method, path string
+		status       int
+		limit        string
+	}{
+		// 路由规则单独计数，不消耗默认规则的额度
+		{method: http.MethodPost, path: "/proxy", status: http.StatusOK, limit: "1"},
+		{method: http.MethodPost, path: "/proxy", status: http.StatusTooManyRequests, limit: "1"},
+		// 未匹配路由规则的接口共享默认规则的计数
+		{method: http.MethodGet, path: "/users/1", status: http.StatusOK, limit: "2"},
+		{method: http.MethodGet, path: "/users/2", status: http.StatusOK, limit: "2"},
+		{method: http.MethodGet, path: "/health", status: http.StatusTooManyRequests, limit: "2"},
+	}
+	for i, tt := range tests {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.status || rec.Header().Get("RateLimit-Limit") != tt.limit {
+			t.Fatalf("request %d %s %s: status = %d, limit = %q; want %d, %q",
+				i+1, tt.method, tt.path, rec.Code, rec.Header().Get("RateLimit-Limit"), tt.status, tt.limit)
+		}
+		if tt.status == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+			t.Fatalf("request %d: missing Retry-After", i+1)
+		}
+	}
+}
+
+func TestRateLimitMiddlewarePerRoute(t *testing.T) {
+	store := NewMemoryRateLimitStore(0)
+	defer store.Close()
+
+	e := echo.New()
+	e.Use(RateLimitMiddleware(RateLimitConfig{
+		Store:       store,
+		DefaultRule: RateLimitRule{RequestsPerSecond: 0.001, Burst: 1},
+		PerRoute:    true,
+	}))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.GET("/a", ok)
+	e.GET("/b", ok)
+
+	for i, tt := range []struct {
+		path   string
+		status int
+	}{
+		{path: "/a", status: http.StatusOK},
+		{path: "/b", status: http.StatusOK},
+		{path: "/a", status: http.StatusTooManyRequests},
+	} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.status {
+			t.Fatalf("request %d GET %s: status = %d, want %d", i+1, tt.path, rec.Code, tt.status)
+		}
+	}
+}
+
+func TestStagedRateLimitMiddleware(t *testing.T) {
+	store := NewMemoryRateLimitStore(0)
+	defer store.Close()
+
+	keyFunc, err := KeyFuncByNames([]string{"user", "ip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	preAuth, postAuth := StagedRateLimitMiddleware(RateLimitConfig{
+		Store:       store,
+		KeyFunc:     keyFunc,
+		DefaultRule: RateLimitRule{RequestsPerSecond: 0.001, Burst: 1},
+	}, RateLimitRule{RequestsPerSecond: 0.001, Burst: 4})
+
+	e := echo.New()
+	e.Use(preAuth, AuthMiddleware(AuthConfig{Verifier: subjectVerifier{}, PublicRoutes: []string{"/login"}}), postAuth)
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.GET("/me", ok)
+	e.GET("/login", ok)
+
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		status int
+	}{
+		// 同一IP后的不同用户各自使用默认规则的额度
+		{name: "ann", path: "/me", token: "ann", status: http.StatusOK},
+		{name: "bob", path: "/me", token: "bob", status: http.StatusOK},
+		{name: "ann again", path: "/me", token: "ann", status: http.StatusTooManyRequests},
+		// 未认证的请求按IP执行默认规则
+		{name: "anonymous", path: "/login", status: http.StatusOK},
+		// 认证前规则的额度（4）已用完，无论令牌是否有效都在认证之前被拒绝
+		{name: "over pre-auth limit", path: "/me", token: "carol", status: http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		if tt.token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Fatalf("%s: status = %d, want %d: %s", tt.name, rec.Code, tt.status, rec.Body.String())
+		}
+	}
+}
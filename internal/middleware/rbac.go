@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"go-echo-app/pkg/utils"
+)
+
+// RequirePermission 要求调用方拥有指定权限，需在AuthMiddleware之后使用；免认证路由不做检查
+func RequirePermission(permission string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if public, _ := c.Get(ContextKeyPublicRoute).(bool); public {
+				return next(c)
+			}
+
+			principal := GetPrincipal(c)
+			if principal == nil {
+				return utils.Unauthorized(c, "Authentication required")
+			}
+			if !principal.HasPermission(permission) {
+				return utils.Forbidden(c, "Missing permission "+permission)
+			}
+			return next(c)
+		}
+	}
+}
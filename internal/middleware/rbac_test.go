@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go-echo-app/internal/auth"
+)
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		public    bool
+		status    int
+	}{
+		{name: "no principal", status: http.StatusUnauthorized},
+		{name: "public route skips the check", public: true, status: http.StatusOK},
+		{name: "missing permission", principal: &auth.Principal{Subject: "u1", Permissions: []string{auth.PermissionUsersRead}}, status: http.StatusForbidden},
+		{name: "exact permission", principal: &auth.Principal{Subject: "u1", Permissions: []string{auth.PermissionUsersWrite}}, status: http.StatusOK},
+		{name: "wildcard permission", principal: &auth.Principal{Subject: "u1", Permissions: []string{"users:*"}}, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					if tt.principal != nil {
+						SetPrincipal(c, tt.principal)
+					}
+					if tt.public {
+						c.Set(ContextKeyPublicRoute, true)
+					}
+					return next(c)
+				}
+			})
+			e.POST("/users", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}, RequirePermission(auth.PermissionUsersWrite))
+
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
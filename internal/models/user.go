@@ -1,39 +1,53 @@
 package models
 
 import (
+	"context"
 	"time"
 )
 
 // User 用户模型
 type User struct {
-	ID        string    `json:"id" db:"id"`
-	Name      string    `json:"name" db:"name"`
-	Email     string    `json:"email" db:"email"`
-	Password  string    `json:"-" db:"password"` // 密码不返回给前端
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID        string     `json:"id" db:"id"`
+	Name      string     `json:"name" db:"name"`
+	Email     string     `json:"email" db:"email"`
+	Phone     string     `json:"phone" db:"phone"`
+	Password  string     `json:"-" db:"password"` // 密码不返回给前端
+	Roles     []string   `json:"roles" db:"roles"`
+	Version   int64      `json:"version" db:"version"` // 每次更新加一，用于乐观锁
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"` // 软删除时间，为nil表示未删除
+	// IdentityIssuer和IdentitySubject 关联的外部OIDC身份（iss、sub），未关联时为空
+	IdentityIssuer  string `json:"-" db:"identity_issuer"`
+	IdentitySubject string `json:"-" db:"identity_subject"`
 }
 
 // CreateUserRequest 创建用户请求
 type CreateUserRequest struct {
 	Name     string `json:"name" validate:"required"`
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
+	Phone    string `json:"phone" validate:"omitempty,phone"`
+	Password string `json:"password" validate:"required,password"`
 }
 
 // UpdateUserRequest 更新用户请求
 type UpdateUserRequest struct {
 	Name  string `json:"name" validate:"required"`
 	Email string `json:"email" validate:"required,email"`
+	Phone string `json:"phone" validate:"omitempty,phone"`
 }
 
 // UserResponse 用户响应
 type UserResponse struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Email     string     `json:"email"`
+	Phone     string     `json:"phone,omitempty"`
+	Roles     []string   `json:"roles"`
+	Version   int64      `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 // ToResponse 转换为响应格式
@@ -42,26 +56,43 @@ func (u *User) ToResponse() UserResponse {
 		ID:        u.ID,
 		Name:      u.Name,
 		Email:     u.Email,
+		Phone:     u.Phone,
+		Roles:     u.Roles,
+		Version:   u.Version,
 		CreatedAt: u.CreatedAt,
 		UpdatedAt: u.UpdatedAt,
+		DeletedAt: u.DeletedAt,
 	}
 }
 
-// UserRepository 用户仓库接口
+// UserRepository 用户仓库接口。除GetByIDIncludeDeleted、GetByIdentity和设置了IncludeDeleted的List外，查询不返回已软删除的用户。
+// 邮箱只在未删除的用户中唯一
 type UserRepository interface {
-	Create(user *User) error
-	GetByID(id string) (*User, error)
-	GetByEmail(email string) (*User, error)
-	GetAll() ([]*User, error)
-	Update(user *User) error
-	Delete(id string) error
+	Create(ctx context.Context, user *User) error
+	GetByID(ctx context.Context, id string) (*User, error)
+	GetByIDIncludeDeleted(ctx context.Context, id string) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	// GetByIdentity 按关联的外部身份查找用户，包括已软删除的用户，外部身份不会因删除而重新关联到新用户
+	GetByIdentity(ctx context.Context, issuer, subject string) (*User, error)
+	GetAll(ctx context.Context) ([]*User, error)
+	List(ctx context.Context, query *UserQuery) (*UserList, error)
+	// Search 返回检索文本包含全部词的用户，在仓库中按相关度排序后取前limit个
+	Search(ctx context.Context, terms []string, limit int) ([]*User, error)
+	// Update 只在仓库中的版本仍为user.Version时更新（包括DeletedAt），成功后user.Version加一
+	Update(ctx context.Context, user *User) error
+	// Purge 永久删除软删除时间早于before的用户，返回删除的数量
+	Purge(ctx context.Context, before time.Time) (int, error)
 }
 
 // UserService 用户服务接口
 type UserService interface {
-	CreateUser(req *CreateUserRequest) (*UserResponse, error)
-	GetUser(id string) (*UserResponse, error)
-	GetAllUsers() ([]*UserResponse, error)
-	UpdateUser(id string, req *UpdateUserRequest) (*UserResponse, error)
-	DeleteUser(id string) error
+	CreateUser(ctx context.Context, req *CreateUserRequest) (*UserResponse, error)
+	GetUser(ctx context.Context, id string, includeDeleted bool) (*UserResponse, error)
+	ListUsers(ctx context.Context, req *ListUsersRequest) (*UserListResponse, error)
+	SearchUsers(ctx context.Context, req *SearchUsersRequest) (*UserSearchResponse, error)
+	// 以下方法的version为客户端要求的当前版本，为0时不检查
+	UpdateUser(ctx context.Context, id string, version int64, req *UpdateUserRequest) (*UserResponse, error)
+	PatchUser(ctx context.Context, id string, version int64, apply func(req *UpdateUserRequest) error) (*UserResponse, error)
+	DeleteUser(ctx context.Context, id string, version int64) error
+	RestoreUser(ctx context.Context, id string, version int64) (*UserResponse, error)
 }
\ No newline at end of file
@@ -0,0 +1,100 @@
+package models
+
+import "time"
+
+// UserSortFields 用户列表允许排序的字段
+var UserSortFields = []string{"created_at", "updated_at", "name", "email"}
+
+// UserCursor 键集分页中的位置：排序字段的值和用户ID
+type UserCursor struct {
+	Value string // 排序字段的值，时间字段为RFC3339Nano格式
+	ID    string
+}
+
+// UserQuery 用户列表查询条件
+type UserQuery struct {
+	Name           string    // 姓名子串，忽略大小写
+	Email          string    // 邮箱子串，忽略大小写
+	CreatedAfter   time.Time // 创建时间下限（含），零值表示不限
+	CreatedBefore  time.Time // 创建时间上限（不含），零值表示不限
+	SortBy         string    // UserSortFields之一，相同时按ID排序
+	Desc           bool
+	Limit          int
+	Offset         int         // 偏移分页，设置了After或Before时忽略
+	After          *UserCursor // 只返回排在该位置之后的用户
+	Before         *UserCursor // 只返回排在该位置之前、紧邻该位置的用户
+	IncludeDeleted bool        // 同时返回已软删除的用户
+}
+
+// UserList 用户列表查询结果，Users按查询的排序方向排列
+type UserList struct {
+	Users   []*User
+	Total   int  // 满足过滤条件的用户总数，不受分页影响
+	HasMore bool // 按分页方向（Before时为向前）还有更多用户
+}
+
+// ListUsersRequest 用户列表请求
+type ListUsersRequest struct {
+	Limit         int       `query:"limit" validate:"omitempty,min=1,max=100"`
+	Page          int       `query:"page" validate:"omitempty,min=1,max=10000"` // 偏移分页不宜过深，更靠后的数据应使用游标分页
+	Cursor        string    `query:"cursor" validate:"omitempty,excluded_with=Page"`
+	Name          string    `query:"name" validate:"omitempty,max=255"`
+	Email         string    `query:"email" validate:"omitempty,max=255"`
+	CreatedAfter  time.Time `query:"created_after"`
+	CreatedBefore time.Time `query:"created_before"`
+	Sort          string    `query:"sort" validate:"omitempty,oneof=created_at -created_at updated_at -updated_at name -name email -email"`
+	// IncludeDeleted 同时返回已软删除的用户，需要users:admin权限
+	IncludeDeleted bool `query:"include_deleted"`
+}
+
+// UserListResponse 用户列表响应。指定page时为偏移分页，否则为游标分页
+type UserListResponse struct {
+	Users      []UserResponse `json:"users"`
+	Count      int            `json:"count"`
+	Total      int            `json:"total"`
+	Limit      int            `json:"limit"`
+	Page       int            `json:"page,omitempty"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	PrevCursor string         `json:"prev_cursor,omitempty"`
+	Links      PageLinks      `json:"links"`
+}
+
+// PageLinks 相邻页的链接
+type PageLinks struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// SortValue 返回用户在排序字段上的值，用于生成游标
+func (u *User) SortValue(field string) string {
+	switch field {
+	case "updated_at":
+		return u.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	case "name":
+		return u.Name
+	case "email":
+		return u.Email
+	default:
+		return u.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+}
+
+// SearchUsersRequest 用户搜索请求
+type SearchUsersRequest struct {
+	Q     string `query:"q" validate:"required,max=200"`
+	Limit int    `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+// UserSearchResult 一条搜索结果，Highlights中以<mark>标记匹配部分，其余文本已做HTML转义
+type UserSearchResult struct {
+	User       UserResponse      `json:"user"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// UserSearchResponse 用户搜索响应，按相关度从高到低排列
+type UserSearchResponse struct {
+	Query   string             `json:"query"`
+	Results []UserSearchResult `json:"results"`
+	Count   int                `json:"count"`
+}
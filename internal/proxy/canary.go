@@ -0,0 +1,240 @@
+package proxy
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 灰度分流默认值
+const (
+	DefaultOverrideHeader = "X-Canary-Variant"
+	defaultCookieMaxAge   = 24 * 60 * 60
+)
+
+// VariantMetrics 单个版本的流量指标
+type VariantMetrics struct {
+	Requests     int64            `json:"requests"`
+	Errors       int64            `json:"errors"`
+	StatusCodes  map[string]int64 `json:"status_codes"`
+	TotalLatency time.Duration    `json:"-"`
+	AvgLatencyMs float64          `json:"avg_latency_ms"`
+}
+
+// VariantStatus 版本的权重和指标
+type VariantStatus struct {
+	Name    string         `json:"name"`
+	URL     string         `json:"url"`
+	Weight  int            `json:"weight"`
+	Metrics VariantMetrics `json:"metrics"`
+}
+
+// Assignment 一次分流结果
+type Assignment struct {
+	Variant *Variant
+	Cookie  *http.Cookie // 需要写回客户端的粘性Cookie，可能为nil
+}
+
+// Canary 灰度分流器，维护各上游版本的权重与指标
+type Canary struct {
+	mu      sync.RWMutex
+	weights map[string]map[string]int
+	metrics map[string]map[string]*VariantMetrics
+}
+
+// NewCanary 根据注册表中的上游创建灰度分流器
+func NewCanary(registry *Registry) *Canary {
+	c := &Canary{
+		weights: make(map[string]map[string]int),
+		metrics: make(map[string]map[string]*VariantMetrics),
+	}
+
+	for _, upstream := range registry.All() {
+		if len(upstream.Variants) == 0 {
+			continue
+		}
+		weights := make(map[string]int)
+		metrics := make(map[string]*VariantMetrics)
+		for _, v := range upstream.Variants {
+			weights[v.Name] = v.Weight
+			metrics[v.Name] = &VariantMetrics{StatusCodes: make(map[string]int64)}
+		}
+		c.weights[upstream.Name] = weights
+		c.metrics[upstream.Name] = metrics
+	}
+	return c
+}
+
+// Assign 为请求选择版本，上游未配置灰度时返回nil。
+// 优先级：强制请求头 > 粘性请求头哈希 > 粘性Cookie > 按权重随机。
+func (c *Canary) Assign(upstream *Upstream, req *http.Request) *Assignment {
+	if upstream == nil || len(upstream.Variants) == 0 {
+		return nil
+	}
+	cfg := upstream.Config.Canary
+
+	c.mu.RLock()
+	weights := c.weights[upstream.Name]
+	c.mu.RUnlock()
+
+	overrideHeader := cfg.OverrideHeader
+	if overrideHeader == "" {
+		overrideHeader = DefaultOverrideHeader
+	}
+	if v := findVariant(upstream.Variants, req.Header.Get(overrideHeader)); v != nil {
+		return &Assignment{Variant: v}
+	}
+
+	if cfg.StickyHeader != "" {
+		if key := req.Header.Get(cfg.StickyHeader); key != "" {
+			h := fnv.New32a()
+			h.Write([]byte(upstream.Name + "\x00" + key))
+			return &Assignment{Variant: pickWeighted(upstream.Variants, weights, int(h.Sum32()&0x7fffffff))}
+		}
+	}
+
+	if cfg.StickyCookie != "" {
+		if cookie, err := req.Cookie(cfg.StickyCookie); err == nil {
+			// 版本权重降为0（如回滚）时重新分配
+			if v := findVariant(upstream.Variants, cookie.Value); v != nil && weights[v.Name] > 0 {
+				return &Assignment{Variant: v}
+			}
+		}
+	}
+
+	assignment := &Assignment{Variant: pickWeighted(upstream.Variants, weights, rand.Int())}
+	if cfg.StickyCookie != "" {
+		maxAge := cfg.CookieMaxAge
+		if maxAge <= 0 {
+			maxAge = defaultCookieMaxAge
+		}
+		assignment.Cookie = &http.Cookie{
+			Name:     cfg.StickyCookie,
+			Value:    assignment.Variant.Name,
+			Path:     "/",
+			MaxAge:   maxAge,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		}
+	}
+	return assignment
+}
+
+// SetWeights 在运行时调整上游各版本权重，未列出的版本保持不变
+func (c *Canary) SetWeights(upstream *Upstream, weights map[string]int) error {
+	if upstream == nil || len(upstream.Variants) == 0 {
+		return fmt.Errorf("upstream has no canary variants")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	updated := make(map[string]int)
+	for name, w := range c.weights[upstream.Name] {
+		updated[name] = w
+	}
+	for name, w := range weights {
+		if _, ok := updated[name]; !ok {
+			return fmt.Errorf("unknown variant %q", name)
+		}
+		if w < 0 {
+			return fmt.Errorf("variant %q has negative weight", name)
+		}
+		updated[name] = w
+	}
+
+	total := 0
+	for _, w := range updated {
+		total += w
+	}
+	if total == 0 {
+		return fmt.Errorf("variants must have a positive total weight")
+	}
+
+	c.weights[upstream.Name] = updated
+	return nil
+}
+
+// Record 记录版本的一次请求结果，status为0表示转发失败
+func (c *Canary) Record(upstream *Upstream, variant *Variant, status int, latency time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	m, ok := c.metrics[upstream.Name][variant.Name]
+	if !ok {
+		return
+	}
+	m.Requests++
+	m.TotalLatency += latency
+	if status == 0 || status >= 500 {
+		m.Errors++
+	}
+	if status == 0 {
+		m.StatusCodes["error"]++
+	} else {
+		m.StatusCodes[fmt.Sprintf("%dxx", status/100)]++
+	}
+}
+
+// Status 返回上游各版本的权重和指标
+func (c *Canary) Status(upstream *Upstream) []VariantStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	statuses := make([]VariantStatus, 0, len(upstream.Variants))
+	for _, v := range upstream.Variants {
+		m := c.metrics[upstream.Name][v.Name]
+		metrics := VariantMetrics{
+			Requests:    m.Requests,
+			Errors:      m.Errors,
+			StatusCodes: make(map[string]int64, len(m.StatusCodes)),
+		}
+		for code, n := range m.StatusCodes {
+			metrics.StatusCodes[code] = n
+		}
+		if m.Requests > 0 {
+			metrics.AvgLatencyMs = float64(m.TotalLatency.Microseconds()) / float64(m.Requests) / 1000
+		}
+
+		statuses = append(statuses, VariantStatus{
+			Name:    v.Name,
+			URL:     v.URL.String(),
+			Weight:  c.weights[upstream.Name][v.Name],
+			Metrics: metrics,
+		})
+	}
+	return statuses
+}
+
+// findVariant 按名称查找版本
+func findVariant(variants []*Variant, name string) *Variant {
+	if name == "" {
+		return nil
+	}
+	for _, v := range variants {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// pickWeighted 按权重从版本中选择，n为非负随机数或哈希值
+func pickWeighted(variants []*Variant, weights map[string]int, n int) *Variant {
+	total := 0
+	for _, v := range variants {
+		total += weights[v.Name]
+	}
+
+	point := n % total
+	for _, v := range variants {
+		point -= weights[v.Name]
+		if point < 0 {
+			return v
+		}
+	}
+	return variants[len(variants)-1]
+}
@@ -0,0 +1,219 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"go-echo-app/internal/config"
+)
+
+// newCanaryUpstream 创建带stable/beta两个版本的上游
+func newCanaryUpstream(t *testing.T, stable, beta int, canary config.CanaryConfig) (*Upstream, *Canary) {
+	t.Helper()
+	canary.Variants = []config.VariantConfig{
+		{Name: "stable", URL: "http://stable.example.com", Weight: stable},
+		{Name: "beta", URL: "http://beta.example.com", Weight: beta},
+	}
+	r, err := NewRegistry([]config.UpstreamConfig{{Name: "orders", URL: "http://orders.example.com", Canary: &canary}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r.Get("orders"), NewCanary(r)
+}
+
+// canaryRequest 创建带可选请求头和Cookie的请求
+func canaryRequest(header map[string]string, cookies ...*http.Cookie) *http.Request {
+	req, _ := http.NewRequest(http.MethodGet, "http://orders.example.com/items", nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	return req
+}
+
+func TestCanaryAssignStickiness(t *testing.T) {
+	tests := []struct {
+		name       string
+		stable     int
+		beta       int
+		cfg        config.CanaryConfig
+		header     map[string]string
+		cookie     *http.Cookie
+		want       string // 为空时只要求多次分配结果一致
+		wantCookie bool
+	}{
+		{
+			name: "override header wins", stable: 100, beta: 0,
+			cfg:    config.CanaryConfig{StickyHeader: "X-User"},
+			header: map[string]string{"X-Canary-Variant": "beta", "X-User": "u1"},
+			want:   "beta",
+		},
+		{
+			name: "custom override header", stable: 100, beta: 0,
+			cfg:    config.CanaryConfig{OverrideHeader: "X-Version"},
+			header: map[string]string{"X-Version": "beta"},
+			want:   "beta",
+		},
+		{
+			name: "unknown override falls back to weights", stable: 100, beta: 0,
+			header: map[string]string{"X-Canary-Variant": "gamma"},
+			want:   "stable",
+		},
+		{
+			name: "sticky header hashes consistently", stable: 50, beta: 50,
+			cfg:    config.CanaryConfig{StickyHeader: "X-User", StickyCookie: "variant"},
+			header: map[string]string{"X-User": "user-42"},
+		},
+		{
+			name: "sticky cookie is honoured", stable: 1, beta: 99,
+			cfg:    config.CanaryConfig{StickyCookie: "variant"},
+			cookie: &http.Cookie{Name: "variant", Value: "stable"},
+			want:   "stable",
+		},
+		{
+			name: "cookie for a drained variant is reassigned", stable: 0, beta: 100,
+			cfg:        config.CanaryConfig{StickyCookie: "variant"},
+			cookie:     &http.Cookie{Name: "variant", Value: "stable"},
+			want:       "beta",
+			wantCookie: true,
+		},
+		{
+			name: "new client gets a sticky cookie", stable: 100, beta: 0,
+			cfg:        config.CanaryConfig{StickyCookie: "variant"},
+			want:       "stable",
+			wantCookie: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream, canary := newCanaryUpstream(t, tt.stable, tt.beta, tt.cfg)
+
+			var first string
+			for i := 0; i < 50; i++ {
+				var cookies []*http.Cookie
+				if tt.cookie != nil {
+					cookies = append(cookies, tt.cookie)
+				}
+				a := canary.Assign(upstream, canaryRequest(tt.header, cookies...))
+				if first == "" {
+					first = a.Variant.Name
+				}
+				if a.Variant.Name != first {
+					t.Fatalf("assignment changed from %s to %s", first, a.Variant.Name)
+				}
+				if tt.want != "" && a.Variant.Name != tt.want {
+					t.Fatalf("assigned %s, want %s", a.Variant.Name, tt.want)
+				}
+				if (a.Cookie != nil) != tt.wantCookie {
+					t.Fatalf("cookie set = %v, want %v", a.Cookie != nil, tt.wantCookie)
+				}
+				if a.Cookie != nil && (a.Cookie.Value != a.Variant.Name || a.Cookie.MaxAge != defaultCookieMaxAge) {
+					t.Fatalf("cookie = %+v", a.Cookie)
+				}
+			}
+		})
+	}
+}
+
+func TestCanaryStickyHeaderSpreadsUsers(t *testing.T) {
+	upstream, canary := newCanaryUpstream(t, 50, 50, config.CanaryConfig{StickyHeader: "X-User"})
+
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		a := canary.Assign(upstream, canaryRequest(map[string]string{"X-User": fmt.Sprintf("user-%d", i)}))
+		counts[a.Variant.Name]++
+	}
+	if counts["stable"] < 350 || counts["beta"] < 350 {
+		t.Fatalf("sticky header split = %v, want roughly even", counts)
+	}
+}
+
+func TestPickWeighted(t *testing.T) {
+	variants := []*Variant{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	weights := map[string]int{"a": 1, "b": 0, "c": 3}
+
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{n: 0, want: "a"},
+		{n: 1, want: "c"},
+		{n: 3, want: "c"},
+		{n: 4, want: "a"},
+		{n: 7, want: "c"},
+	}
+	for _, tt := range tests {
+		if got := pickWeighted(variants, weights, tt.n); got.Name != tt.want {
+			t.Errorf("pickWeighted(%d) = %s, want %s", tt.n, got.Name, tt.want)
+		}
+	}
+}
+
+func TestCanarySetWeights(t *testing.T) {
+	upstream, canary := newCanaryUpstream(t, 90, 10, config.CanaryConfig{})
+
+	tests := []struct {
+		name    string
+		weights map[string]int
+		wantErr bool
+	}{
+		{name: "unknown variant", weights: map[string]int{"gamma": 1}, wantErr: true},
+		{name: "negative weight", weights: map[string]int{"beta": -1}, wantErr: true},
+		{name: "zero total", weights: map[string]int{"stable": 0, "beta": 0}, wantErr: true},
+		{name: "shift all traffic to beta", weights: map[string]int{"stable": 0, "beta": 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := canary.SetWeights(upstream, tt.weights); (err != nil) != tt.wantErr {
+				t.Fatalf("SetWeights() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// 权重调整立即生效
+	for i := 0; i < 50; i++ {
+		if a := canary.Assign(upstream, canaryRequest(nil)); a.Variant.Name != "beta" {
+			t.Fatalf("assigned %s after shifting traffic to beta", a.Variant.Name)
+		}
+	}
+	statuses := canary.Status(upstream)
+	if statuses[0].Weight != 0 || statuses[1].Weight != 100 {
+		t.Fatalf("Status() weights = %d, %d", statuses[0].Weight, statuses[1].Weight)
+	}
+}
+
+func TestCanaryRecord(t *testing.T) {
+	upstream, canary := newCanaryUpstream(t, 1, 1, config.CanaryConfig{})
+	beta := upstream.Variants[1]
+
+	canary.Record(upstream, beta, http.StatusOK, 10*time.Millisecond)
+	canary.Record(upstream, beta, http.StatusBadGateway, 30*time.Millisecond)
+	canary.Record(upstream, beta, 0, 20*time.Millisecond)
+
+	m := canary.Status(upstream)[1].Metrics
+	if m.Requests != 3 || m.Errors != 2 || m.AvgLatencyMs != 20 {
+		t.Fatalf("metrics = %+v", m)
+	}
+	if m.StatusCodes["2xx"] != 1 || m.StatusCodes["5xx"] != 1 || m.StatusCodes["error"] != 1 {
+		t.Fatalf("status codes = %v", m.StatusCodes)
+	}
+}
+
+func TestCanaryNotConfigured(t *testing.T) {
+	r, err := NewRegistry([]config.UpstreamConfig{{Name: "plain", URL: "http://plain.example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	canary := NewCanary(r)
+	if a := canary.Assign(r.Get("plain"), canaryRequest(nil)); a != nil {
+		t.Fatalf("Assign() = %+v, want nil", a)
+	}
+	if a := canary.Assign(nil, canaryRequest(nil)); a != nil {
+		t.Fatalf("Assign(nil) = %+v, want nil", a)
+	}
+}
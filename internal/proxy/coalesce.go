@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// credentialHeaders 视为携带凭据的请求头
+var credentialHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "X-API-Key"}
+
+// Coalescer 合并相同的并发上游请求，同一时刻每个键只有一个请求发往上游
+type Coalescer struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight 进行中的上游请求
+type flight struct {
+	done chan struct{}
+	resp *Response
+	err  error
+}
+
+// NewCoalescer 创建请求合并器
+func NewCoalescer() *Coalescer {
+	return &Coalescer{flights: make(map[string]*flight)}
+}
+
+// Do 执行或等待键对应的上游请求。每个调用方得到独立的响应副本，
+// shared表示响应来自其他调用方发起的请求。ctx只控制当前调用方的等待。
+func (g *Coalescer) Do(ctx context.Context, key string, fn func() (*Response, error)) (resp *Response, shared bool, err error) {
+	g.mu.Lock()
+	if f, ok := g.flights[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.result(true)
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		}
+	}
+
+	f := &flight{done: make(chan struct{})}
+	g.flights[key] = f
+	g.mu.Unlock()
+
+	f.resp, f.err = fn()
+
+	g.mu.Lock()
+	delete(g.flights, key)
+	g.mu.Unlock()
+	close(f.done)
+
+	return f.result(false)
+}
+
+// result 返回请求结果的副本
+func (f *flight) result(shared bool) (*Response, bool, error) {
+	if f.err != nil {
+		return nil, shared, f.err
+	}
+	return f.resp.Clone(), shared, nil
+}
+
+// CoalesceKey 计算请求的合并键，请求不允许合并时返回false
+func CoalesceKey(upstream *Upstream, req *http.Request) (string, bool) {
+	if upstream == nil || !upstream.Config.Coalesce.Enabled {
+		return "", false
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return "", false
+	}
+	cfg := upstream.Config.Coalesce
+
+	h := sha256.New()
+	h.Write([]byte(req.Method + " " + req.URL.String() + "\n"))
+	for _, name := range cfg.Headers {
+		h.Write([]byte(http.CanonicalHeaderKey(name) + ": " + strings.Join(req.Header.Values(name), ",") + "\n"))
+	}
+
+	for _, name := range credentialHeaders {
+		values := req.Header.Values(name)
+		if len(values) == 0 || upstream.setsHeader(name) {
+			// 上游配置注入的凭证对所有调用方相同，不影响合并
+			continue
+		}
+		if !cfg.AllowCredentials {
+			return "", false
+		}
+		// 凭据参与合并键，只有凭据相同的请求才会共享响应
+		h.Write([]byte(name + ": " + strings.Join(values, ",") + "\n"))
+	}
+
+	return upstream.Name + ":" + hex.EncodeToString(h.Sum(nil)), true
+}
@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+
+	"go-echo-app/internal/config"
+)
+
+func TestCoalesceKey(t *testing.T) {
+	newUpstream := func(cfg config.UpstreamConfig) *Upstream {
+		cfg.Name, cfg.URL = "catalog", "http://catalog.example.com"
+		cfg.Coalesce.Enabled = true
+		r, err := NewRegistry([]config.UpstreamConfig{cfg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.Get("catalog")
+	}
+	newRequest := func(method string, header map[string]string) *http.Request {
+		req, _ := http.NewRequest(method, "http://catalog.example.com/items", nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		return req
+	}
+
+	tests := []struct {
+		name     string
+		upstream config.UpstreamConfig
+		method   string
+		header   map[string]string
+		want     bool
+	}{
+		{name: "plain get", method: http.MethodGet, want: true},
+		{name: "post is never coalesced", method: http.MethodPost, want: false},
+		{name: "client credentials", method: http.MethodGet, header: map[string]string{"Cookie": "session=1"}, want: false},
+		{
+			name:     "client credentials allowed",
+			upstream: config.UpstreamConfig{Coalesce: config.CoalesceConfig{AllowCredentials: true}},
+			method:   http.MethodGet, header: map[string]string{"Cookie": "session=1"}, want: true,
+		},
+		{
+			name:     "credentials injected from upstream config",
+			upstream: config.UpstreamConfig{Headers: map[string]string{"authorization": "Bearer upstream"}},
+			method:   http.MethodGet, header: map[string]string{"Authorization": "Bearer upstream"}, want: true,
+		},
+		{
+			name:     "client credentials next to injected ones",
+			upstream: config.UpstreamConfig{Headers: map[string]string{"Authorization": "Bearer upstream"}},
+			method:   http.MethodGet, header: map[string]string{"Authorization": "Bearer upstream", "X-API-Key": "k"}, want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := CoalesceKey(newUpstream(tt.upstream), newRequest(tt.method, tt.header)); ok != tt.want {
+				t.Fatalf("CoalesceKey() ok = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+
+	// 允许凭据时，凭据不同的请求不共享响应
+	u := newUpstream(config.UpstreamConfig{Coalesce: config.CoalesceConfig{AllowCredentials: true}})
+	a, _ := CoalesceKey(u, newRequest(http.MethodGet, map[string]string{"Cookie": "session=1"}))
+	b, _ := CoalesceKey(u, newRequest(http.MethodGet, map[string]string{"Cookie": "session=2"}))
+	if a == b {
+		t.Fatal("requests with different credentials share a coalescing key")
+	}
+}
@@ -0,0 +1,208 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// 支持的内容编码
+const (
+	EncodingGzip     = "gzip"
+	EncodingDeflate  = "deflate"
+	EncodingBrotli   = "br"
+	EncodingIdentity = "identity"
+)
+
+// AcceptEncoding 中转向上游声明的可解码编码
+const AcceptEncoding = "br, gzip, deflate"
+
+// encodingPreference 服务端偏好顺序，q值相同时按此顺序选择
+var encodingPreference = []string{EncodingBrotli, EncodingGzip, EncodingDeflate}
+
+// SupportedEncoding 判断编码是否可由中转编解码
+func SupportedEncoding(encoding string) bool {
+	switch normalizeEncoding(encoding) {
+	case EncodingGzip, EncodingDeflate, EncodingBrotli, EncodingIdentity:
+		return true
+	}
+	return false
+}
+
+// Decode 按Content-Encoding解码数据，多重编码按逆序逐层解码
+func Decode(contentEncoding string, data []byte) ([]byte, error) {
+	encodings := parseContentEncoding(contentEncoding)
+	for i := len(encodings) - 1; i >= 0; i-- {
+		var err error
+		if data, err = decodeOne(encodings[i], data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// Encode 使用指定编码压缩数据
+func Encode(encoding string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+
+	switch normalizeEncoding(encoding) {
+	case "", EncodingIdentity:
+		return data, nil
+	case EncodingGzip:
+		w = gzip.NewWriter(&buf)
+	case EncodingDeflate:
+		w = zlib.NewWriter(&buf)
+	case EncodingBrotli:
+		w = brotli.NewWriter(&buf)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// NegotiateEncoding 根据客户端Accept-Encoding选择最合适的压缩编码，
+// 返回空字符串表示使用identity
+func NegotiateEncoding(acceptEncoding string) string {
+	accepted := parseAcceptEncoding(acceptEncoding)
+
+	best, bestQ := "", 0.0
+	for _, encoding := range encodingPreference {
+		q, ok := accepted[encoding]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// AcceptsEncoding 判断客户端是否接受指定的内容编码
+func AcceptsEncoding(acceptEncoding, contentEncoding string) bool {
+	encodings := parseContentEncoding(contentEncoding)
+	if len(encodings) == 0 {
+		return true
+	}
+	if len(encodings) > 1 {
+		return false
+	}
+
+	accepted := parseAcceptEncoding(acceptEncoding)
+	if q, ok := accepted[encodings[0]]; ok {
+		return q > 0
+	}
+	q, ok := accepted["*"]
+	return ok && q > 0
+}
+
+// Compressible 判断内容类型是否值得压缩
+func Compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml",
+		"application/x-www-form-urlencoded", "image/svg+xml":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// decodeOne 解码单层编码
+func decodeOne(encoding string, data []byte) ([]byte, error) {
+	var r io.Reader
+	switch encoding {
+	case EncodingIdentity:
+		return data, nil
+	case EncodingGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decode gzip: %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	case EncodingDeflate:
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decode deflate: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	case EncodingBrotli:
+		r = brotli.NewReader(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", encoding, err)
+	}
+	return decoded, nil
+}
+
+// parseContentEncoding 解析Content-Encoding，忽略identity
+func parseContentEncoding(header string) []string {
+	var encodings []string
+	for _, part := range strings.Split(header, ",") {
+		encoding := normalizeEncoding(part)
+		if encoding != "" && encoding != EncodingIdentity {
+			encodings = append(encodings, encoding)
+		}
+	}
+	return encodings
+}
+
+// parseAcceptEncoding 解析Accept-Encoding为编码到q值的映射
+func parseAcceptEncoding(header string) map[string]float64 {
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		encoding := normalizeEncoding(fields[0])
+		if encoding == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		accepted[encoding] = q
+	}
+	return accepted
+}
+
+// normalizeEncoding 规范化编码名称
+func normalizeEncoding(encoding string) string {
+	encoding = strings.ToLower(strings.TrimSpace(encoding))
+	if encoding == "x-gzip" {
+		return EncodingGzip
+	}
+	return encoding
+}
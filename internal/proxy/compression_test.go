@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{accept: "", want: ""},
+		{accept: "identity", want: ""},
+		{accept: "gzip", want: EncodingGzip},
+		{accept: "gzip, deflate, br", want: EncodingBrotli},
+		{accept: "br;q=0.5, gzip", want: EncodingGzip},
+		{accept: "x-gzip", want: EncodingGzip},
+		{accept: "*", want: EncodingBrotli},
+		{accept: "*, br;q=0", want: EncodingGzip},
+		{accept: "gzip;q=0, deflate;q=0.1", want: EncodingDeflate},
+		{accept: "compress", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if got := NegotiateEncoding(tt.accept); got != tt.want {
+				t.Fatalf("NegotiateEncoding(%q) = %q, want %q", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	tests := []struct {
+		accept, encoding string
+		want             bool
+	}{
+		{accept: "", encoding: "", want: true},
+		{accept: "", encoding: "identity", want: true},
+		{accept: "", encoding: "gzip", want: false},
+		{accept: "gzip", encoding: "GZIP", want: true},
+		{accept: "gzip;q=0", encoding: "gzip", want: false},
+		{accept: "*", encoding: "br", want: true},
+		// 多重编码总是重新协商
+		{accept: "gzip, br", encoding: "gzip, br", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := AcceptsEncoding(tt.accept, tt.encoding); got != tt.want {
+			t.Errorf("AcceptsEncoding(%q, %q) = %v, want %v", tt.accept, tt.encoding, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	data := bytes.Repeat([]byte("hello proxy "), 100)
+
+	for _, encoding := range []string{EncodingGzip, EncodingDeflate, EncodingBrotli, EncodingIdentity} {
+		t.Run(encoding, func(t *testing.T) {
+			encoded, err := Encode(encoding, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := Decode(encoding, encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decoded, data) {
+				t.Fatal("decoded data differs from the original")
+			}
+		})
+	}
+
+	// 多重编码按逆序解码
+	gz, _ := Encode(EncodingGzip, data)
+	br, _ := Encode(EncodingBrotli, gz)
+	if decoded, err := Decode("gzip, br", br); err != nil || !bytes.Equal(decoded, data) {
+		t.Fatalf("Decode(gzip, br) error = %v", err)
+	}
+
+	if _, err := Encode("compress", data); err == nil {
+		t.Error("Encode(compress): expected error")
+	}
+	if _, err := Decode(EncodingGzip, []byte("not gzip")); err == nil {
+		t.Error("Decode of corrupt gzip: expected error")
+	}
+}
+
+func TestCompressible(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"text/html; charset=utf-8", true},
+		{"application/json", true},
+		{"application/problem+json", true},
+		{"application/atom+xml", true},
+		{"image/png", false},
+		{"application/octet-stream", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := Compressible(tt.contentType); got != tt.want {
+			t.Errorf("Compressible(%q) = %v, want %v", tt.contentType, got, tt.want)
+		}
+	}
+}
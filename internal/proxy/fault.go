@@ -0,0 +1,223 @@
+package proxy
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	"go-echo-app/internal/config"
+)
+
+// FaultDecision 一次请求命中的故障动作
+type FaultDecision struct {
+	RuleID        string
+	Delay         time.Duration
+	AbortStatus   int
+	TruncateBytes int // 小于0表示不截断
+	Reset         bool
+}
+
+// Active 判断是否需要注入任何故障
+func (d *FaultDecision) Active() bool {
+	return d != nil && (d.Delay > 0 || d.AbortStatus != 0 || d.TruncateBytes >= 0 || d.Reset)
+}
+
+// FaultInjector 可在运行时调整的故障注入器
+type FaultInjector struct {
+	mu      sync.RWMutex
+	enabled bool
+	rules   []config.FaultRule
+}
+
+// NewFaultInjector 创建故障注入器
+func NewFaultInjector(enabled bool, rules []config.FaultRule) (*FaultInjector, error) {
+	if err := ValidateFaultRules(rules); err != nil {
+		return nil, err
+	}
+	return &FaultInjector{enabled: enabled, rules: rules}, nil
+}
+
+// Enabled 返回故障注入总开关状态
+func (f *FaultInjector) Enabled() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.enabled
+}
+
+// SetEnabled 设置故障注入总开关
+func (f *FaultInjector) SetEnabled(enabled bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.enabled = enabled
+}
+
+// Rules 返回当前规则的副本
+func (f *FaultInjector) Rules() []config.FaultRule {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return append([]config.FaultRule(nil), f.rules...)
+}
+
+// SetRules 整体替换规则
+func (f *FaultInjector) SetRules(rules []config.FaultRule) error {
+	if err := ValidateFaultRules(rules); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append([]config.FaultRule(nil), rules...)
+	return nil
+}
+
+// SetRuleEnabled 启用或停用单条规则，规则不存在时返回false
+func (f *FaultInjector) SetRuleEnabled(id string, enabled bool) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range f.rules {
+		if f.rules[i].ID == id {
+			f.rules[i].Enabled = enabled
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate 计算请求需要注入的故障，未命中时返回nil。
+// 多条规则同时命中时，按规则顺序取第一条命中规则的各项动作。
+func (f *FaultInjector) Evaluate(upstream, targetPath string, header http.Header) *FaultDecision {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if !f.enabled {
+		return nil
+	}
+
+	for _, rule := range f.rules {
+		if !rule.Enabled || !faultMatches(rule.Match, upstream, targetPath, header) {
+			continue
+		}
+
+		decision := &FaultDecision{RuleID: rule.ID, TruncateBytes: -1}
+		if rule.Delay != nil && roll(rule.Delay.Percent) {
+			decision.Delay = time.Duration(rule.Delay.FixedMs) * time.Millisecond
+			if rule.Delay.RandomMs > 0 {
+				decision.Delay += time.Duration(rand.Intn(rule.Delay.RandomMs)) * time.Millisecond
+			}
+		}
+		if rule.Reset != nil && roll(rule.Reset.Percent) {
+			decision.Reset = true
+		} else if rule.Abort != nil && roll(rule.Abort.Percent) {
+			decision.AbortStatus = rule.Abort.Status
+		} else if rule.Truncate != nil && roll(rule.Truncate.Percent) {
+			decision.TruncateBytes = rule.Truncate.Bytes
+		}
+
+		if decision.Active() {
+			return decision
+		}
+	}
+	return nil
+}
+
+// ValidateFaultRules 校验故障注入规则
+func ValidateFaultRules(rules []config.FaultRule) error {
+	ids := make(map[string]bool)
+	for _, rule := range rules {
+		if rule.ID == "" {
+			return fmt.Errorf("fault rule must have an id")
+		}
+		if ids[rule.ID] {
+			return fmt.Errorf("duplicate fault rule %q", rule.ID)
+		}
+		ids[rule.ID] = true
+
+		if rule.Match.PathPattern != "" {
+			if _, err := path.Match(rule.Match.PathPattern, "/"); err != nil {
+				return fmt.Errorf("fault rule %q has invalid path pattern: %w", rule.ID, err)
+			}
+		}
+		if rule.Match.HeaderValue != "" && rule.Match.Header == "" {
+			return fmt.Errorf("fault rule %q sets header_value without header", rule.ID)
+		}
+
+		percents := []*float64{}
+		if rule.Delay != nil {
+			if rule.Delay.FixedMs < 0 || rule.Delay.RandomMs < 0 {
+				return fmt.Errorf("fault rule %q has negative delay", rule.ID)
+			}
+			percents = append(percents, rule.Delay.Percent)
+		}
+		if rule.Abort != nil {
+			if rule.Abort.Status < 100 || rule.Abort.Status > 599 {
+				return fmt.Errorf("fault rule %q has invalid abort status %d", rule.ID, rule.Abort.Status)
+			}
+			percents = append(percents, rule.Abort.Percent)
+		}
+		if rule.Truncate != nil {
+			if rule.Truncate.Bytes < 0 {
+				return fmt.Errorf("fault rule %q has negative truncate size", rule.ID)
+			}
+			percents = append(percents, rule.Truncate.Percent)
+		}
+		if rule.Reset != nil {
+			percents = append(percents, rule.Reset.Percent)
+		}
+		if len(percents) == 0 {
+			return fmt.Errorf("fault rule %q has no fault action", rule.ID)
+		}
+		for _, p := range percents {
+			if p != nil && (*p < 0 || *p > 100) {
+				return fmt.Errorf("fault rule %q has percent outside 0-100", rule.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// faultMatches 判断请求是否在规则作用范围内
+func faultMatches(match config.FaultMatch, upstream, targetPath string, header http.Header) bool {
+	if match.Upstream != "" && match.Upstream != upstream {
+		return false
+	}
+	if match.PathPattern != "" {
+		if ok, _ := path.Match(match.PathPattern, targetPath); !ok {
+			return false
+		}
+	}
+	if match.Header != "" {
+		values, ok := header[http.CanonicalHeaderKey(match.Header)]
+		if !ok {
+			return false
+		}
+		if match.HeaderValue != "" && !containsString(values, match.HeaderValue) {
+			return false
+		}
+	}
+	return true
+}
+
+// roll 按百分比随机决定是否触发，未设置时视为100，0表示从不触发
+func roll(percent *float64) bool {
+	if percent == nil || *percent >= 100 {
+		return true
+	}
+	if *percent <= 0 {
+		return false
+	}
+	return rand.Float64()*100 < *percent
+}
+
+// containsString 判断切片是否包含指定字符串
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
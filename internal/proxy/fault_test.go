@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+
+	"go-echo-app/internal/config"
+)
+
+func percent(p float64) *float64 {
+	return &p
+}
+
+func TestFaultInjectorPercent(t *testing.T) {
+	tests := []struct {
+		name    string
+		percent *float64
+		want    bool
+	}{
+		{name: "unset means always", percent: nil, want: true},
+		{name: "100 means always", percent: percent(100), want: true},
+		{name: "0 means never", percent: percent(0), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFaultInjector(true, []config.FaultRule{{
+				ID:      "abort",
+				Enabled: true,
+				Abort:   &config.FaultAbort{Status: http.StatusServiceUnavailable, Percent: tt.percent},
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 100; i++ {
+				got := f.Evaluate("orders", "/v1/orders", http.Header{}) != nil
+				if got != tt.want {
+					t.Fatalf("Evaluate() fired = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFaultRulesPercentRange(t *testing.T) {
+	for _, p := range []float64{-1, 100.5} {
+		err := ValidateFaultRules([]config.FaultRule{{
+			ID:    "delay",
+			Delay: &config.FaultDelay{FixedMs: 10, Percent: percent(p)},
+		}})
+		if err == nil {
+			t.Errorf("percent %v: expected error", p)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// IdempotencyState 幂等键的查询结果
+type IdempotencyState int
+
+const (
+	// IdempotencyAcquired 首次出现的键，调用方应继续处理并在完成后调用Complete或Release
+	IdempotencyAcquired IdempotencyState = iota
+	// IdempotencyInProgress 相同键的请求仍在处理中
+	IdempotencyInProgress
+	// IdempotencyReplay 已有完成的响应，应直接重放
+	IdempotencyReplay
+	// IdempotencyMismatch 键被复用于不同的请求内容
+	IdempotencyMismatch
+)
+
+// IdempotencyStore 幂等记录存储
+type IdempotencyStore interface {
+	// Acquire 尝试占用键，状态为IdempotencyReplay时同时返回已保存的响应
+	Acquire(key, fingerprint string, ttl time.Duration) (IdempotencyState, *Response)
+	// Complete 保存键对应的最终响应
+	Complete(key string, resp *Response)
+	// Release 放弃占用，之后的重试可以重新执行
+	Release(key string)
+}
+
+// idempotencyRecord 内存中的幂等记录
+type idempotencyRecord struct {
+	fingerprint string
+	response    *Response
+	expiresAt   time.Time
+}
+
+// MemoryIdempotencyStore 基于内存的幂等记录存储
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	records   map[string]*idempotencyRecord
+	lastSweep time.Time
+}
+
+// sweepInterval 清理过期记录的最小间隔
+const sweepInterval = time.Minute
+
+// NewMemoryIdempotencyStore 创建内存幂等记录存储
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: make(map[string]*idempotencyRecord)}
+}
+
+// Acquire 实现IdempotencyStore
+func (s *MemoryIdempotencyStore) Acquire(key, fingerprint string, ttl time.Duration) (IdempotencyState, *Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	if record, ok := s.records[key]; ok && now.Before(record.expiresAt) {
+		switch {
+		case record.fingerprint != fingerprint:
+			return IdempotencyMismatch, nil
+		case record.response == nil:
+			return IdempotencyInProgress, nil
+		default:
+			return IdempotencyReplay, record.response.Clone()
+		}
+	}
+
+	s.records[key] = &idempotencyRecord{fingerprint: fingerprint, expiresAt: now.Add(ttl)}
+	return IdempotencyAcquired, nil
+}
+
+// Complete 实现IdempotencyStore
+func (s *MemoryIdempotencyStore) Complete(key string, resp *Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record, ok := s.records[key]; ok {
+		record.response = resp.Clone()
+	}
+}
+
+// Release 实现IdempotencyStore
+func (s *MemoryIdempotencyStore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+}
+
+// sweep 定期删除过期记录，调用方需持有锁
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, record := range s.records {
+		if !now.Before(record.expiresAt) {
+			delete(s.records, key)
+		}
+	}
+}
+
+// IdempotencyKey 由客户端提供的键、客户端标识和路由组合出存储键
+func IdempotencyKey(key, client, method, target string) string {
+	sum := sha256.Sum256([]byte(key + "\x00" + client + "\x00" + method + " " + target))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestFingerprint 计算请求内容指纹，用于识别键被复用于不同请求
+func RequestFingerprint(method, target string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + target + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	resp := &Response{StatusCode: http.StatusCreated, Header: http.Header{}, Body: []byte("created")}
+
+	tests := []struct {
+		name  string
+		setup func(s *MemoryIdempotencyStore)
+		fp    string
+		ttl   time.Duration
+		want  IdempotencyState
+	}{
+		{name: "first use is acquired", setup: func(s *MemoryIdempotencyStore) {}, fp: "a", ttl: time.Hour, want: IdempotencyAcquired},
+		{
+			name:  "concurrent duplicate is in progress",
+			setup: func(s *MemoryIdempotencyStore) { s.Acquire("k", "a", time.Hour) },
+			fp:    "a", ttl: time.Hour, want: IdempotencyInProgress,
+		},
+		{
+			name: "completed key is replayed",
+			setup: func(s *MemoryIdempotencyStore) {
+				s.Acquire("k", "a", time.Hour)
+				s.Complete("k", resp)
+			},
+			fp: "a", ttl: time.Hour, want: IdempotencyReplay,
+		},
+		{
+			name: "different request with the same key",
+			setup: func(s *MemoryIdempotencyStore) {
+				s.Acquire("k", "a", time.Hour)
+				s.Complete("k", resp)
+			},
+			fp: "b", ttl: time.Hour, want: IdempotencyMismatch,
+		},
+		{
+			name: "released key can be retried",
+			setup: func(s *MemoryIdempotencyStore) {
+				s.Acquire("k", "a", time.Hour)
+				s.Release("k")
+			},
+			fp: "a", ttl: time.Hour, want: IdempotencyAcquired,
+		},
+		{
+			name: "expired key is acquired again",
+			setup: func(s *MemoryIdempotencyStore) {
+				s.Acquire("k", "a", -time.Second)
+				s.Complete("k", resp)
+			},
+			fp: "b", ttl: time.Hour, want: IdempotencyAcquired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryIdempotencyStore()
+			tt.setup(s)
+			state, stored := s.Acquire("k", tt.fp, tt.ttl)
+			if state != tt.want {
+				t.Fatalf("Acquire() = %v, want %v", state, tt.want)
+			}
+			if (stored != nil) != (state == IdempotencyReplay) {
+				t.Fatalf("Acquire() response = %v for state %v", stored, state)
+			}
+			if stored != nil && (stored.StatusCode != http.StatusCreated || string(stored.Body) != "created") {
+				t.Fatalf("replayed response = %+v", stored)
+			}
+		})
+	}
+}
+
+func TestMemoryIdempotencyStoreReplayIsACopy(t *testing.T) {
+	s := NewMemoryIdempotencyStore()
+	s.Acquire("k", "a", time.Hour)
+	s.Complete("k", &Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: []byte("v1")})
+
+	_, first := s.Acquire("k", "a", time.Hour)
+	first.Body[0] = 'x'
+	first.Header.Set("X-Changed", "1")
+
+	_, second := s.Acquire("k", "a", time.Hour)
+	if string(second.Body) != "v1" || second.Header.Get("X-Changed") != "" {
+		t.Fatal("modifying a replayed response changed the stored one")
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	base := IdempotencyKey("key", "ip:1.2.3.4", http.MethodPost, "http://a.example.com/orders")
+	for name, other := range map[string]string{
+		"client": IdempotencyKey("key", "ip:5.6.7.8", http.MethodPost, "http://a.example.com/orders"),
+		"method": IdempotencyKey("key", "ip:1.2.3.4", http.MethodPatch, "http://a.example.com/orders"),
+		"target": IdempotencyKey("key", "ip:1.2.3.4", http.MethodPost, "http://a.example.com/refunds"),
+		"key":    IdempotencyKey("key2", "ip:1.2.3.4", http.MethodPost, "http://a.example.com/orders"),
+	} {
+		if other == base {
+			t.Errorf("keys with a different %s collide", name)
+		}
+	}
+
+	if RequestFingerprint(http.MethodPost, "/a", []byte("1")) == RequestFingerprint(http.MethodPost, "/a", []byte("2")) {
+		t.Error("fingerprints ignore the body")
+	}
+}
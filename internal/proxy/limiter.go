@@ -0,0 +1,165 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// defaultQueueTimeout 未配置时的排队等待上限
+const defaultQueueTimeout = time.Second
+
+// 限流错误
+var (
+	ErrRateLimited  = errors.New("upstream rate limit exceeded")
+	ErrQueueFull    = errors.New("upstream concurrency limit reached and queue is full")
+	ErrQueueTimeout = errors.New("timed out waiting for upstream concurrency slot")
+)
+
+// LimitError 限流拒绝错误，RetryAfter为建议的重试等待时间
+type LimitError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+// Error 实现error接口
+func (e *LimitError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap 返回底层错误
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// upstreamLimiter 单个上游的速率和并发限制
+type upstreamLimiter struct {
+	rate         *rate.Limiter
+	slots        chan struct{}
+	queueSize    int64
+	queued       atomic.Int64
+	queueTimeout time.Duration
+}
+
+// Limiter 按上游维护的出站限流器
+type Limiter struct {
+	mu       sync.Mutex
+	limiters map[string]*upstreamLimiter
+}
+
+// NewLimiter 根据注册表中的上游配置创建出站限流器
+func NewLimiter(registry *Registry) *Limiter {
+	l := &Limiter{limiters: make(map[string]*upstreamLimiter)}
+
+	for _, upstream := range registry.All() {
+		cfg := upstream.Config.Limits
+		if cfg.RequestsPerSecond <= 0 && cfg.MaxInFlight <= 0 {
+			continue
+		}
+
+		ul := &upstreamLimiter{
+			queueSize:    int64(cfg.QueueSize),
+			queueTimeout: time.Duration(cfg.QueueTimeoutMs) * time.Millisecond,
+		}
+		if ul.queueTimeout <= 0 {
+			ul.queueTimeout = defaultQueueTimeout
+		}
+		if cfg.RequestsPerSecond > 0 {
+			burst := cfg.Burst
+			if burst <= 0 {
+				burst = int(math.Ceil(cfg.RequestsPerSecond))
+			}
+			ul.rate = rate.NewLimiter(rate.Limit(cfg.RequestsPerSecond), burst)
+		}
+		if cfg.MaxInFlight > 0 {
+			ul.slots = make(chan struct{}, cfg.MaxInFlight)
+		}
+		l.limiters[upstream.Name] = ul
+	}
+	return l
+}
+
+// Acquire 为一次上游调用申请许可，成功时返回的release必须在调用结束后执行。
+// 超过速率限制返回ErrRateLimited，并发已满且无法排队返回ErrQueueFull或ErrQueueTimeout。
+// 速率配额在取得并发许可之后才扣除，被并发或排队拒绝的请求不消耗后续请求的额度
+func (l *Limiter) Acquire(ctx context.Context, upstream *Upstream) (release func(), err error) {
+	if upstream == nil {
+		return func() {}, nil
+	}
+	ul, ok := l.limiters[upstream.Name]
+	if !ok {
+		return func() {}, nil
+	}
+
+	// 令牌不足时直接拒绝，不必先占用并发许可或排队
+	if delay := ul.rateDelay(); delay > 0 {
+		return nil, &LimitError{Err: ErrRateLimited, RetryAfter: delay}
+	}
+
+	release, err = ul.acquireSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ul.rate != nil {
+		reservation := ul.rate.Reserve()
+		if delay := reservation.Delay(); delay > 0 {
+			reservation.Cancel()
+			release()
+			return nil, &LimitError{Err: ErrRateLimited, RetryAfter: delay}
+		}
+	}
+	return release, nil
+}
+
+// rateDelay 估算获得下一个速率令牌需要等待的时间，不扣除令牌
+func (ul *upstreamLimiter) rateDelay() time.Duration {
+	if ul.rate == nil {
+		return 0
+	}
+	missing := 1 - ul.rate.Tokens()
+	if missing <= 0 {
+		return 0
+	}
+	return time.Duration(missing / float64(ul.rate.Limit()) * float64(time.Second))
+}
+
+// acquireSlot 申请并发许可，并发已满时进入有界队列等待
+func (ul *upstreamLimiter) acquireSlot(ctx context.Context) (func(), error) {
+	if ul.slots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case ul.slots <- struct{}{}:
+		return ul.release, nil
+	default:
+	}
+
+	if ul.queued.Add(1) > ul.queueSize {
+		ul.queued.Add(-1)
+		return nil, &LimitError{Err: ErrQueueFull, RetryAfter: ul.queueTimeout}
+	}
+	defer ul.queued.Add(-1)
+
+	timer := time.NewTimer(ul.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case ul.slots <- struct{}{}:
+		return ul.release, nil
+	case <-timer.C:
+		return nil, &LimitError{Err: ErrQueueTimeout, RetryAfter: ul.queueTimeout}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// release 归还并发许可
+func (ul *upstreamLimiter) release() {
+	<-ul.slots
+}
@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-echo-app/internal/config"
+)
+
+func newTestLimiter(t *testing.T, limits config.LimitConfig) (*Limiter, *Upstream) {
+	t.Helper()
+	registry, err := NewRegistry([]config.UpstreamConfig{{
+		Name:   "orders",
+		URL:    "http://orders.internal",
+		Limits: limits,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewLimiter(registry), registry.All()[0]
+}
+
+func TestLimiterRejectedRequestsKeepRateBudget(t *testing.T) {
+	// 速率极低，只有2个突发令牌；并发1且不排队
+	l, upstream := newTestLimiter(t, config.LimitConfig{
+		RequestsPerSecond: 0.001,
+		Burst:             2,
+		MaxInFlight:       1,
+	})
+
+	release, err := l.Acquire(context.Background(), upstream)
+	if err != nil {
+		t.Fatalf("first Acquire: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := l.Acquire(context.Background(), upstream); !errors.Is(err, ErrQueueFull) {
+			t.Fatalf("Acquire while busy = %v, want ErrQueueFull", err)
+		}
+	}
+	release()
+
+	// 被拒绝的请求不应消耗突发额度中的第二个令牌
+	release, err = l.Acquire(context.Background(), upstream)
+	if err != nil {
+		t.Fatalf("Acquire after release: %v", err)
+	}
+	release()
+
+	if _, err := l.Acquire(context.Background(), upstream); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Acquire over burst = %v, want ErrRateLimited", err)
+	}
+}
+
+func TestLimiterQueueTimeoutKeepsRateBudget(t *testing.T) {
+	l, upstream := newTestLimiter(t, config.LimitConfig{
+		RequestsPerSecond: 0.001,
+		Burst:             2,
+		MaxInFlight:       1,
+		QueueSize:         1,
+		QueueTimeoutMs:    10,
+	})
+
+	release, err := l.Acquire(context.Background(), upstream)
+	if err != nil {
+		t.Fatalf("first Acquire: %v", err)
+	}
+	if _, err := l.Acquire(context.Background(), upstream); !errors.Is(err, ErrQueueTimeout) {
+		t.Fatalf("queued Acquire = %v, want ErrQueueTimeout", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, upstream); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("cancelled Acquire = %v, want context.DeadlineExceeded", err)
+	}
+	release()
+
+	if release, err = l.Acquire(context.Background(), upstream); err != nil {
+		t.Fatalf("Acquire after release: %v", err)
+	}
+	release()
+}
@@ -0,0 +1,326 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 镜像相关默认值
+const (
+	defaultMirrorTimeout = 10 * time.Second
+	diffSampleSize       = 512
+)
+
+// comparedHeaders 比对主/影子响应时检查的响应头
+var comparedHeaders = []string{"Content-Type", "Location"}
+
+// MirrorStats 单个上游的镜像统计
+type MirrorStats struct {
+	Sent     int64 `json:"sent"`
+	Dropped  int64 `json:"dropped"`
+	Errors   int64 `json:"errors"`
+	Matched  int64 `json:"matched"`
+	Mismatch int64 `json:"mismatched"`
+}
+
+// MirrorDiff 主/影子响应的差异记录
+type MirrorDiff struct {
+	Time          time.Time `json:"time"`
+	Upstream      string    `json:"upstream"`
+	Method        string    `json:"method"`
+	URL           string    `json:"url"`
+	PrimaryStatus int       `json:"primary_status"`
+	ShadowStatus  int       `json:"shadow_status,omitempty"`
+	ShadowError   string    `json:"shadow_error,omitempty"`
+	Headers       []string  `json:"headers,omitempty"`
+	BodyDiffers   bool      `json:"body_differs"`
+	PrimaryBody   string    `json:"primary_body,omitempty"`
+	ShadowBody    string    `json:"shadow_body,omitempty"`
+}
+
+// Mirror 异步流量镜像器
+//
+// 影子请求在独立的goroutine中发送，并发数超过上限时直接丢弃，
+// 任何影子侧的错误都不会影响主请求。
+type Mirror struct {
+	client    *http.Client
+	sem       chan struct{}
+	diffLimit int
+
+	mu    sync.Mutex
+	stats map[string]*MirrorStats
+	diffs []MirrorDiff
+}
+
+// MirrorCall 一次进行中的镜像，主请求完成后需调用Complete
+type MirrorCall struct {
+	primary chan *Response
+	once    sync.Once
+}
+
+// NewMirror 创建流量镜像器
+func NewMirror(client *http.Client, concurrency, diffLimit int) *Mirror {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &Mirror{
+		client:    client,
+		sem:       make(chan struct{}, concurrency),
+		diffLimit: diffLimit,
+		stats:     make(map[string]*MirrorStats),
+	}
+}
+
+// Start 按采样比例向影子上游发送请求副本，未镜像时返回nil。
+// target、header和body均取自即将发往主上游的请求。
+func (m *Mirror) Start(upstream *Upstream, method string, target *url.URL, header http.Header, body []byte) *MirrorCall {
+	if upstream == nil || upstream.MirrorURL == nil {
+		return nil
+	}
+	cfg := upstream.Config.Mirror
+	if len(cfg.Methods) > 0 && !containsFold(cfg.Methods, method) {
+		return nil
+	}
+	if !roll(cfg.Percent) {
+		return nil
+	}
+
+	select {
+	case m.sem <- struct{}{}:
+	default:
+		m.record(upstream.Name, func(s *MirrorStats) { s.Dropped++ })
+		return nil
+	}
+
+	shadowURL := upstream.Rewrite(target, upstream.MirrorURL)
+	header = header.Clone()
+	setHeaders(header, cfg.Headers)
+	header.Set("X-Proxy-Shadow", "true")
+
+	call := &MirrorCall{primary: make(chan *Response, 1)}
+	go func() {
+		defer func() { <-m.sem }()
+		m.run(upstream, method, shadowURL.String(), header, body, call)
+	}()
+	return call
+}
+
+// Complete 通知镜像主请求已完成，resp为nil表示主请求失败
+func (c *MirrorCall) Complete(resp *Response) {
+	if c == nil {
+		return
+	}
+	c.once.Do(func() {
+		if resp != nil {
+			resp = resp.Clone()
+		}
+		c.primary <- resp
+	})
+}
+
+// Stats 返回各上游的镜像统计
+func (m *Mirror) Stats() map[string]MirrorStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make(map[string]MirrorStats, len(m.stats))
+	for name, s := range m.stats {
+		stats[name] = *s
+	}
+	return stats
+}
+
+// Diffs 返回记录的差异，upstream为空时返回全部
+func (m *Mirror) Diffs(upstream string) []MirrorDiff {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	diffs := make([]MirrorDiff, 0, len(m.diffs))
+	for _, d := range m.diffs {
+		if upstream == "" || d.Upstream == upstream {
+			diffs = append(diffs, d)
+		}
+	}
+	return diffs
+}
+
+// ClearDiffs 清空差异记录
+func (m *Mirror) ClearDiffs() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.diffs = nil
+}
+
+// run 发送影子请求并在需要时与主响应比对
+func (m *Mirror) run(upstream *Upstream, method, target string, header http.Header, body []byte, call *MirrorCall) {
+	cfg := upstream.Config.Mirror
+	timeout := defaultMirrorTimeout
+	if cfg.TimeoutMs > 0 {
+		timeout = time.Duration(cfg.TimeoutMs) * time.Millisecond
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	shadow, shadowErr := m.send(ctx, method, target, header, body)
+	m.record(upstream.Name, func(s *MirrorStats) {
+		s.Sent++
+		if shadowErr != nil {
+			s.Errors++
+		}
+	})
+
+	if !cfg.RecordDiffs {
+		return
+	}
+
+	// 等待主请求结果，主请求失败或超时则不比对
+	var primary *Response
+	select {
+	case primary = <-call.primary:
+	case <-ctx.Done():
+	}
+	if primary == nil {
+		return
+	}
+
+	diff, differs := compareResponses(primary, shadow, shadowErr)
+	m.record(upstream.Name, func(s *MirrorStats) {
+		if differs {
+			s.Mismatch++
+		} else {
+			s.Matched++
+		}
+	})
+	if !differs {
+		return
+	}
+
+	diff.Time = time.Now()
+	diff.Upstream = upstream.Name
+	diff.Method = method
+	diff.URL = target
+	m.addDiff(diff)
+}
+
+// send 发送影子请求
+func (m *Mirror) send(ctx context.Context, method, target string, header http.Header, body []byte) (*Response, error) {
+	var reader io.Reader
+	if len(body) > 0 {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return ReadResponse(resp)
+}
+
+// record 更新上游统计
+func (m *Mirror) record(upstream string, update func(*MirrorStats)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.stats[upstream]
+	if !ok {
+		s = &MirrorStats{}
+		m.stats[upstream] = s
+	}
+	update(s)
+}
+
+// addDiff 记录差异，超过上限时丢弃最旧的记录
+func (m *Mirror) addDiff(diff MirrorDiff) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.diffLimit <= 0 {
+		return
+	}
+	m.diffs = append(m.diffs, diff)
+	if len(m.diffs) > m.diffLimit {
+		m.diffs = m.diffs[len(m.diffs)-m.diffLimit:]
+	}
+}
+
+// compareResponses 比对主/影子响应，返回差异及是否存在差异
+func compareResponses(primary, shadow *Response, shadowErr error) (MirrorDiff, bool) {
+	diff := MirrorDiff{PrimaryStatus: primary.StatusCode}
+	if shadowErr != nil {
+		diff.ShadowError = shadowErr.Error()
+		return diff, true
+	}
+	diff.ShadowStatus = shadow.StatusCode
+
+	for _, name := range comparedHeaders {
+		if primary.Header.Get(name) != shadow.Header.Get(name) {
+			diff.Headers = append(diff.Headers, name)
+		}
+	}
+
+	primaryBody, err := primary.DecodedBody()
+	if err != nil {
+		primaryBody = primary.Body
+	}
+	shadowBody, err := shadow.DecodedBody()
+	if err != nil {
+		shadowBody = shadow.Body
+	}
+	diff.BodyDiffers = !bodiesEqual(primary.Header.Get("Content-Type"), primaryBody, shadowBody)
+	if diff.BodyDiffers {
+		diff.PrimaryBody = sample(primaryBody)
+		diff.ShadowBody = sample(shadowBody)
+	}
+
+	differs := diff.PrimaryStatus != diff.ShadowStatus || len(diff.Headers) > 0 || diff.BodyDiffers
+	return diff, differs
+}
+
+// bodiesEqual 比较响应体，JSON按语义比较以忽略字段顺序和空白
+func bodiesEqual(contentType string, a, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	if !strings.Contains(contentType, "json") {
+		return false
+	}
+
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// sample 截取响应体样本用于展示
+func sample(body []byte) string {
+	if len(body) > diffSampleSize {
+		return string(body[:diffSampleSize]) + "..."
+	}
+	return string(body)
+}
+
+// containsFold 忽略大小写判断切片是否包含指定字符串
+func containsFold(values []string, target string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"go-echo-app/internal/config"
+)
+
+// newMirroredUpstream 创建镜像到shadowURL的上游
+func newMirroredUpstream(t *testing.T, shadowURL string, mirror config.MirrorConfig) *Upstream {
+	t.Helper()
+	mirror.URL = shadowURL
+	r, err := NewRegistry([]config.UpstreamConfig{{Name: "orders", URL: "http://primary.example.com/api", Mirror: &mirror}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r.Get("orders")
+}
+
+// waitForStats 等待上游的镜像统计满足条件
+func waitForStats(t *testing.T, m *Mirror, upstream string, done func(MirrorStats) bool) MirrorStats {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats := m.Stats()[upstream]
+		if done(stats) {
+			return stats
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for mirror stats, got %+v", stats)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMirrorSampling(t *testing.T) {
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer shadow.Close()
+
+	tests := []struct {
+		name    string
+		percent *float64
+		methods []string
+		method  string
+		want    bool
+	}{
+		{name: "unset percent mirrors everything", percent: nil, method: http.MethodGet, want: true},
+		{name: "100 percent", percent: percent(100), method: http.MethodGet, want: true},
+		{name: "0 percent never mirrors", percent: percent(0), method: http.MethodGet, want: false},
+		{name: "method allowed", percent: nil, methods: []string{"get"}, method: http.MethodGet, want: true},
+		{name: "method not allowed", percent: nil, methods: []string{"GET"}, method: http.MethodPost, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMirror(http.DefaultClient, 100, 10)
+			upstream := newMirroredUpstream(t, shadow.URL, config.MirrorConfig{Percent: tt.percent, Methods: tt.methods})
+
+			req, _ := http.NewRequest(tt.method, "http://primary.example.com/api/orders", nil)
+			for i := 0; i < 20; i++ {
+				call := m.Start(upstream, req.Method, req.URL, req.Header, nil)
+				if (call != nil) != tt.want {
+					t.Fatalf("Start() mirrored = %v, want %v", call != nil, tt.want)
+				}
+				call.Complete(nil)
+			}
+			if tt.want {
+				waitForStats(t, m, "orders", func(s MirrorStats) bool { return s.Sent == 20 })
+			}
+		})
+	}
+}
+
+func TestMirrorRejectsPercentOutOfRange(t *testing.T) {
+	for _, p := range []float64{-1, 101} {
+		_, err := NewRegistry([]config.UpstreamConfig{{
+			Name:   "orders",
+			URL:    "http://primary.example.com",
+			Mirror: &config.MirrorConfig{URL: "http://shadow.example.com", Percent: percent(p)},
+		}})
+		if err == nil {
+			t.Errorf("percent %v: expected error", p)
+		}
+	}
+}
+
+func TestMirrorRecordsDiffs(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 1, "total": 20}`))
+	}))
+	defer shadow.Close()
+
+	m := NewMirror(http.DefaultClient, 1, 10)
+	upstream := newMirroredUpstream(t, shadow.URL+"/v2", config.MirrorConfig{RecordDiffs: true})
+
+	req, _ := http.NewRequest(http.MethodGet, "http://primary.example.com/api/orders?id=1", nil)
+	call := m.Start(upstream, req.Method, req.URL, req.Header, nil)
+	call.Complete(&Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       []byte(`{"total":10,"id":1}`),
+	})
+
+	got := <-received
+	if got.URL.Path != "/v2/orders" || got.URL.RawQuery != "id=1" || got.Header.Get("X-Proxy-Shadow") != "true" {
+		t.Fatalf("shadow request = %s?%s, shadow header %q", got.URL.Path, got.URL.RawQuery, got.Header.Get("X-Proxy-Shadow"))
+	}
+
+	stats := waitForStats(t, m, "orders", func(s MirrorStats) bool { return s.Matched+s.Mismatch == 1 })
+	if stats.Mismatch != 1 {
+		t.Fatalf("stats = %+v, want one mismatch", stats)
+	}
+	diffs := m.Diffs("orders")
+	if len(diffs) != 1 || !diffs[0].BodyDiffers || diffs[0].ShadowStatus != http.StatusOK {
+		t.Fatalf("diffs = %+v", diffs)
+	}
+	if len(m.Diffs("other")) != 0 {
+		t.Fatal("Diffs() did not filter by upstream")
+	}
+	m.ClearDiffs()
+	if len(m.Diffs("")) != 0 {
+		t.Fatal("ClearDiffs() kept records")
+	}
+}
+
+func TestMirrorDiffLimit(t *testing.T) {
+	tests := []struct {
+		limit int
+		added int
+		want  []string
+	}{
+		{limit: 0, added: 3, want: nil},
+		{limit: 2, added: 1, want: []string{"/0"}},
+		{limit: 2, added: 2, want: []string{"/0", "/1"}},
+		{limit: 2, added: 5, want: []string{"/3", "/4"}},
+	}
+
+	for _, tt := range tests {
+		m := NewMirror(http.DefaultClient, 1, tt.limit)
+		for i := 0; i < tt.added; i++ {
+			m.addDiff(MirrorDiff{URL: "/" + string(rune('0'+i))})
+		}
+
+		diffs := m.Diffs("")
+		if len(diffs) != len(tt.want) {
+			t.Fatalf("limit %d, added %d: kept %d diffs, want %d", tt.limit, tt.added, len(diffs), len(tt.want))
+		}
+		for i, d := range diffs {
+			if d.URL != tt.want[i] {
+				t.Errorf("limit %d, added %d: diff %d = %s, want %s", tt.limit, tt.added, i, d.URL, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestMirrorDropsOverConcurrency(t *testing.T) {
+	release := make(chan struct{})
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-release }))
+	defer shadow.Close()
+	defer close(release)
+
+	m := NewMirror(http.DefaultClient, 1, 10)
+	upstream := newMirroredUpstream(t, shadow.URL, config.MirrorConfig{})
+	req, _ := http.NewRequest(http.MethodGet, "http://primary.example.com/api/orders", nil)
+
+	first := m.Start(upstream, req.Method, req.URL, req.Header, nil)
+	second := m.Start(upstream, req.Method, req.URL, req.Header, nil)
+	if first == nil || second != nil {
+		t.Fatalf("Start() = %v, %v; want the second call dropped", first, second)
+	}
+	if dropped := m.Stats()["orders"].Dropped; dropped != 1 {
+		t.Fatalf("Dropped = %d, want 1", dropped)
+	}
+	first.Complete(nil)
+}
+
+func TestCompareResponses(t *testing.T) {
+	jsonResp := func(status int, body string) *Response {
+		return &Response{StatusCode: status, Header: http.Header{"Content-Type": {"application/json"}}, Body: []byte(body)}
+	}
+	location := jsonResp(http.StatusFound, "")
+	location.Header.Set("Location", "/elsewhere")
+
+	tests := []struct {
+		name    string
+		primary *Response
+		shadow  *Response
+		err     error
+		differs bool
+	}{
+		{name: "identical", primary: jsonResp(200, `{"a":1}`), shadow: jsonResp(200, `{"a":1}`), differs: false},
+		{name: "json field order ignored", primary: jsonResp(200, `{"a":1,"b":2}`), shadow: jsonResp(200, `{ "b": 2, "a": 1 }`), differs: false},
+		{name: "status differs", primary: jsonResp(200, `{}`), shadow: jsonResp(500, `{}`), differs: true},
+		{name: "body differs", primary: jsonResp(200, `{"a":1}`), shadow: jsonResp(200, `{"a":2}`), differs: true},
+		{name: "header differs", primary: jsonResp(http.StatusFound, ""), shadow: location, differs: true},
+		{name: "shadow error", primary: jsonResp(200, `{}`), err: errors.New("timeout"), differs: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, differs := compareResponses(tt.primary, tt.shadow, tt.err); differs != tt.differs {
+				t.Fatalf("compareResponses() differs = %v, want %v", differs, tt.differs)
+			}
+		})
+	}
+}
+
+func TestUpstreamRewriteForMirror(t *testing.T) {
+	upstream := newMirroredUpstream(t, "http://shadow.example.com/base/", config.MirrorConfig{})
+	target, _ := url.Parse("http://primary.example.com/api/orders/1?x=y")
+	if got := upstream.Rewrite(target, upstream.MirrorURL).String(); got != "http://shadow.example.com/base/orders/1?x=y" {
+		t.Fatalf("Rewrite() = %s", got)
+	}
+}
@@ -0,0 +1,130 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// Response 缓冲后的上游响应
+//
+// Body保持上游原始编码，需要检查或改写响应内容时应先调用Decode，
+// 写回客户端前再通过Negotiate按客户端的Accept-Encoding重新编码。
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// ReadResponse 读取并缓冲上游响应
+func ReadResponse(resp *http.Response) (*Response, error) {
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+	}, nil
+}
+
+// Clone 深拷贝响应，便于多个调用方各自修改
+func (r *Response) Clone() *Response {
+	body := make([]byte, len(r.Body))
+	copy(body, r.Body)
+	return &Response{
+		StatusCode: r.StatusCode,
+		Header:     r.Header.Clone(),
+		Body:       body,
+	}
+}
+
+// Encoding 返回响应当前的Content-Encoding
+func (r *Response) Encoding() string {
+	return r.Header.Get("Content-Encoding")
+}
+
+// DecodedBody 返回解码后的响应体，不修改响应本身
+func (r *Response) DecodedBody() ([]byte, error) {
+	return Decode(r.Encoding(), r.Body)
+}
+
+// Decode 将响应体就地解码为identity编码
+func (r *Response) Decode() error {
+	if len(parseContentEncoding(r.Encoding())) == 0 {
+		return nil
+	}
+
+	body, err := r.DecodedBody()
+	if err != nil {
+		return err
+	}
+	r.setBody(body, "")
+	return nil
+}
+
+// Negotiate 根据客户端Accept-Encoding调整响应编码：
+// 客户端接受上游编码时原样透传，否则解码后按协商结果重新压缩。
+// minSize为0时不对未压缩的响应做额外压缩。
+func (r *Response) Negotiate(acceptEncoding string, minSize int) error {
+	if !bodyAllowed(r.StatusCode) || len(r.Body) == 0 {
+		return nil
+	}
+	r.Header.Add("Vary", "Accept-Encoding")
+
+	current := r.Encoding()
+	if len(parseContentEncoding(current)) > 0 {
+		if AcceptsEncoding(acceptEncoding, current) {
+			return nil
+		}
+		if err := r.Decode(); err != nil {
+			return err
+		}
+	}
+
+	if minSize <= 0 || len(r.Body) < minSize || !Compressible(r.Header.Get("Content-Type")) {
+		return nil
+	}
+
+	encoding := NegotiateEncoding(acceptEncoding)
+	if encoding == "" {
+		return nil
+	}
+
+	body, err := Encode(encoding, r.Body)
+	if err != nil {
+		return err
+	}
+	r.setBody(body, encoding)
+	return nil
+}
+
+// setBody 替换响应体并同步相关响应头
+func (r *Response) setBody(body []byte, encoding string) {
+	r.Body = body
+	if encoding == "" {
+		r.Header.Del("Content-Encoding")
+	} else {
+		r.Header.Set("Content-Encoding", encoding)
+	}
+	r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	// 编码变化后原ETag不再对应当前字节，降级为弱校验
+	if etag := r.Header.Get("ETag"); etag != "" && len(etag) > 1 && etag[:2] != "W/" {
+		r.Header.Set("ETag", "W/"+etag)
+	}
+}
+
+// bodyAllowed 判断状态码是否允许携带响应体
+func bodyAllowed(status int) bool {
+	switch {
+	case status >= 100 && status < 200:
+		return false
+	case status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+)
+
+func TestResponseNegotiate(t *testing.T) {
+	plain := bytes.Repeat([]byte(`{"name":"value"},`), 100)
+	gzipped, err := Encode(EncodingGzip, plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		status       int
+		contentType  string
+		encoding     string
+		body         []byte
+		accept       string
+		minSize      int
+		wantEncoding string
+		wantVary     bool
+	}{
+		{
+			name: "upstream encoding accepted is passed through", status: http.StatusOK, contentType: "application/json",
+			encoding: EncodingGzip, body: gzipped, accept: "gzip", wantEncoding: EncodingGzip, wantVary: true,
+		},
+		{
+			name: "upstream encoding not accepted is decoded", status: http.StatusOK, contentType: "application/json",
+			encoding: EncodingGzip, body: gzipped, accept: "", wantEncoding: "", wantVary: true,
+		},
+		{
+			name: "upstream encoding recoded to the client's choice", status: http.StatusOK, contentType: "application/json",
+			encoding: EncodingGzip, body: gzipped, accept: "br", minSize: 1, wantEncoding: EncodingBrotli, wantVary: true,
+		},
+		{
+			name: "identity compressed at min size", status: http.StatusOK, contentType: "application/json",
+			body: plain, accept: "gzip", minSize: len(plain), wantEncoding: EncodingGzip, wantVary: true,
+		},
+		{
+			name: "identity below min size is left alone", status: http.StatusOK, contentType: "application/json",
+			body: plain, accept: "gzip", minSize: len(plain) + 1, wantEncoding: "", wantVary: true,
+		},
+		{
+			name: "min size 0 disables compression", status: http.StatusOK, contentType: "application/json",
+			body: plain, accept: "gzip", wantEncoding: "", wantVary: true,
+		},
+		{
+			name: "binary content is not compressed", status: http.StatusOK, contentType: "image/png",
+			body: plain, accept: "gzip", minSize: 1, wantEncoding: "", wantVary: true,
+		},
+		{
+			name: "no body for 304", status: http.StatusNotModified, contentType: "application/json",
+			encoding: EncodingGzip, body: gzipped, accept: "", wantEncoding: EncodingGzip, wantVary: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{"Content-Type": {tt.contentType}}
+			if tt.encoding != "" {
+				header.Set("Content-Encoding", tt.encoding)
+			}
+			resp := &Response{StatusCode: tt.status, Header: header, Body: tt.body}
+
+			if err := resp.Negotiate(tt.accept, tt.minSize); err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.Encoding(); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := resp.Header.Get("Vary") == "Accept-Encoding"; got != tt.wantVary {
+				t.Fatalf("Vary set = %v, want %v", got, tt.wantVary)
+			}
+			if !tt.wantVary {
+				return
+			}
+			decoded, err := resp.DecodedBody()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decoded, plain) {
+				t.Fatal("decoded body differs from the upstream body")
+			}
+		})
+	}
+}
+
+func TestResponseNegotiateWeakensETag(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), 100)
+	resp := &Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/plain"}, "Etag": {`"v1"`}},
+		Body:       body,
+	}
+	if err := resp.Negotiate("gzip", 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Header.Get("ETag"); got != `W/"v1"` {
+		t.Fatalf("ETag = %q, want weak", got)
+	}
+}
@@ -0,0 +1,218 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go-echo-app/internal/config"
+)
+
+// gatewayCredentialHeaders 网关自身认证使用的请求头，不转发给上游
+var gatewayCredentialHeaders = []string{"Authorization", "X-API-Key"}
+
+// StripGatewayCredentials 删除网关认证使用的请求头，调用方已通过网关认证时使用
+func StripGatewayCredentials(header http.Header) {
+	for _, name := range gatewayCredentialHeaders {
+		header.Del(name)
+	}
+}
+
+// setHeaders 将配置的请求头写入header，覆盖同名请求头
+func setHeaders(header http.Header, values map[string]string) {
+	for name, value := range values {
+		header.Set(name, value)
+	}
+}
+
+// Upstream 已解析的上游服务
+type Upstream struct {
+	Name      string
+	URL       *url.URL
+	MirrorURL *url.URL
+	Variants  []*Variant
+	Config    config.UpstreamConfig
+}
+
+// Variant 上游的一个灰度版本
+type Variant struct {
+	Name   string
+	URL    *url.URL
+	Weight int
+}
+
+// Registry 上游服务注册表，按URL前缀匹配中转目标
+type Registry struct {
+	upstreams []*Upstream
+	byName    map[string]*Upstream
+}
+
+// NewRegistry 根据配置创建上游注册表
+func NewRegistry(cfgs []config.UpstreamConfig) (*Registry, error) {
+	r := &Registry{byName: make(map[string]*Upstream)}
+
+	for _, cfg := range cfgs {
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("upstream with url %q has no name", cfg.URL)
+		}
+		if _, exists := r.byName[cfg.Name]; exists {
+			return nil, fmt.Errorf("duplicate upstream %q", cfg.Name)
+		}
+
+		u, err := parseBaseURL(cfg.URL)
+		if err != nil {
+			return nil, fmt.Errorf("upstream %q has invalid url %q", cfg.Name, cfg.URL)
+		}
+		if cfg.Compression.RequestEncoding != "" && !SupportedEncoding(cfg.Compression.RequestEncoding) {
+			return nil, fmt.Errorf("upstream %q has unsupported request encoding %q", cfg.Name, cfg.Compression.RequestEncoding)
+		}
+
+		upstream := &Upstream{Name: cfg.Name, URL: u, Config: cfg}
+		if cfg.Mirror != nil {
+			if upstream.MirrorURL, err = parseBaseURL(cfg.Mirror.URL); err != nil {
+				return nil, fmt.Errorf("upstream %q has invalid mirror url %q", cfg.Name, cfg.Mirror.URL)
+			}
+			if p := cfg.Mirror.Percent; p != nil && (*p < 0 || *p > 100) {
+				return nil, fmt.Errorf("upstream %q has mirror percent outside 0-100", cfg.Name)
+			}
+		}
+		if cfg.Canary != nil {
+			if upstream.Variants, err = parseVariants(cfg.Canary.Variants); err != nil {
+				return nil, fmt.Errorf("upstream %q: %w", cfg.Name, err)
+			}
+		}
+
+		r.upstreams = append(r.upstreams, upstream)
+		r.byName[cfg.Name] = upstream
+	}
+
+	return r, nil
+}
+
+// Get 按名称获取上游
+func (r *Registry) Get(name string) *Upstream {
+	return r.byName[name]
+}
+
+// All 返回所有上游
+func (r *Registry) All() []*Upstream {
+	return r.upstreams
+}
+
+// Match 返回与目标URL前缀匹配最长的上游，未匹配时返回nil
+func (r *Registry) Match(target *url.URL) *Upstream {
+	var best *Upstream
+	for _, upstream := range r.upstreams {
+		if !upstream.Matches(target) {
+			continue
+		}
+		if best == nil || len(upstream.URL.Path) > len(best.URL.Path) {
+			best = upstream
+		}
+	}
+	return best
+}
+
+// Matches 判断目标URL是否属于该上游
+func (u *Upstream) Matches(target *url.URL) bool {
+	if !strings.EqualFold(u.URL.Scheme, target.Scheme) || !strings.EqualFold(u.URL.Host, target.Host) {
+		return false
+	}
+
+	prefix := strings.TrimSuffix(u.URL.Path, "/")
+	if prefix == "" {
+		return true
+	}
+	return target.Path == prefix || strings.HasPrefix(target.Path, prefix+"/")
+}
+
+// SetHeaders 写入该上游配置的请求头
+func (u *Upstream) SetHeaders(header http.Header) {
+	setHeaders(header, u.Config.Headers)
+}
+
+// setsHeader 判断上游配置是否设置了该请求头
+func (u *Upstream) setsHeader(name string) bool {
+	for configured := range u.Config.Headers {
+		if strings.EqualFold(configured, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rewrite 将属于该上游的目标URL前缀替换为base，用于镜像或切换版本
+func (u *Upstream) Rewrite(target *url.URL, base *url.URL) *url.URL {
+	rewritten := *target
+	rewritten.Scheme = base.Scheme
+	rewritten.Host = base.Host
+	rewritten.User = base.User
+
+	rest := strings.TrimPrefix(target.Path, strings.TrimSuffix(u.URL.Path, "/"))
+	rewritten.Path = strings.TrimSuffix(base.Path, "/") + rest
+	rewritten.RawPath = ""
+	return &rewritten
+}
+
+// CompressRequestBody 按上游配置压缩请求体，并同步Content-Encoding请求头
+func (u *Upstream) CompressRequestBody(header http.Header, body []byte) ([]byte, error) {
+	cfg := u.Config.Compression
+	if cfg.RequestEncoding == "" || len(body) == 0 || len(body) < cfg.MinSize {
+		return body, nil
+	}
+	// 客户端已自行编码的请求体保持不变
+	if header.Get("Content-Encoding") != "" {
+		return body, nil
+	}
+
+	encoded, err := Encode(cfg.RequestEncoding, body)
+	if err != nil {
+		return nil, err
+	}
+	header.Set("Content-Encoding", normalizeEncoding(cfg.RequestEncoding))
+	return encoded, nil
+}
+
+// parseVariants 解析并校验灰度版本
+func parseVariants(cfgs []config.VariantConfig) ([]*Variant, error) {
+	if len(cfgs) == 0 {
+		return nil, fmt.Errorf("canary requires at least one variant")
+	}
+
+	variants := make([]*Variant, 0, len(cfgs))
+	names := make(map[string]bool)
+	total := 0
+	for _, cfg := range cfgs {
+		if cfg.Name == "" || names[cfg.Name] {
+			return nil, fmt.Errorf("canary variant name %q is empty or duplicated", cfg.Name)
+		}
+		names[cfg.Name] = true
+
+		u, err := parseBaseURL(cfg.URL)
+		if err != nil {
+			return nil, fmt.Errorf("canary variant %q has invalid url %q", cfg.Name, cfg.URL)
+		}
+		if cfg.Weight < 0 {
+			return nil, fmt.Errorf("canary variant %q has negative weight", cfg.Name)
+		}
+		total += cfg.Weight
+		variants = append(variants, &Variant{Name: cfg.Name, URL: u, Weight: cfg.Weight})
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("canary variants must have a positive total weight")
+	}
+	return variants, nil
+}
+
+// parseBaseURL 解析上游基础URL，要求包含scheme和host
+func parseBaseURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("url %q must be absolute", raw)
+	}
+	return u, nil
+}
@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"go-echo-app/internal/config"
+)
+
+func TestRegistryMatch(t *testing.T) {
+	r, err := NewRegistry([]config.UpstreamConfig{
+		{Name: "api", URL: "https://api.example.com"},
+		{Name: "orders", URL: "https://api.example.com/orders/"},
+		{Name: "plain", URL: "http://plain.example.com/v1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{target: "https://api.example.com/users", want: "api"},
+		{target: "https://API.example.com/orders", want: "orders"},
+		{target: "https://api.example.com/orders/1", want: "orders"},
+		{target: "https://api.example.com/ordersx", want: "api"},
+		{target: "http://api.example.com/users", want: ""},
+		{target: "http://plain.example.com/v1/x", want: "plain"},
+		{target: "http://plain.example.com/v2", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			u, _ := url.Parse(tt.target)
+			got := ""
+			if upstream := r.Match(u); upstream != nil {
+				got = upstream.Name
+			}
+			if got != tt.want {
+				t.Fatalf("Match(%s) = %q, want %q", tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRegistryValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		cfgs []config.UpstreamConfig
+	}{
+		{name: "missing name", cfgs: []config.UpstreamConfig{{URL: "https://a.example.com"}}},
+		{name: "relative url", cfgs: []config.UpstreamConfig{{Name: "a", URL: "/a"}}},
+		{name: "duplicate name", cfgs: []config.UpstreamConfig{{Name: "a", URL: "https://a.example.com"}, {Name: "a", URL: "https://b.example.com"}}},
+		{name: "unsupported encoding", cfgs: []config.UpstreamConfig{{Name: "a", URL: "https://a.example.com", Compression: config.CompressionConfig{RequestEncoding: "zstd"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRegistry(tt.cfgs); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestCompressRequestBody(t *testing.T) {
+	upstream := &Upstream{Config: config.UpstreamConfig{Compression: config.CompressionConfig{RequestEncoding: "gzip", MinSize: 10}}}
+
+	tests := []struct {
+		name         string
+		body         string
+		clientCoding string
+		wantEncoding string
+	}{
+		{name: "below min size", body: "short", wantEncoding: ""},
+		{name: "at min size", body: "0123456789", wantEncoding: EncodingGzip},
+		{name: "already encoded by the client", body: "0123456789", clientCoding: EncodingDeflate, wantEncoding: EncodingDeflate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.clientCoding != "" {
+				header.Set("Content-Encoding", tt.clientCoding)
+			}
+			body, err := upstream.CompressRequestBody(header, []byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := header.Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if tt.wantEncoding == EncodingGzip {
+				decoded, err := Decode(EncodingGzip, body)
+				if err != nil || string(decoded) != tt.body {
+					t.Fatalf("decoded body = %q, %v", decoded, err)
+				}
+			} else if string(body) != tt.body {
+				t.Fatalf("body = %q, want unchanged", body)
+			}
+		})
+	}
+}
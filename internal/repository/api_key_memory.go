@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go-echo-app/internal/models"
+)
+
+// MemoryAPIKeyRepository 基于内存的API Key仓库
+type MemoryAPIKeyRepository struct {
+	mu       sync.RWMutex
+	keys     map[string]*models.APIKey
+	byPrefix map[string]string // 前缀 -> ID
+}
+
+// NewMemoryAPIKeyRepository 创建内存API Key仓库
+func NewMemoryAPIKeyRepository() *MemoryAPIKeyRepository {
+	return &MemoryAPIKeyRepository{
+		keys:     make(map[string]*models.APIKey),
+		byPrefix: make(map[string]string),
+	}
+}
+
+// Create 实现models.APIKeyRepository
+func (r *MemoryAPIKeyRepository) Create(_ context.Context, key *models.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys[key.ID] = copyAPIKey(key)
+	r.byPrefix[key.Prefix] = key.ID
+	return nil
+}
+
+// GetByID 实现models.APIKeyRepository
+func (r *MemoryAPIKeyRepository) GetByID(_ context.Context, id string) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyAPIKey(key), nil
+}
+
+// GetByPrefix 实现models.APIKeyRepository
+func (r *MemoryAPIKeyRepository) GetByPrefix(_ context.Context, prefix string) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.byPrefix[prefix]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyAPIKey(r.keys[id]), nil
+}
+
+// ListByOwner 实现models.APIKeyRepository，按创建时间排序
+func (r *MemoryAPIKeyRepository) ListByOwner(_ context.Context, ownerID string) ([]*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]*models.APIKey, 0)
+	for _, key := range r.keys {
+		if key.OwnerID == ownerID {
+			keys = append(keys, copyAPIKey(key))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+// Revoke 实现models.APIKeyRepository
+func (r *MemoryAPIKeyRepository) Revoke(_ context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if key.RevokedAt == nil {
+		key.RevokedAt = &at
+	}
+	return nil
+}
+
+// TouchLastUsed 实现models.APIKeyRepository
+func (r *MemoryAPIKeyRepository) TouchLastUsed(_ context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	key.LastUsedAt = &at
+	return nil
+}
+
+// copyAPIKey 复制记录，避免调用方修改仓库内的数据
+func copyAPIKey(key *models.APIKey) *models.APIKey {
+	k := *key
+	k.Scopes = append([]string(nil), key.Scopes...)
+	return &k
+}
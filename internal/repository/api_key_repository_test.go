@@ -0,0 +1,140 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"go-echo-app/internal/models"
+	"go-echo-app/internal/repository"
+)
+
+// apiKeyRepoFactory 创建空的API Key仓库及其所属用户的仓库
+type apiKeyRepoFactory struct {
+	name string
+	open func(t *testing.T) (models.APIKeyRepository, models.UserRepository)
+}
+
+// apiKeyRepositories 返回参与测试的仓库：内存、SQLite，以及设置了TEST_POSTGRES_DSN时的Postgres
+func apiKeyRepositories() []apiKeyRepoFactory {
+	factories := []apiKeyRepoFactory{
+		{name: "memory", open: func(*testing.T) (models.APIKeyRepository, models.UserRepository) {
+			return repository.NewMemoryAPIKeyRepository(), repository.NewMemoryUserRepository()
+		}},
+		{name: "sqlite", open: func(t *testing.T) (models.APIKeyRepository, models.UserRepository) {
+			db := openSQLiteDB(t)
+			return repository.NewSQLiteAPIKeyRepository(db), repository.NewSQLiteUserRepository(db)
+		}},
+	}
+	if os.Getenv(postgresDSNEnv) != "" {
+		factories = append(factories, apiKeyRepoFactory{name: "postgres", open: func(t *testing.T) (models.APIKeyRepository, models.UserRepository) {
+			db := openPostgresDB(t)
+			return repository.NewPostgresAPIKeyRepository(db), repository.NewPostgresUserRepository(db)
+		}})
+	}
+	return factories
+}
+
+func newAPIKey(id, prefix, ownerID string, offset time.Duration) *models.APIKey {
+	return &models.APIKey{
+		ID:        id,
+		Prefix:    prefix,
+		Hash:      "hash-" + id,
+		Name:      "key " + id,
+		OwnerID:   ownerID,
+		Scopes:    []string{"proxy:use"},
+		CreatedAt: baseTime.Add(offset),
+	}
+}
+
+func TestAPIKeyRepository(t *testing.T) {
+	ctx := context.Background()
+	for _, f := range apiKeyRepositories() {
+		t.Run(f.name, func(t *testing.T) {
+			keys, users := f.open(t)
+			mustCreate(t, users, newUser("u1", "Ann", "ann@example.com", 0), newUser("u2", "Bob", "bob@example.com", 0))
+
+			expires := baseTime.Add(24 * time.Hour)
+			k1 := newAPIKey("k1", "aaaaaaaaaaaa", "u1", time.Second)
+			k1.ExpiresAt = &expires
+			k2 := newAPIKey("k2", "bbbbbbbbbbbb", "u1", 0)
+			k2.Scopes = nil
+			k3 := newAPIKey("k3", "cccccccccccc", "u2", 0)
+			for _, key := range []*models.APIKey{k1, k2, k3} {
+				if err := keys.Create(ctx, key); err != nil {
+					t.Fatalf("Create(%s): %v", key.ID, err)
+				}
+			}
+
+			got, err := keys.GetByPrefix(ctx, "aaaaaaaaaaaa")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != "k1" || got.Hash != "hash-k1" || !reflect.DeepEqual(got.Scopes, []string{"proxy:use"}) ||
+				got.ExpiresAt == nil || !got.ExpiresAt.Equal(expires) || !got.CreatedAt.Equal(k1.CreatedAt) || got.RevokedAt != nil {
+				t.Fatalf("GetByPrefix() = %+v", got)
+			}
+			if _, err := keys.GetByPrefix(ctx, "dddddddddddd"); !errors.Is(err, repository.ErrNotFound) {
+				t.Fatalf("GetByPrefix() of unknown prefix error = %v, want ErrNotFound", err)
+			}
+
+			list, err := keys.ListByOwner(ctx, "u1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != 2 || list[0].ID != "k2" || list[1].ID != "k1" || len(list[0].Scopes) != 0 {
+				t.Fatalf("ListByOwner(u1) = %+v, want k2,k1", list)
+			}
+
+			used := baseTime.Add(time.Hour)
+			if err := keys.TouchLastUsed(ctx, "k1", used); err != nil {
+				t.Fatal(err)
+			}
+			revoked := baseTime.Add(2 * time.Hour)
+			if err := keys.Revoke(ctx, "k1", revoked); err != nil {
+				t.Fatal(err)
+			}
+			if err := keys.Revoke(ctx, "k1", revoked.Add(time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+			if got, err = keys.GetByID(ctx, "k1"); err != nil || got.LastUsedAt == nil || !got.LastUsedAt.Equal(used) ||
+				got.RevokedAt == nil || !got.RevokedAt.Equal(revoked) {
+				t.Fatalf("GetByID() after Revoke = %+v, %v; want first revocation time kept", got, err)
+			}
+			if err := keys.Revoke(ctx, "missing", revoked); !errors.Is(err, repository.ErrNotFound) {
+				t.Fatalf("Revoke() of unknown key error = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+// TestSQLAPIKeyRepositoryOwnerPurged 永久删除用户时其API Key一并删除
+func TestSQLAPIKeyRepositoryOwnerPurged(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLiteDB(t)
+	keys, users := repository.NewSQLiteAPIKeyRepository(db), repository.NewSQLiteUserRepository(db)
+
+	user := newUser("u1", "Ann", "ann@example.com", 0)
+	mustCreate(t, users, user)
+	if err := keys.Create(ctx, newAPIKey("k1", "aaaaaaaaaaaa", "u1", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.Create(ctx, newAPIKey("k2", "bbbbbbbbbbbb", "ghost", 0)); err == nil {
+		t.Fatal("Create() for unknown owner succeeded")
+	}
+
+	deletedAt := baseTime
+	user.DeletedAt = &deletedAt
+	if err := users.Update(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.Purge(ctx, deletedAt.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keys.GetByID(ctx, "k1"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("GetByID() after owner purge error = %v, want ErrNotFound", err)
+	}
+}
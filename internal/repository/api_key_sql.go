@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-echo-app/internal/models"
+)
+
+// apiKeyColumns 查询API Key时读取的列，顺序与scanAPIKey一致
+const apiKeyColumns = "id, prefix, hash, name, owner_id, scopes, expires_at, last_used_at, revoked_at, created_at"
+
+// SQLAPIKeyRepository 基于database/sql的API Key仓库，SQL差异由Dialect处理
+type SQLAPIKeyRepository struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// NewSQLAPIKeyRepository 创建SQL API Key仓库
+func NewSQLAPIKeyRepository(db *sql.DB, dialect Dialect) *SQLAPIKeyRepository {
+	return &SQLAPIKeyRepository{db: db, dialect: dialect}
+}
+
+// NewPostgresAPIKeyRepository 创建Postgres API Key仓库
+func NewPostgresAPIKeyRepository(db *sql.DB) *SQLAPIKeyRepository {
+	return NewSQLAPIKeyRepository(db, PostgresDialect{})
+}
+
+// NewSQLiteAPIKeyRepository 创建SQLite API Key仓库
+func NewSQLiteAPIKeyRepository(db *sql.DB) *SQLAPIKeyRepository {
+	return NewSQLAPIKeyRepository(db, SQLiteDialect{})
+}
+
+// Create 实现models.APIKeyRepository
+func (r *SQLAPIKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	scopes, err := encodeRoles(key.Scopes)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("INSERT INTO api_keys (%s) VALUES (%s)", apiKeyColumns, placeholderList(r.dialect, 1, 10))
+	_, err = r.db.ExecContext(ctx, query,
+		key.ID, key.Prefix, key.Hash, key.Name, key.OwnerID, scopes, utcPtr(key.ExpiresAt), utcPtr(key.LastUsedAt), utcPtr(key.RevokedAt), key.CreatedAt.UTC())
+	return err
+}
+
+// GetByID 实现models.APIKeyRepository
+func (r *SQLAPIKeyRepository) GetByID(ctx context.Context, id string) (*models.APIKey, error) {
+	query := fmt.Sprintf("SELECT %s FROM api_keys WHERE id = %s", apiKeyColumns, r.dialect.Placeholder(1))
+	return r.queryAPIKey(ctx, query, id)
+}
+
+// GetByPrefix 实现models.APIKeyRepository，使用唯一索引api_keys_prefix_key
+func (r *SQLAPIKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	query := fmt.Sprintf("SELECT %s FROM api_keys WHERE prefix = %s", apiKeyColumns, r.dialect.Placeholder(1))
+	return r.queryAPIKey(ctx, query, prefix)
+}
+
+// ListByOwner 实现models.APIKeyRepository，按创建时间排序
+func (r *SQLAPIKeyRepository) ListByOwner(ctx context.Context, ownerID string) ([]*models.APIKey, error) {
+	query := fmt.Sprintf("SELECT %s FROM api_keys WHERE owner_id = %s ORDER BY created_at, id", apiKeyColumns, r.dialect.Placeholder(1))
+	rows, err := r.db.QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]*models.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke 实现models.APIKeyRepository，已吊销的Key保留原吊销时间
+func (r *SQLAPIKeyRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	query := fmt.Sprintf("UPDATE api_keys SET revoked_at = COALESCE(revoked_at, %s) WHERE id = %s", r.dialect.Placeholder(1), r.dialect.Placeholder(2))
+	return r.exec(ctx, query, at.UTC(), id)
+}
+
+// TouchLastUsed 实现models.APIKeyRepository
+func (r *SQLAPIKeyRepository) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	query := fmt.Sprintf("UPDATE api_keys SET last_used_at = %s WHERE id = %s", r.dialect.Placeholder(1), r.dialect.Placeholder(2))
+	return r.exec(ctx, query, at.UTC(), id)
+}
+
+// exec 执行按ID的更新，未影响任何行时返回ErrNotFound
+func (r *SQLAPIKeyRepository) exec(ctx context.Context, query string, args ...interface{}) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// queryAPIKey 查询单个API Key
+func (r *SQLAPIKeyRepository) queryAPIKey(ctx context.Context, query string, args ...interface{}) (*models.APIKey, error) {
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return key, err
+}
+
+// scanAPIKey 读取一行API Key数据
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var (
+		key    models.APIKey
+		scopes string
+	)
+	if err := row.Scan(&key.ID, &key.Prefix, &key.Hash, &key.Name, &key.OwnerID, &scopes, &key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return nil, fmt.Errorf("decode scopes of api key %s: %w", key.ID, err)
+	}
+	// Postgres的TIMESTAMPTZ按会话时区返回，统一转换为UTC
+	key.CreatedAt = key.CreatedAt.UTC()
+	key.ExpiresAt = utcPtr(key.ExpiresAt)
+	key.LastUsedAt = utcPtr(key.LastUsedAt)
+	key.RevokedAt = utcPtr(key.RevokedAt)
+	return &key, nil
+}
+
+// utcPtr 返回转换为UTC的时间，nil保持为nil
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
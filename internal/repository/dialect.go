@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Dialect SQL方言差异
+type Dialect interface {
+	// Placeholder 返回第n个（从1开始）参数占位符
+	Placeholder(n int) string
+	// IsUniqueViolation 判断错误是否为唯一约束冲突
+	IsUniqueViolation(err error) bool
+	// SearchQuery 返回search_text包含全部词的未删除用户查询及其参数，读取columns列，
+	// 按相关度排序后取前limit个
+	SearchQuery(columns string, terms []string, limit int) (string, []interface{})
+}
+
+// PostgresDialect Postgres方言
+type PostgresDialect struct{}
+
+// Placeholder 实现Dialect
+func (PostgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// IsUniqueViolation 实现Dialect
+func (PostgresDialect) IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// SearchQuery 实现Dialect，pg_trgm的GIN索引加速LIKE，在LIMIT之前按与查询词的相似度排序
+func (d PostgresDialect) SearchQuery(columns string, terms []string, limit int) (string, []interface{}) {
+	n := len(terms)
+	query := fmt.Sprintf("SELECT %s FROM users WHERE deleted_at IS NULL AND %s ORDER BY similarity(search_text, %s) DESC, id LIMIT %s",
+		columns, likeAll(d, "search_text", n), d.Placeholder(n+1), d.Placeholder(n+2))
+	return query, append(likeArgs(terms), strings.Join(terms, " "), limit)
+}
+
+// SQLiteDialect SQLite方言
+type SQLiteDialect struct{}
+
+// Placeholder 实现Dialect
+func (SQLiteDialect) Placeholder(n int) string {
+	return "?" + strconv.Itoa(n)
+}
+
+// IsUniqueViolation 实现Dialect
+func (SQLiteDialect) IsUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+// SearchQuery 实现Dialect，在FTS5 trigram索引users_search上匹配，在LIMIT之前按bm25排序。
+// trigram只能以MATCH检索至少三个字符的词，较短的词只用LIKE过滤；全部词都较短时无法计算bm25，
+// 改为优先返回检索文本较短（查询词占比较高）的用户
+func (d SQLiteDialect) SearchQuery(columns string, terms []string, limit int) (string, []interface{}) {
+	n := len(terms)
+	args := likeArgs(terms)
+	var phrases []string
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) >= 3 {
+			phrases = append(phrases, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+		}
+	}
+	if len(phrases) == 0 {
+		query := fmt.Sprintf("SELECT %s FROM users WHERE deleted_at IS NULL AND search_rowid IN (SELECT rowid FROM users_search WHERE %s) ORDER BY length(search_text), id LIMIT %s",
+			columns, likeAll(d, "search_text", n), d.Placeholder(n+1))
+		return query, append(args, limit)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM users JOIN users_search ON users_search.rowid = users.search_rowid WHERE users.deleted_at IS NULL AND users_search MATCH %s AND %s ORDER BY bm25(users_search), users.id LIMIT %s",
+		columns, d.Placeholder(n+1), likeAll(d, "users_search.search_text", n), d.Placeholder(n+2))
+	return query, append(args, strings.Join(phrases, " AND "), limit)
+}
+
+// likeArgs 返回各个词的LIKE模式参数
+func likeArgs(terms []string) []interface{} {
+	args := make([]interface{}, 0, len(terms)+2)
+	for _, term := range terms {
+		args = append(args, likePattern(term))
+	}
+	return args
+}
+
+// likeAll 返回column匹配前n个LIKE模式参数的条件
+func likeAll(d Dialect, column string, n int) string {
+	conditions := make([]string, n)
+	for i := range conditions {
+		conditions[i] = column + " LIKE " + d.Placeholder(i+1)
+	}
+	return strings.Join(conditions, " AND ")
+}
@@ -0,0 +1,11 @@
+package repository
+
+import "errors"
+
+// 仓库错误
+var (
+	ErrNotFound          = errors.New("record not found")
+	ErrDuplicateEmail    = errors.New("email already exists")
+	ErrDuplicateIdentity = errors.New("external identity is already linked to another user")
+	ErrVersionConflict   = errors.New("version conflict")
+)
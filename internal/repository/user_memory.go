@@ -0,0 +1,385 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-echo-app/internal/models"
+	"go-echo-app/internal/search"
+)
+
+// MemoryUserRepository 基于内存的用户仓库，并发安全，适用于本地开发
+type MemoryUserRepository struct {
+	mu      sync.RWMutex
+	users   map[string]*models.User
+	byEmail map[string]string              // 小写邮箱 -> 未删除的用户ID，已删除的用户不占用邮箱
+	byIdent map[string]string              // 外部身份（iss、sub） -> 用户ID
+	docs    map[string]string              // 用户ID -> 检索文本
+	grams   map[string]map[string]struct{} // 三字符片段 -> 用户ID集合（倒排索引）
+}
+
+// NewMemoryUserRepository 创建内存用户仓库
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users:   make(map[string]*models.User),
+		byEmail: make(map[string]string),
+		byIdent: make(map[string]string),
+		docs:    make(map[string]string),
+		grams:   make(map[string]map[string]struct{}),
+	}
+}
+
+// Create 实现models.UserRepository，新用户的版本为1
+func (r *MemoryUserRepository) Create(_ context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	email, active := normalizeEmail(user.Email), user.DeletedAt == nil
+	if _, ok := r.byEmail[email]; active && ok {
+		return ErrDuplicateEmail
+	}
+	ident, linked := identityKey(user)
+	if _, ok := r.byIdent[ident]; linked && ok {
+		return ErrDuplicateIdentity
+	}
+
+	user.Version = 1
+	r.users[user.ID] = copyUser(user)
+	if active {
+		r.byEmail[email] = user.ID
+	}
+	if linked {
+		r.byIdent[ident] = user.ID
+	}
+	r.index(user)
+	return nil
+}
+
+// GetByID 实现models.UserRepository
+func (r *MemoryUserRepository) GetByID(_ context.Context, id string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	return copyUser(user), nil
+}
+
+// GetByIDIncludeDeleted 实现models.UserRepository
+func (r *MemoryUserRepository) GetByIDIncludeDeleted(_ context.Context, id string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyUser(user), nil
+}
+
+// GetByEmail 实现models.UserRepository，邮箱比较忽略大小写
+func (r *MemoryUserRepository) GetByEmail(_ context.Context, email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.byEmail[normalizeEmail(email)]
+	if !ok || r.users[id].DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	return copyUser(r.users[id]), nil
+}
+
+// GetByIdentity 实现models.UserRepository，包括已软删除的用户
+func (r *MemoryUserRepository) GetByIdentity(_ context.Context, issuer, subject string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.byIdent[issuer+"\x00"+subject]
+	if subject == "" || !ok {
+		return nil, ErrNotFound
+	}
+	return copyUser(r.users[id]), nil
+}
+
+// GetAll 实现models.UserRepository，按创建时间排序
+func (r *MemoryUserRepository) GetAll(_ context.Context) ([]*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]*models.User, 0, len(r.users))
+	for _, user := range r.users {
+		if user.DeletedAt == nil {
+			users = append(users, copyUser(user))
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].ID < users[j].ID
+		}
+		return users[i].CreatedAt.Before(users[j].CreatedAt)
+	})
+	return users, nil
+}
+
+// List 实现models.UserRepository，在全部用户上过滤、排序后分页
+func (r *MemoryUserRepository) List(_ context.Context, q *models.UserQuery) (*models.UserList, error) {
+	column, ok := sortColumn(q.SortBy)
+	if !ok {
+		return nil, fmt.Errorf("unsupported sort field %q", q.SortBy)
+	}
+
+	r.mu.RLock()
+	users := make([]*models.User, 0, len(r.users))
+	for _, user := range r.users {
+		if matchesUserFilter(user, q) {
+			users = append(users, copyUser(user))
+		}
+	}
+	r.mu.RUnlock()
+
+	// direction为1表示升序，-1表示降序
+	direction := 1
+	if q.Desc {
+		direction = -1
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return direction*compareUser(users[i], column, sortKey(users[j], column), users[j].ID) < 0
+	})
+	total := len(users)
+
+	switch {
+	case q.After != nil:
+		value, err := cursorValue(column, q.After)
+		if err != nil {
+			return nil, err
+		}
+		i := sort.Search(len(users), func(i int) bool {
+			return direction*compareUser(users[i], column, value, q.After.ID) > 0
+		})
+		users = users[i:]
+	case q.Before != nil:
+		value, err := cursorValue(column, q.Before)
+		if err != nil {
+			return nil, err
+		}
+		i := sort.Search(len(users), func(i int) bool {
+			return direction*compareUser(users[i], column, value, q.Before.ID) >= 0
+		})
+		users = users[:i]
+		hasMore := len(users) > q.Limit
+		if hasMore {
+			users = users[len(users)-q.Limit:]
+		}
+		return &models.UserList{Users: users, Total: total, HasMore: hasMore}, nil
+	default:
+		if q.Offset >= len(users) {
+			users = users[:0]
+		} else {
+			users = users[q.Offset:]
+		}
+	}
+
+	hasMore := len(users) > q.Limit
+	if hasMore {
+		users = users[:q.Limit]
+	}
+	return &models.UserList{Users: users, Total: total, HasMore: hasMore}, nil
+}
+
+// Update 实现models.UserRepository，只在版本一致时更新，成功后版本加一
+func (r *MemoryUserRepository) Update(_ context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.users[user.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if existing.Version != user.Version {
+		return ErrVersionConflict
+	}
+
+	// 只有未删除的用户占用邮箱，恢复用户时同样需要检查
+	oldEmail, newEmail := normalizeEmail(existing.Email), normalizeEmail(user.Email)
+	wasActive, active := existing.DeletedAt == nil, user.DeletedAt == nil
+	if id, taken := r.byEmail[newEmail]; active && taken && id != user.ID {
+		return ErrDuplicateEmail
+	}
+	oldIdent, wasLinked := identityKey(existing)
+	newIdent, linked := identityKey(user)
+	if _, taken := r.byIdent[newIdent]; oldIdent != newIdent && linked && taken {
+		return ErrDuplicateIdentity
+	}
+
+	// 两项检查都通过后再更新索引，避免失败时留下半更新的状态
+	if wasActive {
+		delete(r.byEmail, oldEmail)
+	}
+	if active {
+		r.byEmail[newEmail] = user.ID
+	}
+	if wasLinked {
+		delete(r.byIdent, oldIdent)
+	}
+	if linked {
+		r.byIdent[newIdent] = user.ID
+	}
+
+	user.Version++
+	r.users[user.ID] = copyUser(user)
+	r.unindex(user.ID)
+	r.index(user)
+	return nil
+}
+
+// Purge 实现models.UserRepository
+func (r *MemoryUserRepository) Purge(_ context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := 0
+	for id, user := range r.users {
+		if user.DeletedAt == nil || !user.DeletedAt.Before(before) {
+			continue
+		}
+		if ident, linked := identityKey(user); linked {
+			delete(r.byIdent, ident)
+		}
+		delete(r.users, id)
+		r.unindex(id)
+		purged++
+	}
+	return purged, nil
+}
+
+// Search 实现models.UserRepository。包含至少三个字符的词时通过倒排索引取候选用户，
+// 再确认用户未删除且检索文本包含全部词；对全部匹配的用户计算与服务层相同的相关度后再截取，
+// 得分相同时按姓名、ID排序以保证稳定
+func (r *MemoryUserRepository) Search(_ context.Context, terms []string, limit int) ([]*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var candidates map[string]struct{}
+	for _, term := range terms {
+		for _, gram := range search.Trigrams(term) {
+			candidates = intersect(candidates, r.grams[gram])
+		}
+	}
+
+	type scored struct {
+		user  *models.User
+		score float64
+	}
+	matches := make([]scored, 0)
+	match := func(id string) {
+		user := r.users[id]
+		if user.DeletedAt != nil {
+			return
+		}
+		doc := r.docs[id]
+		for _, term := range terms {
+			if !strings.Contains(doc, term) {
+				return
+			}
+		}
+		score, _, _ := search.Rank(search.UserFields(user.Name, user.Email, user.Phone), terms)
+		matches = append(matches, scored{user: user, score: score})
+	}
+	if candidates != nil {
+		for id := range candidates {
+			match(id)
+		}
+	} else if len(terms) > 0 {
+		for id := range r.docs {
+			match(id)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.user.Name != b.user.Name {
+			return a.user.Name < b.user.Name
+		}
+		return a.user.ID < b.user.ID
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	users := make([]*models.User, 0, len(matches))
+	for _, m := range matches {
+		users = append(users, copyUser(m.user))
+	}
+	return users, nil
+}
+
+// index 将用户加入倒排索引，调用方需持有写锁
+func (r *MemoryUserRepository) index(user *models.User) {
+	doc := search.Document(user.Name, user.Email, user.Phone)
+	r.docs[user.ID] = doc
+	for _, gram := range search.Trigrams(doc) {
+		ids, ok := r.grams[gram]
+		if !ok {
+			ids = make(map[string]struct{})
+			r.grams[gram] = ids
+		}
+		ids[user.ID] = struct{}{}
+	}
+}
+
+// unindex 将用户移出倒排索引，调用方需持有写锁
+func (r *MemoryUserRepository) unindex(id string) {
+	for _, gram := range search.Trigrams(r.docs[id]) {
+		delete(r.grams[gram], id)
+		if len(r.grams[gram]) == 0 {
+			delete(r.grams, gram)
+		}
+	}
+	delete(r.docs, id)
+}
+
+// intersect 返回两个集合的交集，a为nil表示尚未限制
+func intersect(a, b map[string]struct{}) map[string]struct{} {
+	if a == nil {
+		result := make(map[string]struct{}, len(b))
+		for id := range b {
+			result[id] = struct{}{}
+		}
+		return result
+	}
+	for id := range a {
+		if _, ok := b[id]; !ok {
+			delete(a, id)
+		}
+	}
+	return a
+}
+
+// copyUser 复制用户，避免调用方修改仓库内的数据
+func copyUser(user *models.User) *models.User {
+	u := *user
+	u.Roles = append([]string(nil), user.Roles...)
+	if user.DeletedAt != nil {
+		deletedAt := *user.DeletedAt
+		u.DeletedAt = &deletedAt
+	}
+	return &u
+}
+
+// identityKey 返回用户关联的外部身份索引键，未关联时第二个返回值为false
+func identityKey(user *models.User) (string, bool) {
+	return user.IdentityIssuer + "\x00" + user.IdentitySubject, user.IdentitySubject != ""
+}
+
+// normalizeEmail 统一邮箱大小写用于唯一性判断
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go-echo-app/internal/models"
+)
+
+// ErrInvalidCursor 游标中的值与排序字段不匹配
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// sortColumns 允许排序的字段和对应的列，避免将请求参数拼接进SQL
+var sortColumns = map[string]string{
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"name":       "name",
+	"email":      "email",
+}
+
+// sortColumn 返回排序列，未指定时按创建时间
+func sortColumn(field string) (string, bool) {
+	if field == "" {
+		return "created_at", true
+	}
+	column, ok := sortColumns[field]
+	return column, ok
+}
+
+// isTimeColumn 判断排序列是否为时间
+func isTimeColumn(column string) bool {
+	return column == "created_at" || column == "updated_at"
+}
+
+// cursorValue 将游标中的值转换为列的类型
+func cursorValue(column string, cursor *models.UserCursor) (interface{}, error) {
+	if !isTimeColumn(column) {
+		return cursor.Value, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, cursor.Value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return t.UTC(), nil
+}
+
+// matchesUserFilter 判断用户是否满足查询的过滤条件
+func matchesUserFilter(user *models.User, q *models.UserQuery) bool {
+	if user.DeletedAt != nil && !q.IncludeDeleted {
+		return false
+	}
+	if q.Name != "" && !strings.Contains(strings.ToLower(user.Name), strings.ToLower(q.Name)) {
+		return false
+	}
+	if q.Email != "" && !strings.Contains(strings.ToLower(user.Email), strings.ToLower(q.Email)) {
+		return false
+	}
+	if !q.CreatedAfter.IsZero() && user.CreatedAt.Before(q.CreatedAfter) {
+		return false
+	}
+	if !q.CreatedBefore.IsZero() && !user.CreatedAt.Before(q.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+// sortKey 返回用户在排序列上的值
+func sortKey(user *models.User, column string) interface{} {
+	switch column {
+	case "updated_at":
+		return user.UpdatedAt
+	case "name":
+		return user.Name
+	case "email":
+		return user.Email
+	default:
+		return user.CreatedAt
+	}
+}
+
+// compareUser 按排序列比较用户与位置(value, id)，返回-1、0或1（升序）
+func compareUser(user *models.User, column string, value interface{}, id string) int {
+	var c int
+	switch column {
+	case "created_at":
+		c = user.CreatedAt.Compare(value.(time.Time))
+	case "updated_at":
+		c = user.UpdatedAt.Compare(value.(time.Time))
+	case "name":
+		c = strings.Compare(user.Name, value.(string))
+	case "email":
+		c = strings.Compare(user.Email, value.(string))
+	}
+	if c == 0 {
+		c = strings.Compare(user.ID, id)
+	}
+	return c
+}
@@ -0,0 +1,416 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go-echo-app/internal/auth"
+	"go-echo-app/internal/config"
+	"go-echo-app/internal/database"
+	"go-echo-app/internal/handlers"
+	"go-echo-app/internal/models"
+	"go-echo-app/internal/repository"
+	"go-echo-app/internal/services"
+	"go-echo-app/pkg/validator"
+)
+
+// postgresDSNEnv 设置后同时在该Postgres数据库上运行仓库测试，测试会清空users表（api_keys随之级联删除）
+const postgresDSNEnv = "TEST_POSTGRES_DSN"
+
+// repoFactory 创建一个空的用户仓库，sql为false时不支持上下文取消
+type repoFactory struct {
+	name string
+	sql  bool
+	open func(t *testing.T) models.UserRepository
+}
+
+// userRepositories 返回参与测试的仓库：内存、SQLite，以及设置了TEST_POSTGRES_DSN时的Postgres
+func userRepositories() []repoFactory {
+	factories := []repoFactory{
+		{name: "memory", open: func(*testing.T) models.UserRepository { return repository.NewMemoryUserRepository() }},
+		{name: "sqlite", sql: true, open: openSQLite},
+	}
+	if os.Getenv(postgresDSNEnv) != "" {
+		factories = append(factories, repoFactory{name: "postgres", sql: true, open: openPostgres})
+	}
+	return factories
+}
+
+func openSQLite(t *testing.T) models.UserRepository {
+	return repository.NewSQLiteUserRepository(openSQLiteDB(t))
+}
+
+// openSQLiteDB 打开临时文件中已迁移的SQLite数据库
+func openSQLiteDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := database.Open(&config.DatabaseConfig{
+		Driver:         "sqlite",
+		Path:           filepath.Join(t.TempDir(), "test.db"),
+		MaxOpenConns:   1,
+		ConnectTimeout: 5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrate(t, db, "sqlite")
+	return db
+}
+
+func openPostgres(t *testing.T) models.UserRepository {
+	return repository.NewPostgresUserRepository(openPostgresDB(t))
+}
+
+// openPostgresDB 打开TEST_POSTGRES_DSN指定的已迁移数据库，并清空测试用到的表
+func openPostgresDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("pgx", os.Getenv(postgresDSNEnv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrate(t, db, "postgres")
+	if _, err := db.Exec("DELETE FROM users"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func migrate(t *testing.T, db *sql.DB, driver string) {
+	t.Helper()
+	m, err := database.NewMigrator(db, driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// baseTime 测试用户的创建时间基准，取整到秒以免数据库的时间精度影响比较
+var baseTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newUser(id, name, email string, offset time.Duration) *models.User {
+	created := baseTime.Add(offset)
+	return &models.User{
+		ID:        id,
+		Name:      name,
+		Email:     email,
+		Roles:     []string{"user"},
+		CreatedAt: created,
+		UpdatedAt: created,
+	}
+}
+
+func mustCreate(t *testing.T, repo models.UserRepository, users ...*models.User) {
+	t.Helper()
+	for _, user := range users {
+		if err := repo.Create(context.Background(), user); err != nil {
+			t.Fatalf("Create(%s): %v", user.ID, err)
+		}
+	}
+}
+
+func userIDs(users []*models.User) string {
+	ids := make([]string, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestUserRepositoryCRUD(t *testing.T) {
+	ctx := context.Background()
+	for _, f := range userRepositories() {
+		t.Run(f.name, func(t *testing.T) {
+			repo := f.open(t)
+			user := newUser("u1", "Ann", "Ann@Example.com", 0)
+			mustCreate(t, repo, user)
+			if user.Version != 1 {
+				t.Fatalf("Version after Create = %d, want 1", user.Version)
+			}
+
+			got, err := repo.GetByEmail(ctx, "ann@example.COM")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != "u1" || got.Name != "Ann" || len(got.Roles) != 1 || !got.CreatedAt.Equal(user.CreatedAt) {
+				t.Fatalf("GetByEmail() = %+v", got)
+			}
+
+			got.Name = "Anne"
+			if err := repo.Update(ctx, got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Version != 2 {
+				t.Fatalf("Version after Update = %d, want 2", got.Version)
+			}
+			stale := *user
+			stale.Name = "Stale"
+			if err := repo.Update(ctx, &stale); !errors.Is(err, repository.ErrVersionConflict) {
+				t.Fatalf("stale Update() error = %v, want ErrVersionConflict", err)
+			}
+
+			deletedAt := baseTime.Add(time.Hour)
+			got.DeletedAt = &deletedAt
+			if err := repo.Update(ctx, got); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := repo.GetByID(ctx, "u1"); !errors.Is(err, repository.ErrNotFound) {
+				t.Fatalf("GetByID() of deleted user error = %v, want ErrNotFound", err)
+			}
+			if got, err = repo.GetByIDIncludeDeleted(ctx, "u1"); err != nil || got.Name != "Anne" || got.DeletedAt == nil {
+				t.Fatalf("GetByIDIncludeDeleted() = %+v, %v", got, err)
+			}
+
+			if n, err := repo.Purge(ctx, deletedAt); err != nil || n != 0 {
+				t.Fatalf("Purge(at deletion) = %d, %v; want 0", n, err)
+			}
+			if n, err := repo.Purge(ctx, deletedAt.Add(time.Second)); err != nil || n != 1 {
+				t.Fatalf("Purge(after deletion) = %d, %v; want 1", n, err)
+			}
+			if _, err := repo.GetByIDIncludeDeleted(ctx, "u1"); !errors.Is(err, repository.ErrNotFound) {
+				t.Fatalf("GetByIDIncludeDeleted() after Purge error = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestUserRepositoryDuplicates(t *testing.T) {
+	ctx := context.Background()
+	for _, f := range userRepositories() {
+		t.Run(f.name, func(t *testing.T) {
+			repo := f.open(t)
+			ann := newUser("u1", "Ann", "ann@example.com", 0)
+			ann.IdentityIssuer, ann.IdentitySubject = "https://sso.example.com", "ann"
+			bob := newUser("u2", "Bob", "bob@example.com", time.Second)
+			mustCreate(t, repo, ann, bob)
+
+			if err := repo.Create(ctx, newUser("u3", "Ann", "ANN@example.com", 0)); !errors.Is(err, repository.ErrDuplicateEmail) {
+				t.Fatalf("Create() with taken email error = %v, want ErrDuplicateEmail", err)
+			}
+			dup := newUser("u3", "Ann", "other@example.com", 0)
+			dup.IdentityIssuer, dup.IdentitySubject = ann.IdentityIssuer, ann.IdentitySubject
+			if err := repo.Create(ctx, dup); !errors.Is(err, repository.ErrDuplicateIdentity) {
+				t.Fatalf("Create() with linked identity error = %v, want ErrDuplicateIdentity", err)
+			}
+
+			bob.Email = "ann@example.com"
+			if err := repo.Update(ctx, bob); !errors.Is(err, repository.ErrDuplicateEmail) {
+				t.Fatalf("Update() to taken email error = %v, want ErrDuplicateEmail", err)
+			}
+			if got, err := repo.GetByEmail(ctx, "bob@example.com"); err != nil || got.Version != 1 {
+				t.Fatalf("failed Update changed the user: %+v, %v", got, err)
+			}
+			if got, err := repo.GetByIdentity(ctx, ann.IdentityIssuer, "ann"); err != nil || got.ID != "u1" {
+				t.Fatalf("GetByIdentity() = %+v, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestUserRepositoryContextCancellation(t *testing.T) {
+	for _, f := range userRepositories() {
+		if !f.sql {
+			continue
+		}
+		t.Run(f.name, func(t *testing.T) {
+			repo := f.open(t)
+			mustCreate(t, repo, newUser("u1", "Ann", "ann@example.com", 0))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			calls := map[string]func() error{
+				"Create": func() error { return repo.Create(ctx, newUser("u2", "Bob", "bob@example.com", 0)) },
+				"GetByID": func() error {
+					_, err := repo.GetByID(ctx, "u1")
+					return err
+				},
+				"GetAll": func() error {
+					_, err := repo.GetAll(ctx)
+					return err
+				},
+				"List": func() error {
+					_, err := repo.List(ctx, &models.UserQuery{Limit: 10})
+					return err
+				},
+				"Search": func() error {
+					_, err := repo.Search(ctx, []string{"ann"}, 10)
+					return err
+				},
+			}
+			for name, call := range calls {
+				if err := call(); !errors.Is(err, context.Canceled) {
+					t.Errorf("%s with cancelled context error = %v, want context.Canceled", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestUserRepositoryListCursor(t *testing.T) {
+	// u1..u5按创建时间递增；u2和u3姓名相同，按姓名排序时由ID决定先后
+	seed := func(t *testing.T, repo models.UserRepository) {
+		mustCreate(t, repo,
+			newUser("u1", "Dan", "u1@example.com", 1*time.Minute),
+			newUser("u2", "Bea", "u2@example.com", 2*time.Minute),
+			newUser("u3", "Bea", "u3@example.com", 3*time.Minute),
+			newUser("u4", "Cal", "u4@example.com", 4*time.Minute),
+			newUser("u5", "Abe", "u5@example.com", 5*time.Minute),
+		)
+	}
+	at := func(minutes int, id string) *models.UserCursor {
+		return &models.UserCursor{Value: baseTime.Add(time.Duration(minutes) * time.Minute).Format(time.RFC3339Nano), ID: id}
+	}
+
+	tests := []struct {
+		name    string
+		query   models.UserQuery
+		want    string
+		hasMore bool
+		wantErr error
+	}{
+		{name: "first page", query: models.UserQuery{Limit: 2}, want: "u1,u2", hasMore: true},
+		{name: "limit equals total", query: models.UserQuery{Limit: 5}, want: "u1,u2,u3,u4,u5"},
+		{name: "after middle", query: models.UserQuery{Limit: 2, After: at(2, "u2")}, want: "u3,u4", hasMore: true},
+		{name: "after with exactly limit left", query: models.UserQuery{Limit: 2, After: at(3, "u3")}, want: "u4,u5"},
+		{name: "after last", query: models.UserQuery{Limit: 2, After: at(5, "u5")}, want: ""},
+		{name: "after position between users", query: models.UserQuery{Limit: 5, After: at(3, "u2")}, want: "u3,u4,u5"},
+		{name: "before middle", query: models.UserQuery{Limit: 2, Before: at(4, "u4")}, want: "u2,u3", hasMore: true},
+		{name: "before with exactly limit left", query: models.UserQuery{Limit: 2, Before: at(3, "u3")}, want: "u1,u2"},
+		{name: "before first", query: models.UserQuery{Limit: 2, Before: at(1, "u1")}, want: ""},
+		{name: "descending after", query: models.UserQuery{Limit: 2, Desc: true, After: at(4, "u4")}, want: "u3,u2", hasMore: true},
+		{name: "descending before", query: models.UserQuery{Limit: 2, Desc: true, Before: at(2, "u2")}, want: "u4,u3", hasMore: true},
+		{name: "ties broken by id", query: models.UserQuery{Limit: 2, SortBy: "name", After: &models.UserCursor{Value: "Bea", ID: "u2"}}, want: "u3,u4", hasMore: true},
+		{name: "offset", query: models.UserQuery{Limit: 2, Offset: 4}, want: "u5"},
+		{name: "offset past end", query: models.UserQuery{Limit: 2, Offset: 10}, want: ""},
+		{name: "invalid time cursor", query: models.UserQuery{Limit: 2, After: &models.UserCursor{Value: "yesterday", ID: "u1"}}, wantErr: repository.ErrInvalidCursor},
+	}
+
+	for _, f := range userRepositories() {
+		t.Run(f.name, func(t *testing.T) {
+			repo := f.open(t)
+			seed(t, repo)
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					q := tt.query
+					list, err := repo.List(context.Background(), &q)
+					if tt.wantErr != nil {
+						if !errors.Is(err, tt.wantErr) {
+							t.Fatalf("List() error = %v, want %v", err, tt.wantErr)
+						}
+						return
+					}
+					if err != nil {
+						t.Fatal(err)
+					}
+					if got := userIDs(list.Users); got != tt.want || list.HasMore != tt.hasMore || list.Total != 5 {
+						t.Fatalf("List() = [%s] hasMore=%v total=%d, want [%s] hasMore=%v total=5",
+							got, list.HasMore, list.Total, tt.want, tt.hasMore)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestCreateUserDuplicateEmailIsConflict(t *testing.T) {
+	hasher, err := auth.NewPasswordHasher(&config.PasswordConfig{Algorithm: auth.PasswordBcrypt, BcryptCost: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range userRepositories() {
+		t.Run(f.name, func(t *testing.T) {
+			e := echo.New()
+			e.Validator = validator.NewCustomValidator()
+			h := handlers.NewUserHandler(services.NewUserService(f.open(t), hasher), &config.UsersConfig{})
+			e.POST("/users", h.CreateUser)
+
+			for i, want := range []int{http.StatusCreated, http.StatusConflict} {
+				body := fmt.Sprintf(`{"name":"Ann","email":"%s","password":"Passw0rd!"}`, []string{"ann@example.com", "ANN@example.com"}[i])
+				req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+				if rec.Code != want {
+					t.Fatalf("request %d: status = %d, want %d: %s", i+1, rec.Code, want, rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestUserRepositorySearchRanksBeforeLimit(t *testing.T) {
+	for _, f := range userRepositories() {
+		t.Run(f.name, func(t *testing.T) {
+			repo := f.open(t)
+			// 大量较弱的匹配按ID排在最佳匹配之前，排序必须发生在截取之前
+			for i := 0; i < 600; i++ {
+				mustCreate(t, repo, newUser(fmt.Sprintf("a%03d", i), fmt.Sprintf("Xjohnx Filler Person %d", i),
+					fmt.Sprintf("filler.person.%d@example.com", i), 0))
+			}
+			mustCreate(t, repo, newUser("z", "John", "j@x.io", 0))
+
+			for _, terms := range [][]string{{"john"}, {"jo"}} {
+				users, err := repo.Search(context.Background(), terms, 1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := userIDs(users); got != "z" {
+					t.Fatalf("Search(%q, 1) = [%s], want [z]", terms, got)
+				}
+			}
+		})
+	}
+}
+
+func TestUserRepositoryDeletedUserReleasesEmail(t *testing.T) {
+	ctx := context.Background()
+	for _, f := range userRepositories() {
+		t.Run(f.name, func(t *testing.T) {
+			repo := f.open(t)
+			old := newUser("u1", "Ann", "ann@example.com", 0)
+			old.IdentityIssuer, old.IdentitySubject = "https://sso.example.com", "ann"
+			mustCreate(t, repo, old)
+			deletedAt := baseTime.Add(time.Hour)
+			old.DeletedAt = &deletedAt
+			if err := repo.Update(ctx, old); err != nil {
+				t.Fatal(err)
+			}
+
+			// 已删除的用户不占用邮箱，但外部身份仍属于该用户
+			mustCreate(t, repo, newUser("u2", "Ann", "ANN@example.com", time.Second))
+			if got, err := repo.GetByEmail(ctx, "ann@example.com"); err != nil || got.ID != "u2" {
+				t.Fatalf("GetByEmail() = %+v, %v; want u2", got, err)
+			}
+			if got, err := repo.GetByIdentity(ctx, old.IdentityIssuer, old.IdentitySubject); err != nil || got.ID != "u1" || got.DeletedAt == nil {
+				t.Fatalf("GetByIdentity() = %+v, %v; want deleted u1", got, err)
+			}
+
+			// 邮箱已被占用时不能恢复
+			old.DeletedAt = nil
+			if err := repo.Update(ctx, old); !errors.Is(err, repository.ErrDuplicateEmail) {
+				t.Fatalf("restore Update() error = %v, want ErrDuplicateEmail", err)
+			}
+
+			// 清理已删除的用户不影响使用同一邮箱的新用户
+			if n, err := repo.Purge(ctx, deletedAt.Add(time.Second)); err != nil || n != 1 {
+				t.Fatalf("Purge() = %d, %v; want 1", n, err)
+			}
+			if got, err := repo.GetByEmail(ctx, "ann@example.com"); err != nil || got.ID != "u2" {
+				t.Fatalf("GetByEmail() after Purge = %+v, %v; want u2", got, err)
+			}
+		})
+	}
+}
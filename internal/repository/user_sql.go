@@ -0,0 +1,399 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-echo-app/internal/models"
+	"go-echo-app/internal/search"
+)
+
+// userColumns 查询用户时读取的列，顺序与scanUser一致
+const userColumns = "id, name, email, phone, password, roles, version, created_at, updated_at, deleted_at, identity_issuer, identity_subject"
+
+// SQLUserRepository 基于database/sql的用户仓库，SQL差异由Dialect处理
+type SQLUserRepository struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// NewSQLUserRepository 创建SQL用户仓库
+func NewSQLUserRepository(db *sql.DB, dialect Dialect) *SQLUserRepository {
+	return &SQLUserRepository{db: db, dialect: dialect}
+}
+
+// NewPostgresUserRepository 创建Postgres用户仓库
+func NewPostgresUserRepository(db *sql.DB) *SQLUserRepository {
+	return NewSQLUserRepository(db, PostgresDialect{})
+}
+
+// NewSQLiteUserRepository 创建SQLite用户仓库
+func NewSQLiteUserRepository(db *sql.DB) *SQLUserRepository {
+	return NewSQLUserRepository(db, SQLiteDialect{})
+}
+
+// Create 实现models.UserRepository，新用户的版本为1
+func (r *SQLUserRepository) Create(ctx context.Context, user *models.User) error {
+	roles, err := encodeRoles(user.Roles)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("INSERT INTO users (%s, search_text) VALUES (%s)", userColumns, r.placeholders(1, 13))
+	_, err = r.db.ExecContext(ctx, query,
+		user.ID, user.Name, user.Email, user.Phone, user.Password, roles, 1, user.CreatedAt, user.UpdatedAt, user.DeletedAt,
+		user.IdentityIssuer, user.IdentitySubject, searchText(user))
+	if err != nil {
+		return r.mapError(err)
+	}
+	user.Version = 1
+	return nil
+}
+
+// GetByID 实现models.UserRepository
+func (r *SQLUserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	query := fmt.Sprintf("SELECT %s FROM users WHERE id = %s AND deleted_at IS NULL", userColumns, r.dialect.Placeholder(1))
+	return r.queryUser(ctx, query, id)
+}
+
+// GetByIDIncludeDeleted 实现models.UserRepository
+func (r *SQLUserRepository) GetByIDIncludeDeleted(ctx context.Context, id string) (*models.User, error) {
+	query := fmt.Sprintf("SELECT %s FROM users WHERE id = %s", userColumns, r.dialect.Placeholder(1))
+	return r.queryUser(ctx, query, id)
+}
+
+// GetByEmail 实现models.UserRepository，邮箱比较忽略大小写
+func (r *SQLUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	query := fmt.Sprintf("SELECT %s FROM users WHERE lower(email) = lower(%s) AND deleted_at IS NULL", userColumns, r.dialect.Placeholder(1))
+	return r.queryUser(ctx, query, strings.TrimSpace(email))
+}
+
+// GetByIdentity 实现models.UserRepository，包括已软删除的用户
+func (r *SQLUserRepository) GetByIdentity(ctx context.Context, issuer, subject string) (*models.User, error) {
+	if subject == "" {
+		return nil, ErrNotFound
+	}
+	query := fmt.Sprintf("SELECT %s FROM users WHERE identity_issuer = %s AND identity_subject = %s",
+		userColumns, r.dialect.Placeholder(1), r.dialect.Placeholder(2))
+	return r.queryUser(ctx, query, issuer, subject)
+}
+
+// GetAll 实现models.UserRepository，按创建时间排序
+func (r *SQLUserRepository) GetAll(ctx context.Context) ([]*models.User, error) {
+	query := fmt.Sprintf("SELECT %s FROM users WHERE deleted_at IS NULL ORDER BY created_at, id", userColumns)
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]*models.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// List 实现models.UserRepository。游标分页按(排序列, id)比较，可以使用对应的联合索引
+func (r *SQLUserRepository) List(ctx context.Context, q *models.UserQuery) (*models.UserList, error) {
+	column, ok := sortColumn(q.SortBy)
+	if !ok {
+		return nil, fmt.Errorf("unsupported sort field %q", q.SortBy)
+	}
+
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return r.dialect.Placeholder(len(args))
+	}
+	if !q.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	if q.Name != "" {
+		conditions = append(conditions, "lower(name) LIKE "+arg(likePattern(q.Name))+` ESCAPE '\'`)
+	}
+	if q.Email != "" {
+		conditions = append(conditions, "lower(email) LIKE "+arg(likePattern(q.Email))+` ESCAPE '\'`)
+	}
+	if !q.CreatedAfter.IsZero() {
+		conditions = append(conditions, "created_at >= "+arg(q.CreatedAfter.UTC()))
+	}
+	if !q.CreatedBefore.IsZero() {
+		conditions = append(conditions, "created_at < "+arg(q.CreatedBefore.UTC()))
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users"+where(conditions), args...).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	// Before时反向查询紧邻游标的用户，再恢复顺序
+	desc := q.Desc
+	cursor := q.After
+	if q.Before != nil {
+		desc = !desc
+		cursor = q.Before
+	}
+	if cursor != nil {
+		value, err := cursorValue(column, cursor)
+		if err != nil {
+			return nil, err
+		}
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		v, id := arg(value), arg(cursor.ID)
+		conditions = append(conditions, fmt.Sprintf("(%s %s %s OR (%s = %s AND id %s %s))", column, op, v, column, v, op, id))
+	}
+
+	order := "ASC"
+	if desc {
+		order = "DESC"
+	}
+	query := fmt.Sprintf("SELECT %s FROM users%s ORDER BY %s %s, id %s LIMIT %s",
+		userColumns, where(conditions), column, order, order, arg(q.Limit+1))
+	if cursor == nil && q.Offset > 0 {
+		query += " OFFSET " + arg(q.Offset)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]*models.User, 0, q.Limit+1)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	hasMore := len(users) > q.Limit
+	if hasMore {
+		users = users[:q.Limit]
+	}
+	if q.Before != nil {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
+	return &models.UserList{Users: users, Total: total, HasMore: hasMore}, nil
+}
+
+// Search 实现models.UserRepository，在search_text上按子串匹配全部词，由方言提供的索引加速并计算相关度
+func (r *SQLUserRepository) Search(ctx context.Context, terms []string, limit int) ([]*models.User, error) {
+	if len(terms) == 0 {
+		return []*models.User{}, nil
+	}
+
+	query, args := r.dialect.SearchQuery(userColumns, terms, limit)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]*models.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// ReindexSearch 为search_text为空的用户（如迁移前已存在的用户）生成检索文本，返回更新的数量
+func (r *SQLUserRepository) ReindexSearch(ctx context.Context) (int, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, name, email, phone FROM users WHERE search_text = ''")
+	if err != nil {
+		return 0, err
+	}
+	var users []*models.User
+	for rows.Next() {
+		user := new(models.User)
+		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Phone); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		users = append(users, user)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf("UPDATE users SET search_text = %s WHERE id = %s", r.dialect.Placeholder(1), r.dialect.Placeholder(2))
+	for _, user := range users {
+		if _, err := r.db.ExecContext(ctx, query, searchText(user), user.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(users), nil
+}
+
+// Update 实现models.UserRepository，只在数据库中的版本仍为user.Version时更新，成功后版本加一
+func (r *SQLUserRepository) Update(ctx context.Context, user *models.User) error {
+	roles, err := encodeRoles(user.Roles)
+	if err != nil {
+		return err
+	}
+
+	p := r.dialect.Placeholder
+	query := fmt.Sprintf("UPDATE users SET name = %s, email = %s, phone = %s, password = %s, roles = %s, updated_at = %s, deleted_at = %s, identity_issuer = %s, identity_subject = %s, search_text = %s, version = version + 1 WHERE id = %s AND version = %s",
+		p(1), p(2), p(3), p(4), p(5), p(6), p(7), p(8), p(9), p(10), p(11), p(12))
+	result, err := r.db.ExecContext(ctx, query,
+		user.Name, user.Email, user.Phone, user.Password, roles, user.UpdatedAt, user.DeletedAt, user.IdentityIssuer, user.IdentitySubject,
+		searchText(user), user.ID, user.Version)
+	if err != nil {
+		return r.mapError(err)
+	}
+	if err := r.requireVersion(ctx, result, user.ID); err != nil {
+		return err
+	}
+	user.Version++
+	return nil
+}
+
+// Purge 实现models.UserRepository
+func (r *SQLUserRepository) Purge(ctx context.Context, before time.Time) (int, error) {
+	query := "DELETE FROM users WHERE deleted_at IS NOT NULL AND deleted_at < " + r.dialect.Placeholder(1)
+	result, err := r.db.ExecContext(ctx, query, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// queryUser 查询单个用户
+func (r *SQLUserRepository) queryUser(ctx context.Context, query string, args ...interface{}) (*models.User, error) {
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return user, err
+}
+
+// placeholders 返回从first开始的n个以逗号分隔的占位符
+func (r *SQLUserRepository) placeholders(first, n int) string {
+	return placeholderList(r.dialect, first, n)
+}
+
+// placeholderList 返回方言d从first开始的n个以逗号分隔的占位符
+func placeholderList(d Dialect, first, n int) string {
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = d.Placeholder(first + i)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// mapError 将唯一约束冲突转换为ErrDuplicateIdentity或ErrDuplicateEmail。
+// Postgres的错误信息包含索引名users_identity_key，SQLite的包含列名identity_issuer、identity_subject
+func (r *SQLUserRepository) mapError(err error) error {
+	if err == nil || !r.dialect.IsUniqueViolation(err) {
+		return err
+	}
+	if strings.Contains(err.Error(), "identity_") {
+		return ErrDuplicateIdentity
+	}
+	return ErrDuplicateEmail
+}
+
+// where 用AND连接条件，没有条件时返回空字符串
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
+
+// likePattern 返回匹配子串的LIKE模式，转义其中的通配符
+func likePattern(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(strings.ToLower(s))
+	return "%" + s + "%"
+}
+
+// searchText 返回用户的检索文本
+func searchText(user *models.User) string {
+	return search.Document(user.Name, user.Email, user.Phone)
+}
+
+// rowScanner sql.Row和sql.Rows的共同接口
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser 读取一行用户数据
+func scanUser(row rowScanner) (*models.User, error) {
+	var (
+		user  models.User
+		roles string
+	)
+	if err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Phone, &user.Password, &roles, &user.Version, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt,
+		&user.IdentityIssuer, &user.IdentitySubject); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(roles), &user.Roles); err != nil {
+		return nil, fmt.Errorf("decode roles of user %s: %w", user.ID, err)
+	}
+	// Postgres的TIMESTAMPTZ按会话时区返回，统一转换为UTC
+	user.CreatedAt = user.CreatedAt.UTC()
+	user.UpdatedAt = user.UpdatedAt.UTC()
+	if user.DeletedAt != nil {
+		deletedAt := user.DeletedAt.UTC()
+		user.DeletedAt = &deletedAt
+	}
+	return &user, nil
+}
+
+// encodeRoles 以JSON数组保存角色，便于不同数据库共用同一表结构
+func encodeRoles(roles []string) (string, error) {
+	if roles == nil {
+		roles = []string{}
+	}
+	data, err := json.Marshal(roles)
+	return string(data), err
+}
+
+// requireVersion 按ID和版本的条件更新未影响任何行时，区分用户不存在（ErrNotFound）和版本不一致（ErrVersionConflict）
+func (r *SQLUserRepository) requireVersion(ctx context.Context, result sql.Result, id string) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	var exists int
+	err = r.db.QueryRowContext(ctx, "SELECT 1 FROM users WHERE id = "+r.dialect.Placeholder(1), id).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return ErrVersionConflict
+}
@@ -0,0 +1,233 @@
+package search
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxTerms 查询中参与匹配的词数上限
+const MaxTerms = 8
+
+// 匹配类型的得分：整词 > 词首 > 词中
+const (
+	scoreExact     = 3
+	scorePrefix    = 2
+	scoreSubstring = 1
+)
+
+// Field 参与匹配和高亮的字段
+type Field struct {
+	Name   string
+	Text   string
+	Weight float64
+	Digits bool // 只比较数字，如电话号码
+}
+
+// UserFields 返回用户参与匹配的字段及权重：姓名高于邮箱和电话，电话只比较数字
+func UserFields(name, email, phone string) []Field {
+	return []Field{
+		{Name: "name", Text: name, Weight: 3},
+		{Name: "email", Text: email, Weight: 2},
+		{Name: "phone", Text: phone, Weight: 2, Digits: true},
+	}
+}
+
+// Normalize 去掉重音符号并转换为小写，用于忽略大小写和重音的比较
+func Normalize(s string) string {
+	runes, _ := fold(s, false)
+	return string(runes)
+}
+
+// Digits 只保留数字
+func Digits(s string) string {
+	runes, _ := fold(s, true)
+	return string(runes)
+}
+
+// Tokenize 将查询拆分为去重后的规范化词，最多MaxTerms个
+func Tokenize(s string) []string {
+	fields := strings.FieldsFunc(Normalize(s), func(r rune) bool {
+		return !isWordRune(r)
+	})
+
+	terms := make([]string, 0, len(fields))
+	seen := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if !seen[f] {
+			seen[f] = true
+			terms = append(terms, f)
+		}
+		if len(terms) == MaxTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// Document 返回用户的检索文本，各字段规范化后以空格分隔；词中不含空格，因此匹配不会跨字段
+func Document(name, email, phone string) string {
+	return Normalize(name) + " " + Normalize(email) + " " + Digits(phone)
+}
+
+// Rank 计算字段与查询词的相关度并生成高亮。每个词取在各字段上的最佳匹配得分乘以字段权重之和，
+// 任一词在所有字段中都没有出现时ok为false。高亮以<mark>标记匹配部分，其余文本经过HTML转义
+func Rank(fields []Field, terms []string) (score float64, highlights map[string]string, ok bool) {
+	folded := make([][]rune, len(fields))
+	origins := make([][]int, len(fields))
+	ranges := make([][][2]int, len(fields))
+	for i, f := range fields {
+		folded[i], origins[i] = fold(f.Text, f.Digits)
+	}
+
+	for _, term := range terms {
+		t := []rune(term)
+		best := 0.0
+		for i, f := range fields {
+			if f.Digits && !isDigits(t) {
+				continue
+			}
+			kind := 0
+			for _, at := range indexAll(folded[i], t) {
+				ranges[i] = append(ranges[i], [2]int{origins[i][at], origins[i][at+len(t)-1] + 1})
+				if k := matchKind(folded[i], at, len(t), f.Digits); k > kind {
+					kind = k
+				}
+			}
+			if s := float64(kind) * f.Weight; s > best {
+				best = s
+			}
+		}
+		if best == 0 {
+			return 0, nil, false
+		}
+		score += best
+	}
+
+	highlights = make(map[string]string)
+	for i, f := range fields {
+		if len(ranges[i]) > 0 {
+			highlights[f.Name] = highlight(f.Text, ranges[i])
+		}
+	}
+	return score, highlights, true
+}
+
+// fold 逐个字符分解并去掉组合符号、转换为小写，返回规范化后的字符及其在原文中的字符下标
+func fold(s string, digitsOnly bool) ([]rune, []int) {
+	runes := make([]rune, 0, len(s))
+	origins := make([]int, 0, len(s))
+	for i, r := range []rune(s) {
+		for _, d := range norm.NFD.String(string(r)) {
+			if unicode.Is(unicode.Mn, d) {
+				continue
+			}
+			d = unicode.ToLower(d)
+			if digitsOnly && !unicode.IsDigit(d) {
+				continue
+			}
+			runes = append(runes, d)
+			origins = append(origins, i)
+		}
+	}
+	return runes, origins
+}
+
+// indexAll 返回sub在s中所有不重叠出现的位置
+func indexAll(s, sub []rune) []int {
+	var positions []int
+	for i := 0; i+len(sub) <= len(s); {
+		if equalRunes(s[i:i+len(sub)], sub) {
+			positions = append(positions, i)
+			i += len(sub)
+			continue
+		}
+		i++
+	}
+	return positions
+}
+
+// matchKind 判断位于at、长度为n的匹配是整词、词首还是词中。数字字段中整体相同视为整词，从开头匹配视为词首
+func matchKind(s []rune, at, n int, digits bool) int {
+	start := at == 0 || (!digits && !isWordRune(s[at-1]))
+	end := at+n == len(s) || (!digits && !isWordRune(s[at+n]))
+	switch {
+	case start && end:
+		return scoreExact
+	case start:
+		return scorePrefix
+	default:
+		return scoreSubstring
+	}
+}
+
+// highlight 用<mark>标记原文中的字符区间，重叠的区间会合并
+func highlight(text string, ranges [][2]int) string {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r[0] <= last[1] {
+			if r[1] > last[1] {
+				last[1] = r[1]
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	runes := []rune(text)
+	var b strings.Builder
+	pos := 0
+	for _, r := range merged {
+		b.WriteString(html.EscapeString(string(runes[pos:r[0]])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(runes[r[0]:r[1]])))
+		b.WriteString("</mark>")
+		pos = r[1]
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:])))
+	return b.String()
+}
+
+// Trigrams 返回文本中各个词的三字符片段，用于内存倒排索引；不足三个字符的词不产生片段
+func Trigrams(text string) []string {
+	seen := make(map[string]bool)
+	var grams []string
+	for _, word := range strings.Fields(text) {
+		r := []rune(word)
+		for i := 0; i+3 <= len(r); i++ {
+			g := string(r[i : i+3])
+			if !seen[g] {
+				seen[g] = true
+				grams = append(grams, g)
+			}
+		}
+	}
+	return grams
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isDigits(r []rune) bool {
+	for _, c := range r {
+		if !unicode.IsDigit(c) {
+			return false
+		}
+	}
+	return len(r) > 0
+}
+
+func equalRunes(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
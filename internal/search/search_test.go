@@ -0,0 +1,162 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"José", "jose"},
+		{"ÅNGSTRÖM", "angstrom"},
+		{"Crème Brûlée", "creme brulee"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDigits(t *testing.T) {
+	if got := Digits("+1 (555) 010-2030"); got != "15550102030" {
+		t.Fatalf("Digits() = %q", got)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{name: "splits on punctuation", in: "José.García@example.com", want: []string{"jose", "garcia", "example", "com"}},
+		{name: "deduplicates", in: "Ann ann ANN", want: []string{"ann"}},
+		{name: "empty", in: "  ,; ", want: []string{}},
+		{name: "caps at MaxTerms", in: "a b c d e f g h i j", want: []string{"a", "b", "c", "d", "e", "f", "g", "h"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Tokenize(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Tokenize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocument(t *testing.T) {
+	got := Document("Zoë Ng", "Zoe@Example.com", "+44 20 7946 0958")
+	if want := "zoe ng zoe@example.com 442079460958"; got != want {
+		t.Fatalf("Document() = %q, want %q", got, want)
+	}
+}
+
+func TestRank(t *testing.T) {
+	fields := func(name, email, phone string) []Field {
+		return []Field{
+			{Name: "name", Text: name, Weight: 3},
+			{Name: "email", Text: email, Weight: 2},
+			{Name: "phone", Text: phone, Weight: 1, Digits: true},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		fields     []Field
+		terms      []string
+		wantOK     bool
+		wantScore  float64
+		highlights map[string]string
+	}{
+		{
+			name:       "exact word in name",
+			fields:     fields("Ann Lee", "ann@example.com", ""),
+			terms:      []string{"ann"},
+			wantOK:     true,
+			wantScore:  scoreExact * 3,
+			highlights: map[string]string{"name": "<mark>Ann</mark> Lee", "email": "<mark>ann</mark>@example.com"},
+		},
+		{
+			name:       "prefix beats substring",
+			fields:     fields("Joanna", "x@example.com", ""),
+			terms:      []string{"jo"},
+			wantOK:     true,
+			wantScore:  scorePrefix * 3,
+			highlights: map[string]string{"name": "<mark>Jo</mark>anna"},
+		},
+		{
+			name:       "accents are ignored and kept in highlights",
+			fields:     fields("José", "j@example.com", ""),
+			terms:      []string{"jose"},
+			wantOK:     true,
+			wantScore:  scoreExact * 3,
+			highlights: map[string]string{"name": "<mark>José</mark>"},
+		},
+		{
+			name:       "phone matches digits across punctuation",
+			fields:     fields("Bob", "bob@example.com", "555-0102"),
+			terms:      []string{"5550"},
+			wantOK:     true,
+			wantScore:  scorePrefix * 1,
+			highlights: map[string]string{"phone": "<mark>555-0</mark>102"},
+		},
+		{
+			name:       "text is escaped",
+			fields:     fields("<b>Eve</b>", "eve@example.com", ""),
+			terms:      []string{"eve"},
+			wantOK:     true,
+			wantScore:  scoreExact * 3,
+			highlights: map[string]string{"name": "&lt;b&gt;<mark>Eve</mark>&lt;/b&gt;", "email": "<mark>eve</mark>@example.com"},
+		},
+		{
+			name:   "every term must match",
+			fields: fields("Ann Lee", "ann@example.com", ""),
+			terms:  []string{"ann", "zed"},
+			wantOK: false,
+		},
+		{
+			name:   "letters never match the phone",
+			fields: fields("", "", "555"),
+			terms:  []string{"abc"},
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, highlights, ok := Rank(tt.fields, tt.terms)
+			if ok != tt.wantOK {
+				t.Fatalf("Rank() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if score != tt.wantScore {
+				t.Errorf("score = %v, want %v", score, tt.wantScore)
+			}
+			if !reflect.DeepEqual(highlights, tt.highlights) {
+				t.Errorf("highlights = %q, want %q", highlights, tt.highlights)
+			}
+		})
+	}
+}
+
+func TestTrigrams(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"abcd", []string{"abc", "bcd"}},
+		{"ab cd", nil},
+		{"aaaa aaa", []string{"aaa"}},
+		{"josé", []string{"jos", "osé"}},
+	}
+	for _, tt := range tests {
+		if got := Trigrams(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Trigrams(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go-echo-app/internal/models"
+)
+
+// purgeTimeout 单次清理的最长时间
+const purgeTimeout = time.Minute
+
+// UserPurger 定期永久删除软删除时间超过保留期的用户。
+// 多个副本同时运行时各自清理，删除条件相同，不会重复删除
+type UserPurger struct {
+	repo      models.UserRepository
+	retention time.Duration
+	interval  time.Duration
+	logf      func(format string, args ...interface{})
+	stop      chan struct{}
+	once      sync.Once
+}
+
+// NewUserPurger 创建用户清理任务，retention为软删除后的保留期，interval为清理间隔
+func NewUserPurger(repo models.UserRepository, retention, interval time.Duration, logf func(format string, args ...interface{})) *UserPurger {
+	return &UserPurger{
+		repo:      repo,
+		retention: retention,
+		interval:  interval,
+		logf:      logf,
+		stop:      make(chan struct{}),
+	}
+}
+
+// Purge 永久删除在now之前超过保留期的用户，返回删除的数量
+func (p *UserPurger) Purge(ctx context.Context, now time.Time) (int, error) {
+	return p.repo.Purge(ctx, now.Add(-p.retention))
+}
+
+// Start 立即清理一次，之后在后台按间隔清理；保留期或间隔<=0时不启动
+func (p *UserPurger) Start() {
+	if p.retention <= 0 || p.interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			p.run()
+			select {
+			case <-ticker.C:
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close 停止后台清理
+func (p *UserPurger) Close() {
+	p.once.Do(func() { close(p.stop) })
+}
+
+// run 执行一次清理并记录结果
+func (p *UserPurger) run() {
+	ctx, cancel := context.WithTimeout(context.Background(), purgeTimeout)
+	defer cancel()
+
+	n, err := p.Purge(ctx, time.Now().UTC())
+	if err != nil {
+		p.logf("purge deleted users failed: %v", err)
+		return
+	}
+	if n > 0 {
+		p.logf("purged %d deleted user(s)", n)
+	}
+}
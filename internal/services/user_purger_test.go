@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-echo-app/internal/models"
+	"go-echo-app/internal/repository"
+)
+
+func TestUserPurgerRetentionBoundary(t *testing.T) {
+	const retention = 30 * 24 * time.Hour
+	deletedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		purged int
+	}{
+		{name: "within retention", now: deletedAt.Add(retention - time.Second), purged: 0},
+		{name: "exactly at retention", now: deletedAt.Add(retention), purged: 0},
+		{name: "just past retention", now: deletedAt.Add(retention + time.Nanosecond), purged: 1},
+		{name: "long past retention", now: deletedAt.Add(2 * retention), purged: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := repository.NewMemoryUserRepository()
+			deleted := &models.User{ID: "u1", Name: "Ann", Email: "ann@example.com", CreatedAt: deletedAt, UpdatedAt: deletedAt, DeletedAt: &deletedAt}
+			active := &models.User{ID: "u2", Name: "Bob", Email: "bob@example.com", CreatedAt: deletedAt, UpdatedAt: deletedAt}
+			for _, user := range []*models.User{deleted, active} {
+				if err := repo.Create(ctx, user); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			n, err := NewUserPurger(repo, retention, time.Hour, t.Logf).Purge(ctx, tt.now)
+			if err != nil || n != tt.purged {
+				t.Fatalf("Purge() = %d, %v; want %d", n, err, tt.purged)
+			}
+			if _, err := repo.GetByID(ctx, "u2"); err != nil {
+				t.Fatalf("active user was purged: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"go-echo-app/internal/auth"
+	"go-echo-app/internal/models"
+	"go-echo-app/internal/repository"
+	"go-echo-app/internal/search"
+	"go-echo-app/pkg/utils"
+)
+
+// 用户服务错误
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrEmailTaken      = errors.New("email already in use")
+	ErrInvalidCursor   = errors.New("invalid cursor")
+	ErrVersionMismatch = errors.New("user version mismatch")
+	ErrUserNotDeleted  = errors.New("user is not deleted")
+)
+
+// defaultPageSize 未指定limit时每页的用户数
+const defaultPageSize = 20
+
+// userService models.UserService的实现
+type userService struct {
+	repo      models.UserRepository
+	passwords *auth.PasswordHasher
+}
+
+// NewUserService 创建用户服务
+func NewUserService(repo models.UserRepository, passwords *auth.PasswordHasher) models.UserService {
+	return &userService{repo: repo, passwords: passwords}
+}
+
+// CreateUser 创建用户，生成ID和时间戳，新用户默认为user角色
+func (s *userService) CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.UserResponse, error) {
+	hash, err := s.passwords.Hash(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	user := &models.User{
+		ID:        utils.NewID(),
+		Name:      req.Name,
+		Email:     req.Email,
+		Phone:     req.Phone,
+		Password:  hash,
+		Roles:     []string{auth.RoleUser},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.Create(ctx, user); err != nil {
+		return nil, mapRepositoryError(err)
+	}
+
+	resp := user.ToResponse()
+	return &resp, nil
+}
+
+// GetUser 获取用户，includeDeleted为true时也返回已删除的用户
+func (s *userService) GetUser(ctx context.Context, id string, includeDeleted bool) (*models.UserResponse, error) {
+	get := s.repo.GetByID
+	if includeDeleted {
+		get = s.repo.GetByIDIncludeDeleted
+	}
+	user, err := get(ctx, id)
+	if err != nil {
+		return nil, mapRepositoryError(err)
+	}
+
+	resp := user.ToResponse()
+	return &resp, nil
+}
+
+// ListUsers 分页获取用户。指定page时按偏移分页，否则按游标分页，
+// 游标中记录了排序方式，与本次请求的排序不一致时视为无效
+func (s *userService) ListUsers(ctx context.Context, req *models.ListUsersRequest) (*models.UserListResponse, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	sortBy := req.Sort
+	if sortBy == "" {
+		sortBy = "created_at"
+	}
+
+	q := &models.UserQuery{
+		Name:           req.Name,
+		Email:          req.Email,
+		CreatedAfter:   req.CreatedAfter,
+		CreatedBefore:  req.CreatedBefore,
+		SortBy:         strings.TrimPrefix(sortBy, "-"),
+		Desc:           strings.HasPrefix(sortBy, "-"),
+		Limit:          limit,
+		IncludeDeleted: req.IncludeDeleted,
+	}
+	switch {
+	case req.Page > 0:
+		q.Offset = (req.Page - 1) * limit
+	case req.Cursor != "":
+		token, err := decodeCursor(req.Cursor)
+		if err != nil || token.Sort != sortBy {
+			return nil, ErrInvalidCursor
+		}
+		cursor := &models.UserCursor{Value: token.Value, ID: token.ID}
+		if token.Before {
+			q.Before = cursor
+		} else {
+			q.After = cursor
+		}
+	}
+
+	list, err := s.repo.List(ctx, q)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		return nil, ErrInvalidCursor
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.UserListResponse{
+		Users: make([]models.UserResponse, 0, len(list.Users)),
+		Count: len(list.Users),
+		Total: list.Total,
+		Limit: limit,
+	}
+	for _, user := range list.Users {
+		resp.Users = append(resp.Users, user.ToResponse())
+	}
+
+	if req.Page > 0 {
+		resp.Page = req.Page
+		return resp, nil
+	}
+
+	// 游标分页：向前翻页（Before）时HasMore表示前面还有用户，游标本身所在的位置总在后面
+	hasNext := list.HasMore || q.Before != nil
+	hasPrev := q.After != nil || (q.Before != nil && list.HasMore)
+	if n := len(list.Users); n > 0 {
+		if hasNext {
+			resp.NextCursor = encodeCursor(sortBy, list.Users[n-1], false)
+		}
+		if hasPrev {
+			resp.PrevCursor = encodeCursor(sortBy, list.Users[0], true)
+		}
+	} else if q.After != nil {
+		resp.PrevCursor = encodeCursorToken(cursorToken{Sort: sortBy, Value: q.After.Value, ID: q.After.ID, Before: true})
+	} else if q.Before != nil {
+		resp.NextCursor = encodeCursorToken(cursorToken{Sort: sortBy, Value: q.Before.Value, ID: q.Before.ID})
+	}
+	return resp, nil
+}
+
+// SearchUsers 按姓名、邮箱或电话搜索用户，忽略大小写和重音。仓库按相关度取前limit个用户，
+// 再在这些用户中按字段加权得分排序并生成高亮
+func (s *userService) SearchUsers(ctx context.Context, req *models.SearchUsersRequest) (*models.UserSearchResponse, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+
+	resp := &models.UserSearchResponse{Query: req.Q, Results: make([]models.UserSearchResult, 0)}
+	terms := search.Tokenize(req.Q)
+	if len(terms) == 0 {
+		return resp, nil
+	}
+
+	users, err := s.repo.Search(ctx, terms, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		score, highlights, ok := search.Rank(search.UserFields(user.Name, user.Email, user.Phone), terms)
+		if !ok {
+			continue
+		}
+		resp.Results = append(resp.Results, models.UserSearchResult{
+			User:       user.ToResponse(),
+			Score:      score,
+			Highlights: highlights,
+		})
+	}
+
+	// 得分相同时保持仓库的相关度顺序
+	sort.SliceStable(resp.Results, func(i, j int) bool {
+		return resp.Results[i].Score > resp.Results[j].Score
+	})
+	resp.Count = len(resp.Results)
+	return resp, nil
+}
+
+// UpdateUser 更新用户姓名、邮箱和电话
+func (s *userService) UpdateUser(ctx context.Context, id string, version int64, req *models.UpdateUserRequest) (*models.UserResponse, error) {
+	user, err := s.getVersion(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.saveUser(ctx, user, req)
+}
+
+// PatchUser 部分更新用户：由apply修改以当前姓名、邮箱和电话填充的请求，apply返回的错误原样返回
+func (s *userService) PatchUser(ctx context.Context, id string, version int64, apply func(req *models.UpdateUserRequest) error) (*models.UserResponse, error) {
+	user, err := s.getVersion(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &models.UpdateUserRequest{Name: user.Name, Email: user.Email, Phone: user.Phone}
+	if err := apply(req); err != nil {
+		return nil, err
+	}
+	return s.saveUser(ctx, user, req)
+}
+
+// getVersion 读取用户，version不为0时要求当前版本与之一致
+func (s *userService) getVersion(ctx context.Context, id string, version int64) (*models.User, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, mapRepositoryError(err)
+	}
+	if version != 0 && user.Version != version {
+		return nil, ErrVersionMismatch
+	}
+	return user, nil
+}
+
+// saveUser 将请求中的字段写入用户并保存。保存时仓库按读取时的版本检查，期间被其他请求修改时返回ErrVersionMismatch
+func (s *userService) saveUser(ctx context.Context, user *models.User, req *models.UpdateUserRequest) (*models.UserResponse, error) {
+	user.Name = req.Name
+	user.Email = req.Email
+	user.Phone = req.Phone
+	user.UpdatedAt = time.Now().UTC()
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, mapRepositoryError(err)
+	}
+
+	resp := user.ToResponse()
+	return &resp, nil
+}
+
+// DeleteUser 软删除用户，删除后的用户不能登录，在保留期内可以恢复
+func (s *userService) DeleteUser(ctx context.Context, id string, version int64) error {
+	user, err := s.getVersion(ctx, id, version)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	user.DeletedAt = &now
+	user.UpdatedAt = now
+	return mapRepositoryError(s.repo.Update(ctx, user))
+}
+
+// RestoreUser 恢复已软删除的用户
+func (s *userService) RestoreUser(ctx context.Context, id string, version int64) (*models.UserResponse, error) {
+	user, err := s.repo.GetByIDIncludeDeleted(ctx, id)
+	if err != nil {
+		return nil, mapRepositoryError(err)
+	}
+	if version != 0 && user.Version != version {
+		return nil, ErrVersionMismatch
+	}
+	if user.DeletedAt == nil {
+		return nil, ErrUserNotDeleted
+	}
+
+	user.DeletedAt = nil
+	user.UpdatedAt = time.Now().UTC()
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, mapRepositoryError(err)
+	}
+
+	resp := user.ToResponse()
+	return &resp, nil
+}
+
+// cursorToken 游标的内容，编码为base64url的JSON，对客户端不透明
+type cursorToken struct {
+	Sort   string `json:"s"`
+	Value  string `json:"v"`
+	ID     string `json:"i"`
+	Before bool   `json:"b,omitempty"`
+}
+
+// encodeCursor 生成指向user之后（before为true时之前）的游标
+func encodeCursor(sortBy string, user *models.User, before bool) string {
+	return encodeCursorToken(cursorToken{
+		Sort:   sortBy,
+		Value:  user.SortValue(strings.TrimPrefix(sortBy, "-")),
+		ID:     user.ID,
+		Before: before,
+	})
+}
+
+// encodeCursorToken 编码游标
+func encodeCursorToken(token cursorToken) string {
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor 解码游标
+func decodeCursor(s string) (*cursorToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	token := new(cursorToken)
+	if err := json.Unmarshal(data, token); err != nil {
+		return nil, err
+	}
+	if token.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return token, nil
+}
+
+// mapRepositoryError 将仓库错误转换为服务错误
+func mapRepositoryError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrUserNotFound
+	case errors.Is(err, repository.ErrDuplicateEmail):
+		return ErrEmailTaken
+	case errors.Is(err, repository.ErrVersionConflict):
+		return ErrVersionMismatch
+	}
+	return err
+}
@@ -1,31 +1,471 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/redis/go-redis/v9"
+	"go-echo-app/internal/auth"
+	"go-echo-app/internal/config"
+	"go-echo-app/internal/database"
 	"go-echo-app/internal/handlers"
+	appmiddleware "go-echo-app/internal/middleware"
+	"go-echo-app/internal/models"
+	"go-echo-app/internal/repository"
+	"go-echo-app/internal/services"
+	"go-echo-app/pkg/utils"
+	"go-echo-app/pkg/validator"
 )
 
+// authRoutes 登录相关接口，始终免认证
+var authRoutes = []string{
+	"POST /api/v1/auth/login",
+	"POST /api/v1/auth/refresh",
+	"POST /api/v1/auth/logout",
+}
+
 func main() {
+	// 加载配置
+	cfg := config.LoadConfig()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(&cfg.Database, os.Args[2:]))
+	}
+	if err := cfg.JWT.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.Proxy.LoadFile(); err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.OIDC.LoadFile(); err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.RBAC.LoadFile(); err != nil {
+		log.Fatal(err)
+	}
+
 	// 创建Echo实例
 	e := echo.New()
+	e.IPExtractor = ipExtractor(cfg.Server.TrustedProxies)
+	e.Validator = validator.NewCustomValidator()
 
 	// 添加中间件
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
-	e.Use(middleware.CORS())
+	// 允许浏览器读取ETag（用于If-Match）和分页的Link响应头
+	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		ExposeHeaders: []string{"ETag", "Link"},
+	}))
+
+	// 创建中转处理器
+	proxyHandler, err := handlers.NewProxyHandler(&cfg.Proxy)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 创建限流中间件
+	preAuthLimit, postAuthLimit, err := rateLimitMiddleware(&cfg.RateLimit, &cfg.Redis)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// 创建用户和API Key仓库
+	userRepo, apiKeyRepo, closeDB, err := newRepositories(&cfg.Database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeDB()
+	passwords, err := auth.NewPasswordHasher(&cfg.Password)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := seedAdmin(context.Background(), userRepo, passwords, &cfg.JWT); err != nil {
+		log.Fatal(err)
+	}
+
+	// 定期永久删除超过保留期的已删除用户
+	purger := services.NewUserPurger(userRepo, time.Duration(cfg.Users.DeletedRetention)*time.Hour, time.Duration(cfg.Users.PurgeInterval)*time.Minute, log.Printf)
+	purger.Start()
+	defer purger.Close()
+
+	// 创建签名密钥集，HS算法时为nil
+	keys, err := auth.NewKeySet(&cfg.JWT)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if keys != nil {
+		keys.StartRotation(time.Duration(cfg.JWT.KeyRotation)*time.Hour, log.Printf)
+	}
+
+	// 创建认证服务和中间件
+	issuer, err := auth.NewTokenIssuer(&cfg.JWT, keys)
+	if err != nil {
+		log.Fatal(err)
+	}
+	authService, err := auth.NewService(userRepo, issuer, auth.NewMemoryRefreshTokenStore(), time.Duration(cfg.JWT.RefreshExpireTime)*time.Hour, passwords)
+	if err != nil {
+		log.Fatal(err)
+	}
+	authHandler := handlers.NewAuthHandler(authService, keys)
+	apiKeyService := auth.NewAPIKeyService(apiKeyRepo, userRepo)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	userHandler := handlers.NewUserHandler(services.NewUserService(userRepo, passwords), &cfg.Users)
+
+	authMiddleware, err := newAuthMiddleware(cfg, keys, userRepo, apiKeyService)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// 设置路由
-	setupRoutes(e)
+	setupRoutes(e, proxyHandler, authHandler, apiKeyHandler, userHandler, authMiddleware, preAuthLimit, postAuthLimit)
 
 	// 启动服务器
-	log.Fatal(e.Start(":8080"))
+	log.Fatal(e.Start(":" + cfg.Server.Port))
+}
+
+// ipExtractor 只信任来自可信代理的X-Forwarded-For，未配置时直接使用连接地址
+func ipExtractor(trustedProxies []string) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	options := make([]echo.TrustOption, 0, len(trustedProxies))
+	for _, cidr := range trustedProxies {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log.Fatalf("invalid trusted proxy %q: %v", cidr, err)
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
+// newAuthMiddleware 根据JWT和OIDC配置创建认证中间件，未启用时返回nil
+func newAuthMiddleware(cfg *config.Config, keys *auth.KeySet, users models.UserRepository, apiKeys auth.TokenVerifier) (echo.MiddlewareFunc, error) {
+	if !cfg.JWT.AuthEnabled {
+		return nil, nil
+	}
+
+	local, err := auth.NewJWTVerifier(&cfg.JWT, keys)
+	if err != nil {
+		return nil, err
+	}
+	clockSkew := time.Duration(cfg.JWT.ClockSkew) * time.Second
+	oidc := auth.NewOIDCVerifier(&cfg.OIDC, clockSkew, users)
+
+	return appmiddleware.AuthMiddleware(appmiddleware.AuthConfig{
+		Verifier:       auth.NewMultiVerifier(local, oidc),
+		APIKeyVerifier: apiKeys,
+		Users:          users,
+		Policy:         auth.NewPolicy(cfg.RBAC.Roles),
+		PublicRoutes:   append(authRoutes, cfg.JWT.PublicRoutes...),
+	}), nil
+}
+
+// newRepositories 按DB_DRIVER选择用户和API Key存储，返回的函数用于关闭数据库连接
+func newRepositories(cfg *config.DatabaseConfig) (models.UserRepository, models.APIKeyRepository, func(), error) {
+	switch cfg.Driver {
+	case "", "memory":
+		return repository.NewMemoryUserRepository(), repository.NewMemoryAPIKeyRepository(), func() {}, nil
+	case "postgres", "sqlite":
+		db, err := database.Open(cfg)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if err := migrateOnStartup(context.Background(), db, cfg); err != nil {
+			db.Close()
+			return nil, nil, nil, err
+		}
+		repo := sqlUserRepository(db, cfg.Driver)
+		reindexUserSearch(context.Background(), repo)
+		return repo, sqlAPIKeyRepository(db, cfg.Driver), func() { db.Close() }, nil
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+	}
+}
+
+// sqlUserRepository 按驱动创建SQL用户仓库
+func sqlUserRepository(db *sql.DB, driver string) *repository.SQLUserRepository {
+	if driver == "sqlite" {
+		return repository.NewSQLiteUserRepository(db)
+	}
+	return repository.NewPostgresUserRepository(db)
+}
+
+// sqlAPIKeyRepository 按驱动创建SQL API Key仓库
+func sqlAPIKeyRepository(db *sql.DB, driver string) *repository.SQLAPIKeyRepository {
+	if driver == "sqlite" {
+		return repository.NewSQLiteAPIKeyRepository(db)
+	}
+	return repository.NewPostgresAPIKeyRepository(db)
+}
+
+// reindexUserSearch 为迁移前已存在的用户生成检索文本，失败（如迁移尚未执行）时只记录日志
+func reindexUserSearch(ctx context.Context, repo *repository.SQLUserRepository) {
+	n, err := repo.ReindexSearch(ctx)
+	if err != nil {
+		log.Printf("reindex user search: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("indexed %d user(s) for search", n)
+	}
+}
+
+// migrateOnStartup 按DB_AUTO_MIGRATE执行未执行的迁移，关闭时只提示未执行的迁移
+func migrateOnStartup(ctx context.Context, db *sql.DB, cfg *config.DatabaseConfig) error {
+	migrator, err := database.NewMigrator(db, cfg.Driver)
+	if err != nil {
+		return err
+	}
+
+	if !cfg.AutoMigrate {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if pending > 0 {
+			log.Printf("%d database migration(s) pending, run \"migrate up\"", pending)
+		}
+		return nil
+	}
+
+	applied, err := migrator.Up(ctx)
+	for _, m := range applied {
+		log.Printf("applied migration %04d_%s", m.Version, m.Name)
+	}
+	return err
+}
+
+// runMigrate 执行migrate子命令，返回进程退出码
+func runMigrate(cfg *config.DatabaseConfig, args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dir := fs.String("dir", "internal/database/migrations", "directory for new migration files (create)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: migrate [-dir DIR] up | down [N] | status | create NAME")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	command := fs.Arg(0)
+	if command == "create" {
+		if fs.NArg() != 2 {
+			fs.Usage()
+			return 2
+		}
+		up, down, err := database.CreateMigration(*dir, fs.Arg(1))
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		fmt.Printf("created %s\ncreated %s\n", up, down)
+		return 0
+	}
+
+	if cfg.Driver != "postgres" && cfg.Driver != "sqlite" {
+		log.Printf("migrate requires DB_DRIVER=postgres or sqlite, got %q", cfg.Driver)
+		return 1
+	}
+	db, err := database.Open(cfg)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	defer db.Close()
+	migrator, err := database.NewMigrator(db, cfg.Driver)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+
+	ctx := context.Background()
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+		reindexUserSearch(ctx, sqlUserRepository(db, cfg.Driver))
+	case "down":
+		steps := 1
+		if fs.NArg() > 1 {
+			if steps, err = strconv.Atoi(fs.Arg(1)); err != nil || steps < 1 {
+				log.Printf("invalid step count %q", fs.Arg(1))
+				return 2
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		if len(reverted) == 0 {
+			fmt.Println("no applied migrations")
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, st := range statuses {
+			state, appliedAt := "pending", "-"
+			if st.Applied {
+				state, appliedAt = "applied", st.AppliedAt.Format(time.RFC3339)
+			}
+			if st.Modified {
+				state = "modified"
+			}
+			if st.Missing {
+				state = "missing"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", st.Version, st.Name, state, appliedAt)
+		}
+		w.Flush()
+	default:
+		fs.Usage()
+		return 2
+	}
+	return 0
+}
+
+// seedAdmin 按配置创建初始管理员，已存在时跳过
+func seedAdmin(ctx context.Context, users models.UserRepository, passwords *auth.PasswordHasher, cfg *config.JWTConfig) error {
+	if cfg.AdminEmail == "" || cfg.AdminPassword == "" {
+		return nil
+	}
+	if _, err := users.GetByEmail(ctx, cfg.AdminEmail); err == nil {
+		return nil
+	}
+	// 已软删除的用户不占用邮箱，但删除的初始管理员不应随重启重新创建
+	matches, err := users.List(ctx, &models.UserQuery{Email: cfg.AdminEmail, IncludeDeleted: true, Limit: 100})
+	if err != nil {
+		return err
+	}
+	for _, user := range matches.Users {
+		if strings.EqualFold(user.Email, cfg.AdminEmail) {
+			log.Printf("initial admin %s has been deleted, restore it to log in", cfg.AdminEmail)
+			return nil
+		}
+	}
+
+	hash, err := passwords.Hash(cfg.AdminPassword)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	err = users.Create(ctx, &models.User{
+		ID:        utils.NewID(),
+		Name:      "admin",
+		Email:     cfg.AdminEmail,
+		Password:  hash,
+		Roles:     []string{auth.RoleAdmin},
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if errors.Is(err, repository.ErrDuplicateEmail) {
+		// 其他副本同时创建了初始管理员
+		return nil
+	}
+	return err
+}
+
+// rateLimitMiddleware 根据配置创建入站限流中间件，未启用时返回nil。
+// 限流键只有ip、route时只在认证之前限流；包含user或api_key时，认证之前按IP执行宽松规则以限制暴力尝试，
+// 认证之后按调用方执行正常规则，未认证的请求按IP计数
+func rateLimitMiddleware(cfg *config.RateLimitConfig, redisCfg *config.RedisConfig) (preAuth, postAuth echo.MiddlewareFunc, err error) {
+	if !cfg.Enabled {
+		return nil, nil, nil
+	}
+
+	var store appmiddleware.RateLimitStore
+	switch cfg.Store {
+	case "memory":
+		store = appmiddleware.NewMemoryRateLimitStore(time.Duration(cfg.IdleTimeout) * time.Second)
+	case "redis":
+		opts, err := redis.ParseURL(redisCfg.URL)
+		if err != nil {
+			return nil, nil, err
+		}
+		timeout := time.Duration(redisCfg.TimeoutMs) * time.Millisecond
+		opts.DialTimeout, opts.ReadTimeout, opts.WriteTimeout = timeout, timeout, timeout
+		store = appmiddleware.NewRedisRateLimitStore(redis.NewClient(opts), cfg.KeyPrefix)
+	default:
+		return nil, nil, fmt.Errorf("unknown rate limit store %q", cfg.Store)
+	}
+
+	principalKeys := false
+	for _, name := range cfg.KeyBy {
+		if appmiddleware.KeyNeedsPrincipal(name) {
+			principalKeys = true
+		}
+	}
+	keyFunc, err := appmiddleware.KeyFuncByNames(cfg.KeyBy)
+	if err != nil {
+		return nil, nil, err
+	}
+	routes, err := cfg.ParseRoutes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	routeLimits := make([]appmiddleware.RouteLimit, 0, len(routes))
+	for _, r := range routes {
+		routeLimits = append(routeLimits, appmiddleware.RouteLimit{
+			Method: r.Method,
+			Path:   r.Path,
+			Rule:   appmiddleware.RateLimitRule{RequestsPerSecond: r.RequestsPerSecond, Burst: r.Burst},
+		})
+	}
+
+	limitConfig := appmiddleware.RateLimitConfig{
+		Store:       store,
+		KeyFunc:     keyFunc,
+		DefaultRule: appmiddleware.RateLimitRule{RequestsPerSecond: cfg.RequestsPerSecond, Burst: cfg.Burst},
+		PerRoute:    cfg.PerRoute,
+		Routes:      routeLimits,
+		FailOpen:    cfg.FailOpen,
+	}
+	if !principalKeys {
+		return appmiddleware.RateLimitMiddleware(limitConfig), nil, nil
+	}
+
+	preAuth, postAuth = appmiddleware.StagedRateLimitMiddleware(limitConfig,
+		appmiddleware.RateLimitRule{RequestsPerSecond: cfg.PreAuthRPS, Burst: cfg.PreAuthBurst})
+	return preAuth, postAuth, nil
 }
 
-func setupRoutes(e *echo.Echo) {
+func setupRoutes(e *echo.Echo, proxyHandler *handlers.ProxyHandler, authHandler *handlers.AuthHandler, apiKeyHandler *handlers.APIKeyHandler, userHandler *handlers.UserHandler, authMiddleware, preAuthLimit, postAuthLimit echo.MiddlewareFunc) {
 	// 健康检查端点
 	e.GET("/health", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]string{
@@ -34,25 +474,74 @@ func setupRoutes(e *echo.Echo) {
 		})
 	})
 
+	// 签名公钥，供其他服务验签
+	e.GET("/.well-known/jwks.json", authHandler.JWKS)
+
 	// API路由组
 	api := e.Group("/api/v1")
+	// 限流先于认证执行，猜测令牌或API Key的请求在校验前就受到限制
+	if preAuthLimit != nil {
+		api.Use(preAuthLimit)
+	}
+	if authMiddleware != nil {
+		api.Use(authMiddleware)
+	}
+	if postAuthLimit != nil {
+		api.Use(postAuthLimit)
+	}
+
+	// 权限检查，未启用认证时不做限制
+	require := func(permission string) echo.MiddlewareFunc {
+		if authMiddleware == nil {
+			return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+		}
+		return appmiddleware.RequirePermission(permission)
+	}
 	
+	// 认证路由
+	api.POST("/auth/login", authHandler.Login)
+	api.POST("/auth/refresh", authHandler.Refresh)
+	api.POST("/auth/logout", authHandler.Logout)
+	api.POST("/auth/password", authHandler.ChangePassword)
+	api.POST("/auth/api-keys", apiKeyHandler.CreateAPIKey, require(auth.PermissionAPIKeysManage))
+	api.GET("/auth/api-keys", apiKeyHandler.ListAPIKeys, require(auth.PermissionAPIKeysManage))
+	api.DELETE("/auth/api-keys/:id", apiKeyHandler.RevokeAPIKey, require(auth.PermissionAPIKeysManage))
+
 	// 用户相关路由
-	api.GET("/users", getUsers)
-	api.GET("/users/:id", getUser)
-	api.POST("/users", createUser)
-	api.PUT("/users/:id", updateUser)
-	api.DELETE("/users/:id", deleteUser)
+	api.GET("/users", userHandler.GetUsers, require(auth.PermissionUsersRead))
+	api.GET("/users/search", userHandler.SearchUsers, require(auth.PermissionUsersRead))
+	api.GET("/users/:id", userHandler.GetUser, require(auth.PermissionUsersRead))
+	api.POST("/users", userHandler.CreateUser, require(auth.PermissionUsersWrite))
+	api.PUT("/users/:id", userHandler.UpdateUser, require(auth.PermissionUsersWrite))
+	api.PATCH("/users/:id", userHandler.PatchUser, require(auth.PermissionUsersWrite))
+	api.DELETE("/users/:id", userHandler.DeleteUser, require(auth.PermissionUsersWrite))
+	api.POST("/users/:id/restore", userHandler.RestoreUser, require(auth.PermissionUsersAdmin))
 
 	// HTTP中转API路由
-	api.POST("/proxy", handlers.ProxyRequest)
-	api.GET("/proxy", handlers.ProxyRequest)
-	api.PUT("/proxy", handlers.ProxyRequest)
-	api.DELETE("/proxy", handlers.ProxyRequest)
-	api.PATCH("/proxy", handlers.ProxyRequest)
+	api.POST("/proxy", proxyHandler.ProxyRequest, require(auth.PermissionProxyUse))
+	api.GET("/proxy", proxyHandler.ProxyRequest, require(auth.PermissionProxyUse))
+	api.PUT("/proxy", proxyHandler.ProxyRequest, require(auth.PermissionProxyUse))
+	api.DELETE("/proxy", proxyHandler.ProxyRequest, require(auth.PermissionProxyUse))
+	api.PATCH("/proxy", proxyHandler.ProxyRequest, require(auth.PermissionProxyUse))
 	
 	// 带配置的HTTP中转API
-	api.POST("/proxy/config", handlers.ProxyRequestWithConfig)
+	api.POST("/proxy/config", proxyHandler.ProxyRequestWithConfig, require(auth.PermissionProxyUse))
+
+	// 中转管理路由：启用认证时需要proxy:admin权限，否则需要携带PROXY_ADMIN_TOKEN
+	var adminGuard echo.MiddlewareFunc = proxyHandler.RequireAdminToken
+	if authMiddleware != nil {
+		adminGuard = appmiddleware.RequirePermission(auth.PermissionProxyAdmin)
+	}
+	admin := api.Group("/admin", adminGuard)
+	admin.GET("/faults", proxyHandler.GetFaults)
+	admin.PUT("/faults", proxyHandler.ReplaceFaults)
+	admin.POST("/faults/toggle", proxyHandler.ToggleFaults)
+	admin.PATCH("/faults/:id", proxyHandler.ToggleFaultRule)
+	admin.GET("/mirror", proxyHandler.GetMirrorStats)
+	admin.GET("/mirror/diffs", proxyHandler.GetMirrorDiffs)
+	admin.DELETE("/mirror/diffs", proxyHandler.ClearMirrorDiffs)
+	admin.GET("/canary", proxyHandler.GetCanaryStatus)
+	admin.PUT("/canary/:upstream/weights", proxyHandler.UpdateCanaryWeights)
 
 	// 根路径
 	e.GET("/", func(c echo.Context) error {
@@ -62,62 +551,3 @@ func setupRoutes(e *echo.Echo) {
 		})
 	})
 }
-
-
-
-// 用户相关的处理函数
-func getUsers(c echo.Context) error {
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"users": []map[string]string{
-			{"id": "1", "name": "John Doe", "email": "john@example.com"},
-			{"id": "2", "name": "Jane Smith", "email": "jane@example.com"},
-		},
-	})
-}
-
-func getUser(c echo.Context) error {
-	id := c.Param("id")
-	return c.JSON(http.StatusOK, map[string]string{
-		"id":    id,
-		"name":  "John Doe",
-		"email": "john@example.com",
-	})
-}
-
-func createUser(c echo.Context) error {
-	user := make(map[string]interface{})
-	if err := c.Bind(&user); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid request body",
-		})
-	}
-	
-	return c.JSON(http.StatusCreated, map[string]interface{}{
-		"message": "User created successfully",
-		"user":    user,
-	})
-}
-
-func updateUser(c echo.Context) error {
-	id := c.Param("id")
-	user := make(map[string]interface{})
-	if err := c.Bind(&user); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid request body",
-		})
-	}
-	
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message": "User updated successfully",
-		"id":      id,
-		"user":    user,
-	})
-}
-
-func deleteUser(c echo.Context) error {
-	id := c.Param("id")
-	return c.JSON(http.StatusOK, map[string]string{
-		"message": "User deleted successfully",
-		"id":      id,
-	})
-}
\ No newline at end of file
@@ -0,0 +1,177 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// assertJSON 按JSON值比较，忽略对象成员顺序和空白
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("result is not valid JSON: %v", err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("want is not valid JSON: %v", err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	// 用例取自RFC 7396附录A
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.patch, func(t *testing.T) {
+			got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestMergePatchErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		doc, patch string
+		want       error
+	}{
+		{name: "invalid patch", doc: `{}`, patch: `{"a":`, want: ErrInvalidPatch},
+		{name: "trailing data", doc: `{}`, patch: `{} {}`, want: ErrInvalidPatch},
+		{name: "invalid document", doc: `nope`, patch: `{}`, want: ErrInvalidDocument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := MergePatch([]byte(tt.doc), []byte(tt.patch)); !errors.Is(err, tt.want) {
+				t.Fatalf("MergePatch() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchApply(t *testing.T) {
+	tests := []struct {
+		name, doc, patch, want string
+	}{
+		{"add member", `{"a":1}`, `[{"op":"add","path":"/b","value":2}]`, `{"a":1,"b":2}`},
+		{"add replaces member", `{"a":1}`, `[{"op":"add","path":"/a","value":2}]`, `{"a":2}`},
+		{"add inserts into array", `{"a":[1,3]}`, `[{"op":"add","path":"/a/1","value":2}]`, `{"a":[1,2,3]}`},
+		{"add appends with dash", `{"a":[1]}`, `[{"op":"add","path":"/a/-","value":2}]`, `{"a":[1,2]}`},
+		{"add at array length", `{"a":[1]}`, `[{"op":"add","path":"/a/1","value":2}]`, `{"a":[1,2]}`},
+		{"add replaces root", `{"a":1}`, `[{"op":"add","path":"","value":[1]}]`, `[1]`},
+		{"remove member", `{"a":1,"b":2}`, `[{"op":"remove","path":"/a"}]`, `{"b":2}`},
+		{"remove array element", `{"a":[1,2,3]}`, `[{"op":"remove","path":"/a/1"}]`, `{"a":[1,3]}`},
+		{"replace", `{"a":{"b":1}}`, `[{"op":"replace","path":"/a/b","value":"x"}]`, `{"a":{"b":"x"}}`},
+		{"move", `{"a":{"b":1},"c":{}}`, `[{"op":"move","from":"/a/b","path":"/c/d"}]`, `{"a":{},"c":{"d":1}}`},
+		{"move to itself", `{"a":1}`, `[{"op":"move","from":"/a","path":"/a"}]`, `{"a":1}`},
+		{"copy is independent", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`},
+		{"test numbers by value", `{"a":1.0}`, `[{"op":"test","path":"/a","value":1}]`, `{"a":1}`},
+		{"test ignores member order", `{"a":{"x":1,"y":2}}`, `[{"op":"test","path":"/a","value":{"y":2,"x":1}}]`, `{"a":{"x":1,"y":2}}`},
+		{"escaped pointer", `{"a/b":{"c~d":1}}`, `[{"op":"replace","path":"/a~1b/c~0d","value":2}]`, `{"a/b":{"c~d":2}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := DecodePatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := patch.Apply([]byte(tt.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestPatchApplyErrors(t *testing.T) {
+	tests := []struct {
+		name, doc, patch string
+		want             error
+	}{
+		{"test fails", `{"a":1}`, `[{"op":"test","path":"/a","value":2}]`, ErrTestFailed},
+		{"replace missing member", `{}`, `[{"op":"replace","path":"/a","value":1}]`, ErrPathNotFound},
+		{"remove missing member", `{}`, `[{"op":"remove","path":"/a"}]`, ErrPathNotFound},
+		{"array index out of range", `[1]`, `[{"op":"add","path":"/2","value":1}]`, ErrPathNotFound},
+		{"array index with leading zero", `[1,2]`, `[{"op":"remove","path":"/01"}]`, ErrPathNotFound},
+		{"dash only valid for add", `[1]`, `[{"op":"remove","path":"/-"}]`, ErrPathNotFound},
+		{"missing parent", `{}`, `[{"op":"add","path":"/a/b","value":1}]`, ErrPathNotFound},
+		{"remove root", `{}`, `[{"op":"remove","path":""}]`, ErrInvalidPatch},
+		{"move into child", `{"a":{}}`, `[{"op":"move","from":"/a","path":"/a/b"}]`, ErrInvalidPatch},
+		{"invalid document", `{`, `[]`, ErrInvalidDocument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := DecodePatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := patch.Apply([]byte(tt.doc)); !errors.Is(err, tt.want) {
+				t.Fatalf("Apply() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchApplyIsAtomic(t *testing.T) {
+	doc := []byte(`{"a":1}`)
+	patch, err := DecodePatch([]byte(`[{"op":"replace","path":"/a","value":2},{"op":"test","path":"/a","value":3}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := patch.Apply(doc); !errors.Is(err, ErrTestFailed) {
+		t.Fatalf("Apply() error = %v, want ErrTestFailed", err)
+	}
+	assertJSON(t, doc, `{"a":1}`)
+}
+
+func TestDecodePatchErrors(t *testing.T) {
+	tests := []struct {
+		name, patch string
+	}{
+		{"not an array", `{"op":"add"}`},
+		{"null", `null`},
+		{"unknown op", `[{"op":"frobnicate","path":"/a"}]`},
+		{"missing value", `[{"op":"add","path":"/a"}]`},
+		{"missing from", `[{"op":"move","path":"/a"}]`},
+		{"path without slash", `[{"op":"remove","path":"a"}]`},
+		{"from without slash", `[{"op":"copy","from":"a","path":"/b"}]`},
+		{"wrong member type", `[{"op":"remove","path":1}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodePatch([]byte(tt.patch)); !errors.Is(err, ErrInvalidPatch) {
+				t.Fatalf("DecodePatch() error = %v, want ErrInvalidPatch", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Package jsonpatch 实现JSON Merge Patch（RFC 7396）和JSON Patch（RFC 6902）
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// 补丁文档的媒体类型
+const (
+	MediaTypeMergePatch = "application/merge-patch+json"
+	MediaTypeJSONPatch  = "application/json-patch+json"
+)
+
+var (
+	// ErrInvalidPatch 补丁文档格式错误
+	ErrInvalidPatch = errors.New("invalid patch document")
+	// ErrInvalidDocument 被修改的文档不是有效的JSON
+	ErrInvalidDocument = errors.New("invalid target document")
+)
+
+// MergePatch 将Merge Patch应用到doc并返回结果。补丁中的对象逐个成员合并，值为null的成员被删除，
+// 其他类型的值（包括数组）整体替换原值
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var p interface{}
+	if err := decode(patch, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	var target interface{}
+	if err := decode(doc, &target); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	return json.Marshal(merge(target, p))
+}
+
+// merge 按RFC 7396第2节的算法合并
+func merge(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+			continue
+		}
+		t[key] = merge(t[key], value)
+	}
+	return t
+}
+
+// decode 解析单个JSON值，数字保留为json.Number以免损失精度
+func decode(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after JSON value")
+	}
+	return nil
+}
@@ -0,0 +1,376 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrPathNotFound 路径指向的位置不存在
+	ErrPathNotFound = errors.New("path not found")
+	// ErrTestFailed test操作的值不相等
+	ErrTestFailed = errors.New("test operation failed")
+)
+
+// Operation JSON Patch中的一个操作
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  *string         `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Patch JSON Patch文档，操作按顺序执行
+type Patch []Operation
+
+// DecodePatch 解析JSON Patch文档并检查每个操作的成员是否齐全
+func DecodePatch(data []byte) (Patch, error) {
+	var patch Patch
+	if err := decode(data, &patch); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		if typeErr.Field != "" {
+			return nil, fmt.Errorf("%w: %s must be a %s", ErrInvalidPatch, typeErr.Field, typeErr.Type.Kind())
+		}
+		patch = nil
+	}
+	if patch == nil {
+		return nil, fmt.Errorf("%w: patch must be an array of operations", ErrInvalidPatch)
+	}
+
+	for i, op := range patch {
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return nil, fmt.Errorf("%w: operation %d (%s) is missing value", ErrInvalidPatch, i, op.Op)
+			}
+		case "remove":
+		case "move", "copy":
+			if op.From == nil {
+				return nil, fmt.Errorf("%w: operation %d (%s) is missing from", ErrInvalidPatch, i, op.Op)
+			}
+			if _, err := parsePointer(*op.From); err != nil {
+				return nil, fmt.Errorf("%w: operation %d (%s): from: %v", ErrInvalidPatch, i, op.Op, err)
+			}
+		default:
+			return nil, fmt.Errorf("%w: operation %d has unknown op %q", ErrInvalidPatch, i, op.Op)
+		}
+		if _, err := parsePointer(op.Path); err != nil {
+			return nil, fmt.Errorf("%w: operation %d (%s): path: %v", ErrInvalidPatch, i, op.Op, err)
+		}
+	}
+	return patch, nil
+}
+
+// Apply 依次执行操作并返回修改后的文档，任一操作失败时整个补丁不生效
+func (p Patch) Apply(doc []byte) ([]byte, error) {
+	var target interface{}
+	if err := decode(doc, &target); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+
+	for i, op := range p {
+		var err error
+		target, err = op.apply(target)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+// apply 执行单个操作，返回新的根节点
+func (op Operation) apply(doc interface{}) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		var value interface{}
+		if err := decode(op.Value, &value); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			return replace(doc, path, value)
+		}
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(current, value) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	case "remove":
+		doc, _, err = remove(doc, path)
+		return doc, err
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: missing from", ErrInvalidPatch)
+		}
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		if op.Op == "copy" {
+			value, err := get(doc, from)
+			if err != nil {
+				return nil, err
+			}
+			return add(doc, path, deepCopy(value))
+		}
+		if *op.From == op.Path {
+			_, err := get(doc, from)
+			return doc, err
+		}
+		if strings.HasPrefix(op.Path, *op.From+"/") {
+			return nil, fmt.Errorf("%w: cannot move a value into one of its children", ErrInvalidPatch)
+		}
+		doc, value, err := remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+// add 在path处添加值：对象成员已存在时替换，数组元素插入到指定下标，下标为"-"时追加到末尾
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(doc, path, func(parent interface{}, key string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			p[key] = value
+			return p, nil
+		case []interface{}:
+			if key == "-" {
+				return append(p, value), nil
+			}
+			i, err := arrayIndex(key, len(p)+1)
+			if err != nil {
+				return nil, err
+			}
+			p = append(p, nil)
+			copy(p[i+1:], p[i:])
+			p[i] = value
+			return p, nil
+		default:
+			return nil, ErrPathNotFound
+		}
+	})
+}
+
+// replace 替换path处已存在的值
+func replace(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(doc, path, func(parent interface{}, key string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := p[key]; !ok {
+				return nil, ErrPathNotFound
+			}
+			p[key] = value
+			return p, nil
+		case []interface{}:
+			i, err := arrayIndex(key, len(p))
+			if err != nil {
+				return nil, err
+			}
+			p[i] = value
+			return p, nil
+		default:
+			return nil, ErrPathNotFound
+		}
+	})
+}
+
+// remove 删除path处的值，返回新的根节点和被删除的值
+func remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the document root", ErrInvalidPatch)
+	}
+	var removed interface{}
+	doc, err := update(doc, path, func(parent interface{}, key string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			value, ok := p[key]
+			if !ok {
+				return nil, ErrPathNotFound
+			}
+			removed = value
+			delete(p, key)
+			return p, nil
+		case []interface{}:
+			i, err := arrayIndex(key, len(p))
+			if err != nil {
+				return nil, err
+			}
+			removed = p[i]
+			return append(p[:i], p[i+1:]...), nil
+		default:
+			return nil, ErrPathNotFound
+		}
+	})
+	return doc, removed, err
+}
+
+// get 返回path处的值
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, key := range path {
+		var err error
+		if doc, err = child(doc, key); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// update 沿path找到最后一级的父节点交给fn修改，并把修改后的节点写回上一级（数组追加或删除元素后可能是新的切片）
+func update(node interface{}, path []string, fn func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(node, path[0])
+	}
+	next, err := child(node, path[0])
+	if err != nil {
+		return nil, err
+	}
+	next, err = update(next, path[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		n[path[0]] = next
+	case []interface{}:
+		i, _ := arrayIndex(path[0], len(n))
+		n[i] = next
+	}
+	return node, nil
+}
+
+// child 返回对象成员或数组元素
+func child(node interface{}, key string) (interface{}, error) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		value, ok := n[key]
+		if !ok {
+			return nil, ErrPathNotFound
+		}
+		return value, nil
+	case []interface{}:
+		i, err := arrayIndex(key, len(n))
+		if err != nil {
+			return nil, err
+		}
+		return n[i], nil
+	default:
+		return nil, ErrPathNotFound
+	}
+}
+
+// arrayIndex 解析数组下标，下标必须是不带前导零的十进制数且小于limit
+func arrayIndex(key string, limit int) (int, error) {
+	if key == "" || (len(key) > 1 && key[0] == '0') || strings.TrimLeft(key, "0123456789") != "" {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrPathNotFound, key)
+	}
+	i, err := strconv.Atoi(key)
+	if err != nil || i >= limit {
+		return 0, fmt.Errorf("%w: array index %s out of range", ErrPathNotFound, key)
+	}
+	return i, nil
+}
+
+// pointerUnescaper 还原JSON Pointer中转义的"~"和"/"
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// parsePointer 将JSON Pointer（RFC 6901）拆分为各级引用，空字符串表示整个文档
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("JSON pointer %q must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = pointerUnescaper.Replace(token)
+	}
+	return tokens, nil
+}
+
+// equal 按RFC 6902第4.6节比较两个值，数字按数值比较，对象不考虑成员顺序
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			other, ok := y[key]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fx, okx := new(big.Float).SetString(x.String())
+		fy, oky := new(big.Float).SetString(y.String())
+		return okx && oky && fx.Cmp(fy) == 0
+	default:
+		return a == b
+	}
+}
+
+// deepCopy 复制对象和数组，copy操作得到的值与原值互不影响
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = deepCopy(item)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, item := range v {
+			s[i] = deepCopy(item)
+		}
+		return s
+	default:
+		return value
+	}
+}
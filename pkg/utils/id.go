@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// NewID 生成随机ID（32位十六进制）
+func NewID() string {
+	return RandomToken(16)
+}
+
+// RandomToken 生成n字节的随机数并以十六进制返回
+func RandomToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
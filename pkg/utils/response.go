@@ -52,6 +52,36 @@ func NotFound(c echo.Context, message string) error {
 	return ErrorResponse(c, http.StatusNotFound, message, "Not Found")
 }
 
+// Conflict 409错误响应
+func Conflict(c echo.Context, message string) error {
+	return ErrorResponse(c, http.StatusConflict, message, "Conflict")
+}
+
+// PreconditionFailed 412错误响应
+func PreconditionFailed(c echo.Context, message string) error {
+	return ErrorResponse(c, http.StatusPreconditionFailed, message, "Precondition Failed")
+}
+
+// UnsupportedMediaType 415错误响应
+func UnsupportedMediaType(c echo.Context, message string) error {
+	return ErrorResponse(c, http.StatusUnsupportedMediaType, message, "Unsupported Media Type")
+}
+
+// UnprocessableEntity 422错误响应，用于请求格式正确但无法处理的情况
+func UnprocessableEntity(c echo.Context, message string) error {
+	return ErrorResponse(c, http.StatusUnprocessableEntity, message, "Unprocessable Entity")
+}
+
+// PreconditionRequired 428错误响应
+func PreconditionRequired(c echo.Context, message string) error {
+	return ErrorResponse(c, http.StatusPreconditionRequired, message, "Precondition Required")
+}
+
+// TooManyRequests 429错误响应
+func TooManyRequests(c echo.Context, message string) error {
+	return ErrorResponse(c, http.StatusTooManyRequests, message, "Too Many Requests")
+}
+
 // InternalServerError 500错误响应
 func InternalServerError(c echo.Context, message string) error {
 	return ErrorResponse(c, http.StatusInternalServerError, message, "Internal Server Error")
@@ -93,7 +93,7 @@ func GetValidationErrors(err error) []ValidationError {
 			// 将字段名转换为小写（JSON格式）
 			field = strings.ToLower(field[:1]) + field[1:]
 			
-			message := getErrorMessage(field, tag)
+			message := getErrorMessage(field, tag, e.Param(), e.Kind())
 			
 			errors = append(errors, ValidationError{
 				Field:   field,
@@ -108,16 +108,25 @@ func GetValidationErrors(err error) []ValidationError {
 }
 
 // getErrorMessage 获取错误消息
-func getErrorMessage(field, tag string) string {
+func getErrorMessage(field, tag, param string, kind reflect.Kind) string {
+	unit := ""
+	if kind == reflect.String {
+		unit = " characters"
+	}
+
 	switch tag {
 	case "required":
 		return field + " is required"
 	case "email":
 		return field + " must be a valid email address"
 	case "min":
-		return field + " must be at least " + tag + " characters"
+		return field + " must be at least " + param + unit
 	case "max":
-		return field + " must be at most " + tag + " characters"
+		return field + " must be at most " + param + unit
+	case "oneof":
+		return field + " must be one of: " + param
+	case "excluded_with":
+		return field + " cannot be used together with " + strings.ToLower(param)
 	case "password":
 		return field + " must be at least 8 characters with uppercase, lowercase and number"
 	case "phone":